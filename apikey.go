@@ -0,0 +1,29 @@
+package krinder
+
+import (
+	"context"
+	"time"
+
+	"github.com/eveisesi/krinder/pkg/errorcode"
+)
+
+// ErrDuplicate is returned by repositories when a record with the same unique key already exists
+var ErrDuplicate = errorcode.New(errorcode.InvalidArgument, "record already exists")
+
+// APIKeyRepository stores the keys that authenticate requests to the REST API. Only the
+// hash of a key is stored, the key itself is shown once when it is created
+type APIKeyRepository interface {
+	APIKey(ctx context.Context, hash string) (*APIKey, error)
+	APIKeys(ctx context.Context, operators ...*Operator) ([]*APIKey, error)
+	CreateAPIKey(ctx context.Context, key *APIKey) error
+	DeleteAPIKey(ctx context.Context, name string) error
+}
+
+type APIKey struct {
+	Name string `bson:"name" json:"name"`
+	// Hash is the hex encoded SHA-256 of the key
+	Hash string `bson:"hash" json:"-"`
+	// RateLimit is the number of requests allowed per minute, zero means the default limit
+	RateLimit int       `bson:"rateLimit" json:"rateLimit"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
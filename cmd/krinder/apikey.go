@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/internal/api"
+	"github.com/eveisesi/krinder/pkg/errorcode"
+	"github.com/urfave/cli/v2"
+)
+
+func apikeyCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "apikey",
+		Usage: "Manages the keys that authenticate requests to the REST API",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "create",
+				Usage:     "Creates a key and prints it. The key cannot be shown again",
+				ArgsUsage: "<name>",
+				Action:    apikeyCreate,
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "rate-limit",
+						Usage: "requests per minute allowed for the key, defaults to API_RATE_LIMIT",
+					},
+				},
+			},
+			{
+				Name:   "list",
+				Usage:  "Lists the names and rate limits of every key",
+				Action: apikeyList,
+			},
+			{
+				Name:      "revoke",
+				Usage:     "Deletes a key, rejecting any further request made with it",
+				ArgsUsage: "<name>",
+				Action:    apikeyRevoke,
+			},
+		},
+	}
+}
+
+func apikeyCreate(c *cli.Context) error {
+
+	name := c.Args().First()
+	if name == "" {
+		return errorcode.New(errorcode.InvalidArgument, "a name is required")
+	}
+
+	s, err := buildServices(c.Context)
+	if err != nil {
+		return err
+	}
+
+	key, err := api.GenerateKey()
+	if err != nil {
+		return err
+	}
+
+	err = s.apiKeys.CreateAPIKey(c.Context, &krinder.APIKey{
+		Name:      name,
+		Hash:      api.HashKey(key),
+		RateLimit: c.Int("rate-limit"),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(c.App.Writer, "created api key %s: %s\n", name, key)
+	return err
+
+}
+
+func apikeyList(c *cli.Context) error {
+
+	s, err := buildServices(c.Context)
+	if err != nil {
+		return err
+	}
+
+	keys, err := s.apiKeys.APIKeys(c.Context, krinder.NewOrderOperator("name", krinder.SortAsc))
+	if err != nil {
+		return err
+	}
+
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		limit := fmt.Sprintf("%d/min", key.RateLimit)
+		if key.RateLimit == 0 {
+			limit = fmt.Sprintf("%d/min (default)", cfg.API.RateLimit)
+		}
+		lines = append(lines, fmt.Sprintf("%s %s created %s", key.Name, limit, key.CreatedAt.Format(time.RFC3339)))
+	}
+
+	return writeLines(c.App.Writer, fmt.Sprintf("%d api keys", len(keys)), lines)
+
+}
+
+func apikeyRevoke(c *cli.Context) error {
+
+	name := c.Args().First()
+	if name == "" {
+		return errorcode.New(errorcode.InvalidArgument, "a name is required")
+	}
+
+	s, err := buildServices(c.Context)
+	if err != nil {
+		return err
+	}
+
+	err = s.apiKeys.DeleteAPIKey(c.Context, name)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(c.App.Writer, "revoked api key %s\n", name)
+	return err
+
+}
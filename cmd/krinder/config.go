@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
@@ -9,29 +10,90 @@ import (
 
 type config struct {
 	Discord struct {
-		Token string `envconfig:"DISCORD_TOKEN" required:"true"`
+		// Token is only required by serve
+		Token string `envconfig:"DISCORD_TOKEN"`
 	}
+	// Log.Format is either text or json
 	Log struct {
-		Level string `envconfig:"LOG_LEVEL" default:"info"`
+		Level  string `envconfig:"LOG_LEVEL" default:"info"`
+		Format string `envconfig:"LOG_FORMAT" default:"text"`
+	}
+	// Admin.Addr is the listen address of the admin server serving /healthz, /readyz, /status
+	// and /metrics, e.g. :9090. The admin server is disabled when it is empty
+	Admin struct {
+		Addr string `envconfig:"ADMIN_ADDR"`
+	}
+	// API.Addr is the listen address of the REST API, e.g. :8080. The API is disabled when it is empty.
+	// API.RateLimit is the number of requests per minute allowed for keys without a limit of their own
+	API struct {
+		Addr      string `envconfig:"API_ADDR"`
+		RateLimit int    `envconfig:"API_RATE_LIMIT" default:"60"`
+	}
+	// SSO configures linking characters through EVE SSO, which is disabled when SSO_CLIENT_ID is empty.
+	// SSO.Addr is the listen address of the callback handler, served at the path of SSO_CALLBACK_URL.
+	// SSO.EncryptionKey is a base64 encoded AES key of 16, 24 or 32 bytes sealing the stored tokens
+	SSO struct {
+		ClientID      string   `envconfig:"SSO_CLIENT_ID"`
+		ClientSecret  string   `envconfig:"SSO_CLIENT_SECRET"`
+		CallbackURL   string   `envconfig:"SSO_CALLBACK_URL"`
+		Addr          string   `envconfig:"SSO_ADDR" default:":8081"`
+		EncryptionKey string   `envconfig:"SSO_ENCRYPTION_KEY"`
+		Scopes        []string `envconfig:"SSO_SCOPES" default:"publicData,esi-killmails.read_killmails.v1,esi-characters.read_notifications.v1"`
+	}
+	// Upstream points the ESI and zKillboard clients at other deployments of the APIs, such as a local fake.
+	// Upstream.Fixtures is a directory every upstream response is recorded into or replayed from, according
+	// to Upstream.FixturesMode, either record or replay. Fixtures are not used when it is empty
+	Upstream struct {
+		ESI          string `envconfig:"ESI_BASE_URL" default:"https://esi.evetech.net"`
+		ZKillboard   string `envconfig:"ZKILLBOARD_BASE_URL" default:"https://zkillboard.com/api"`
+		Fixtures     string `envconfig:"FIXTURES_DIR"`
+		FixturesMode string `envconfig:"FIXTURES_MODE" default:"replay"`
 	}
 	Redis struct {
 		Host string `envconfig:"REDIS_HOST" required:"true"`
 		Pass string `envconfig:"REDIS_PASS" required:"true"`
 	}
+	// Store selects the backend for every repository, either mongo or memory.
+	// The memory store does not persist anything and is intended for local development
+	Store string `envconfig:"STORE" default:"mongo"`
+	// Mongo is only required when one of the stores is set to mongo
 	Mongo struct {
-		Host     string `envconfig:"MONGO_HOST" required:"true"`
-		Database string `envconfig:"MONGO_DATABASE" required:"true"`
-		User     string `envconfig:"MONGO_USER" required:"true"`
-		Pass     string `envconfig:"MONGO_PASS" required:"true"`
+		Host     string `envconfig:"MONGO_HOST"`
+		Database string `envconfig:"MONGO_DATABASE"`
+		User     string `envconfig:"MONGO_USER"`
+		Pass     string `envconfig:"MONGO_PASS"`
 	}
+	// MySQL is only required when Universe.Store is set to mysql
+	// or when migrating groups out of MySQL
 	MySQL struct {
-		Host string `required:"true"`
-		User string `required:"true"`
-		Pass string `required:"true"`
-		DB   string `required:"true"`
+		Host        string
+		User        string
+		Pass        string
+		DB          string
+		AutoMigrate bool `envconfig:"MYSQL_AUTO_MIGRATE" default:"true"`
+	}
+	// Universe.Store overrides Store for the universe repository and
+	// additionally accepts mysql
+	Universe struct {
+		Store string `envconfig:"UNIVERSE_STORE"`
+	}
+	// Jobs configures the background syncs. Schedules accept cron expressions and
+	// descriptors such as @every 3h or @daily. Jitter bounds a random delay added to every run
+	Jobs struct {
+		WarsSchedule     string        `envconfig:"WARS_SCHEDULE" default:"@every 3h"`
+		UniverseSchedule string        `envconfig:"UNIVERSE_SCHEDULE" default:"@every 24h"`
+		Jitter           time.Duration `envconfig:"JOB_JITTER" default:"5m"`
+	}
+	// Notify.Config is the path of a JSON file routing notifications to Discord channels and
+	// webhooks, see notify.Config. Notifications are not sent when it is empty
+	Notify struct {
+		Config string `envconfig:"NOTIFY_CONFIG"`
 	}
-	UserAgent   string `envconfig:"USER_AGENT" required:"true"`
-	Environment string `envconfig:"ENVIRONMENT" required:"true"`
+	// ShutdownTimeout is how long in flight work is given to finish once
+	// the process is asked to stop before it is cancelled
+	ShutdownTimeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"30s"`
+	UserAgent       string        `envconfig:"USER_AGENT" required:"true"`
+	Environment     string        `envconfig:"ENVIRONMENT" required:"true"`
 }
 
 func buildConfig() {
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/internal/admin"
+	"github.com/eveisesi/krinder/internal/jobs"
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+)
+
+// Names of the scheduled jobs, under which their state is persisted
+const (
+	warsJob     = "wars"
+	universeJob = "universe"
+)
+
+// buildScheduler schedules the war and universe syncs. They write different data, so each has a
+// source of its own and a long universe sync does not hold back the wars
+func buildScheduler(s *services) (*jobs.Scheduler, error) {
+
+	scheduler := jobs.NewScheduler(logger, s.jobs, jobs.NewRedisLocker(s.redis))
+
+	warsSchedule, err := cron.ParseStandard(cfg.Jobs.WarsSchedule)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse WARS_SCHEDULE")
+	}
+
+	universeSchedule, err := cron.ParseStandard(cfg.Jobs.UniverseSchedule)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse UNIVERSE_SCHEDULE")
+	}
+
+	for _, job := range []jobs.Job{
+		{Name: warsJob, Source: "esi:wars", Schedule: warsSchedule, Jitter: cfg.Jobs.Jitter, Run: s.wars.Sync, Succeeded: s.wars.MarkComplete},
+		{Name: universeJob, Source: "esi:universe", Schedule: universeSchedule, Jitter: cfg.Jobs.Jitter, Run: s.universe.Sync, Succeeded: s.universe.MarkComplete},
+	} {
+		if err = scheduler.Add(job); err != nil {
+			return nil, err
+		}
+	}
+
+	return scheduler, nil
+
+}
+
+// prime calls complete when the job named name has succeeded before on any replica, as the scheduler
+// only reports successes once it starts
+func prime(ctx context.Context, repo krinder.JobRepository, name string, complete func()) error {
+	job, err := repo.Job(ctx, name)
+	if errors.Is(err, krinder.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to fetch state of job %s", name)
+	}
+
+	if !job.LastSuccess.IsZero() {
+		complete()
+	}
+
+	return nil
+}
+
+// interval approximates how often spec runs, for readiness checks on job freshness
+func interval(spec string) time.Duration {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return 0
+	}
+
+	next := schedule.Next(time.Now())
+	return schedule.Next(next).Sub(next)
+}
+
+// fresh returns a readiness check that fails when the job named name has not succeeded on any
+// replica within twice the interval of spec
+func fresh(scheduler *jobs.Scheduler, name, spec string) admin.Check {
+	maxAge := interval(spec)*2 + cfg.Jobs.Jitter
+	return func(ctx context.Context) error {
+		last, err := scheduler.LastSuccess(ctx, name)
+		if err != nil {
+			return err
+		}
+		return admin.Recent(func() time.Time { return last }, maxAge)(ctx)
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/eveisesi/krinder/internal/killright"
+	"github.com/eveisesi/krinder/pkg/errorcode"
+	"github.com/urfave/cli/v2"
+)
+
+func killrightCommand() *cli.Command {
+	return &cli.Command{
+		Name:    "killright",
+		Aliases: []string{"kr"},
+		Usage:   "Searches for kill rights and prints the report to stdout",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "format",
+				Aliases: []string{"f"},
+				Usage:   "Format of the list outputted. Options include " + strings.Join(killright.Formats(), ", "),
+				Value:   "simple",
+			},
+			&cli.StringFlag{
+				Name:  "as-of",
+				Usage: "Search as kill rights stood at a date (2021-11-15) or time (2021-11-15T18:00:00Z) within the last 90 days rather than now",
+			},
+		},
+		Subcommands: []*cli.Command{
+			{
+				Name:      "attacker",
+				Aliases:   []string{"a"},
+				Usage:     "Search for Kill Rights by Killmail Attacker",
+				ArgsUsage: "<characterID>",
+				Action:    killrightAttacker,
+			},
+			{
+				Name:      "victim",
+				Aliases:   []string{"v"},
+				Usage:     "Search for Kill Rights by Killmail Victim",
+				ArgsUsage: "<characterID>",
+				Action:    killrightVictim,
+			},
+			{
+				Name:      "mail",
+				Aliases:   []string{"m"},
+				Usage:     "Summarise a killmail and the kill rights it likely generated",
+				ArgsUsage: "<killmailID>",
+				Action:    killrightMail,
+			},
+			{
+				Name:      "ship",
+				Aliases:   []string{"s"},
+				Usage:     "Search for Kill Rights by Ship Group and optionally Ship Type",
+				ArgsUsage: "<shipGroupID> [shipTypeID]",
+				Action:    killrightShip,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "verbose",
+						Usage: "list the characters each victim may hold kill rights on",
+					},
+				},
+			},
+		},
+	}
+}
+
+// progress logs the progress of a search so that only the report is written to stdout
+func progress(message string) {
+	logger.WithField("service", "killright").Info(message)
+}
+
+func parseID(c *cli.Context, i int, name string) (uint64, error) {
+	id, err := strconv.ParseUint(c.Args().Get(i), 10, 64)
+	if err != nil {
+		return 0, errorcode.Wrapf(err, errorcode.InvalidArgument, "failed to parse %s", name)
+	}
+	return id, nil
+}
+
+// warn logs a warning attached to a report, keeping stdout for the report itself
+func warn(warning string) {
+	if warning != "" {
+		logger.WithField("service", "killright").Warn(warning)
+	}
+}
+
+// searcher returns the kill right service of s, evaluating kill rights as they stood at the --as-of option when it is set
+func searcher(c *cli.Context, s *services) (*killright.Service, error) {
+	if c.String("as-of") == "" {
+		return s.killright, nil
+	}
+
+	asOf, err := killright.ParseAsOf(c.String("as-of"), s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return s.killright.AsOf(asOf), nil
+}
+
+func writeLines(w io.Writer, header string, lines []string) error {
+	if header != "" {
+		lines = append([]string{header}, lines...)
+	}
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
+
+func killrightAttacker(c *cli.Context) error {
+
+	format, err := killright.ParseFormat(c.String("format"))
+	if err != nil {
+		return err
+	}
+
+	id, err := parseID(c, 0, "character id")
+	if err != nil {
+		return err
+	}
+
+	s, err := buildServices(c.Context)
+	if err != nil {
+		return err
+	}
+
+	kr, err := searcher(c, s)
+	if err != nil {
+		return err
+	}
+
+	report, err := kr.Attacker(c.Context, id, "", progress)
+	if err != nil {
+		return err
+	}
+
+	warn(report.Warning)
+
+	if format.Attached() {
+		return format.Export(c.App.Writer, report.Rows())
+	}
+
+	header := fmt.Sprintf("Found %d potential killrights", len(report.Killmails))
+	if legend := format.Legend(); legend != "" {
+		header = fmt.Sprintf("%s\n%s", header, legend)
+	}
+
+	return writeLines(c.App.Writer, header, report.Lines(format))
+
+}
+
+func killrightVictim(c *cli.Context) error {
+
+	format, err := killright.ParseFormat(c.String("format"))
+	if err != nil {
+		return err
+	}
+
+	id, err := parseID(c, 0, "character id")
+	if err != nil {
+		return err
+	}
+
+	s, err := buildServices(c.Context)
+	if err != nil {
+		return err
+	}
+
+	kr, err := searcher(c, s)
+	if err != nil {
+		return err
+	}
+
+	report, err := kr.Victim(c.Context, id, "", progress)
+	if err != nil {
+		return err
+	}
+
+	warn(report.Warning)
+
+	if format.Attached() {
+		return format.Export(c.App.Writer, report.Rows())
+	}
+
+	header := fmt.Sprintf("Found %d potential attacker(s) who this victim may have killrights for", len(report.Aggressors))
+	if format == killright.FormatEveLink {
+		header = fmt.Sprintf("%s\n%s", header, format.Legend())
+	}
+
+	return writeLines(c.App.Writer, header, report.Lines(format))
+
+}
+
+func killrightShip(c *cli.Context) error {
+
+	format, err := killright.ParseFormat(c.String("format"))
+	if err != nil {
+		return err
+	}
+
+	if c.Args().Len() == 0 || c.Args().Len() > 2 {
+		return errorcode.Newf(errorcode.InvalidArgument, "expected 1 or 2 args, got %d", c.Args().Len())
+	}
+
+	groupID, err := parseID(c, 0, "ship group id")
+	if err != nil {
+		return err
+	}
+
+	var shipTypeID uint64
+	if c.Args().Len() == 2 {
+		shipTypeID, err = parseID(c, 1, "ship type id")
+		if err != nil {
+			return err
+		}
+	}
+
+	s, err := buildServices(c.Context)
+	if err != nil {
+		return err
+	}
+
+	kr, err := searcher(c, s)
+	if err != nil {
+		return err
+	}
+
+	report, err := kr.Ship(c.Context, groupID, shipTypeID, progress)
+	if err != nil {
+		return err
+	}
+
+	warn(report.Warning)
+
+	if format.Attached() {
+		return format.Export(c.App.Writer, report.Rows())
+	}
+
+	if shipTypeID == 0 {
+		header := fmt.Sprintf("Found a total of %d potential kill rights across %d ships", report.Killmails, len(report.Ships))
+		return writeLines(c.App.Writer, header, report.SummaryLines())
+	}
+
+	header := fmt.Sprintf("Found %d victims who lost ship type %d", len(report.Victims), shipTypeID)
+	return writeLines(c.App.Writer, header, report.VictimLines(c.Bool("verbose")))
+
+}
+
+func killrightMail(c *cli.Context) error {
+
+	id, err := parseID(c, 0, "killmail id")
+	if err != nil {
+		return err
+	}
+
+	s, err := buildServices(c.Context)
+	if err != nil {
+		return err
+	}
+
+	summary, err := s.killright.Killmail(c.Context, id)
+	if err != nil {
+		return err
+	}
+
+	warn(summary.Warning)
+
+	return writeLines(c.App.Writer, "", summary.Lines())
+
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/eveisesi/krinder/pkg/correlation"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/natefinch/lumberjack.v2"
 )
@@ -22,13 +23,33 @@ func buildLogger() {
 		log.Panicf(fmt.Sprintf("failed to configure log level: %s", err))
 	}
 
+	var formatter logrus.Formatter
+	var prefix bool
+	switch cfg.Log.Format {
+	case "json":
+		formatter = &logrus.JSONFormatter{}
+	case "text":
+		formatter = &logrus.TextFormatter{
+			DisableQuote: true,
+		}
+		prefix = true
+	default:
+		log.Panicf("unsupported log format %s, expected one of text, json", cfg.Log.Format)
+	}
+
 	logger = logrus.New()
 
 	logger.SetOutput(ioutil.Discard)
 
+	// Hooks fire in the order they are added, so the correlation id is
+	// added to an entry before any of the writers format it
+	logger.AddHook(correlation.Hook{})
+
+	// Logs are written to stderr so that commands can write their output to stdout
 	logger.AddHook(&writerHook{
-		Writer:    os.Stdout,
-		LogLevels: logrus.AllLevels,
+		Writer:        os.Stderr,
+		LogLevels:     logrus.AllLevels,
+		PrefixService: prefix,
 	})
 
 	logger.AddHook(&writerHook{
@@ -43,6 +64,7 @@ func buildLogger() {
 			logrus.ErrorLevel,
 			logrus.WarnLevel,
 		},
+		PrefixService: prefix,
 	})
 
 	logger.AddHook(&writerHook{
@@ -55,33 +77,32 @@ func buildLogger() {
 		LogLevels: []logrus.Level{
 			logrus.InfoLevel,
 		},
+		PrefixService: prefix,
 	})
 
 	logger.SetLevel(level)
-	logger.SetFormatter(&logrus.TextFormatter{
-		DisableQuote: true,
-	})
+	logger.SetFormatter(formatter)
 
 }
 
 type writerHook struct {
 	Writer    io.Writer
 	LogLevels []logrus.Level
+	// PrefixService moves the service field in front of the message, which reads
+	// better in text logs than a trailing field
+	PrefixService bool
 }
 
 func (w *writerHook) Fire(entry *logrus.Entry) error {
 
-	data := entry.Data
-
-	message := entry.Message
-
-	var service string
-	var ok bool
-	if service, ok = data["service"].(string); ok {
-		message = fmt.Sprintf("[%s] %s", strings.ToLower(service), message)
-		delete(data, "service")
-		entry.Data = data
-		entry.Message = message
+	// The entry is shared with the other hooks, so it is copied before being changed
+	if service, ok := entry.Data["service"].(string); ok && w.PrefixService {
+		dup := entry.Dup()
+		delete(dup.Data, "service")
+		dup.Level = entry.Level
+		dup.Caller = entry.Caller
+		dup.Message = fmt.Sprintf("[%s] %s", strings.ToLower(service), entry.Message)
+		entry = dup
 	}
 
 	line, err := entry.Bytes()
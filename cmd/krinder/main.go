@@ -4,23 +4,25 @@ import (
 	"context"
 	"database/sql"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/eveisesi/krinder/internal/admin"
+	"github.com/eveisesi/krinder/internal/api"
+	"github.com/eveisesi/krinder/internal/bot"
+	"github.com/eveisesi/krinder/internal/chat"
 	"github.com/eveisesi/krinder/internal/discord"
-	"github.com/eveisesi/krinder/internal/esi"
-	"github.com/eveisesi/krinder/internal/store"
-	"github.com/eveisesi/krinder/internal/universe"
-	"github.com/eveisesi/krinder/internal/wars"
-	"github.com/eveisesi/krinder/internal/zkillboard"
+	"github.com/eveisesi/krinder/pkg/graceful"
 	"github.com/go-redis/redis/v8"
 	mysqlDriver "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
-	"github.com/robfig/cron/v3"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
 	"go.mongodb.org/mongo-driver/mongo"
 	mongoDriver "go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -37,80 +39,210 @@ func init() {
 	buildLogger()
 }
 
+// stopMetadata is the key of the function in App.Metadata that stops relaying signals to the
+// context of commands
+const stopMetadata = "stop"
+
 func main() {
-	// Initialize context with a timeout. This will prevent elongated issues with
-	// dependency connections. We have 5 seconds to establish a connection or we die
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	app := &cli.App{
+		Name:   "krinder",
+		Usage:  "Discord bot for finding kill rights in EVE Online",
+		Action: serve,
+		Commands: []*cli.Command{
+			{
+				Name:   "serve",
+				Usage:  "Runs the Discord bot along with the scheduled syncs. This is the default command",
+				Action: serve,
+			},
+			syncCommand(),
+			backfillCommand(),
+			cacheCommand(),
+			statusCommand(),
+			notifyCommand(),
+			apikeyCommand(),
+			replCommand(),
+			killrightCommand(),
+			whoCommand(),
+			importSDECommand(),
+			migrateGroupsCommand(),
+			migrateCommand(),
+		},
+	}
 
-	// Builds a redis client
-	redis := buildRedis(ctx)
+	// Every command receives a context that is cancelled when the process is asked to stop
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	app.Metadata = map[string]interface{}{stopMetadata: stop}
+
+	err := app.RunContext(ctx, os.Args)
+	if err != nil {
+		logger.WithError(err).Fatal("failed to run krinder")
+	}
+}
 
-	// Builds a mongo client
-	mongoConn := buildMongo(ctx)
+func serve(c *cli.Context) error {
 
-	mysqlConn := buildMySQL()
+	if cfg.Discord.Token == "" {
+		return errors.New("DISCORD_TOKEN must be set to serve the bot")
+	}
 
-	// Call cancel func. We are done with that context and can release the resources
-	cancel()
+	// The root context is cancelled when the process is asked to stop, which
+	// signals every service to stop accepting new work
+	ctx := c.Context
 
-	warsRepo, err := store.NewWarRepository(mongoConn.Database(cfg.Mongo.Database))
+	s, err := buildServices(ctx)
 	if err != nil {
-		logger.WithError(err).Fatal("failed to initialize wars repository")
+		return err
 	}
 
-	universeRepo, err := store.NewUniverseRepository(mysqlConn, mongoConn.Database(cfg.Mongo.Database))
+	redis, mongodb, universeRepo := s.redis, s.mongodb, s.universeRepo
+	wars, universe := s.wars, s.universe
+
+	scheduler, err := buildScheduler(s)
 	if err != nil {
-		logger.WithError(err).Fatal("failed to initialize universe repository")
+		return err
 	}
 
-	// Build out the services we want to use
-	zkb := zkillboard.New(cfg.UserAgent)
-	esi := esi.New(cfg.UserAgent, redis)
-	wars := wars.NewService(logger, esi, warsRepo)
-	wars.Run()
+	discord := discord.New(cfg.Discord.Token, cfg.Environment, logger, s.clock)
+	bot := bot.New(logger, discord, s.esi, s.killright, universe, wars, s.sso, s.paginations, s.clock)
 
-	universe := universe.New(logger, redis, esi, universeRepo)
-	universe.Run()
-
-	cn := cron.New()
-	_, err = cn.AddJob("@every 3h", wars)
+	err = routeNotifications(s, map[string]chat.Transport{"discord": discord})
 	if err != nil {
-		logger.WithError(err).Fatal("failed to add job to cron scheduler")
+		return err
 	}
-	_, err = cn.AddJob("@every 24h", universe)
-	if err != nil {
-		logger.WithError(err).Fatal("failed to add job to cron scheduler")
+
+	var adminServer *admin.Server
+	if cfg.Admin.Addr != "" {
+		adminServer = admin.New(logger, cfg.Admin.Addr)
+		adminServer.AddCheck("discord", discord.Ready)
+		adminServer.AddCheck("redis", func(ctx context.Context) error {
+			return redis.Ping(ctx).Err()
+		})
+		if mongodb != nil {
+			adminServer.AddCheck("mongo", func(ctx context.Context) error {
+				return mongodb.Client().Ping(ctx, nil)
+			})
+		}
+		if pinger, ok := universeRepo.(interface{ Ping(context.Context) error }); ok {
+			adminServer.AddCheck(universeStore(), pinger.Ping)
+		}
+		adminServer.AddCheck(warsJob, fresh(scheduler, warsJob, cfg.Jobs.WarsSchedule))
+		adminServer.AddCheck(universeJob, fresh(scheduler, universeJob, cfg.Jobs.UniverseSchedule))
+		adminServer.AddJob(wars.Status)
+		adminServer.AddJob(universe.Status)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			adminServer.Run()
+		}()
+	}
+
+	var apiServer *api.Server
+	if cfg.API.Addr != "" {
+		apiServer = api.New(logger, cfg.API.Addr, s.apiKeys, api.NewRedisLimiter(redis), cfg.API.RateLimit, s.killright, wars, universe)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			apiServer.Run()
+		}()
+	}
+
+	var ssoServer *http.Server
+	if s.sso.Enabled() {
+		ssoServer, err = buildSSOServer(s, discord)
+		if err != nil {
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.WithField("service", "sso").WithField("addr", ssoServer.Addr).Info("starting sso callback server")
+			err := ssoServer.ListenAndServe()
+			if err != nil && err != http.ErrServerClosed {
+				logger.WithError(err).WithField("service", "sso").Error("sso callback server stopped unexpectedly")
+			}
+		}()
 	}
-	done := make(chan bool, 1)
+
+	// Work started before shutdown is given until the shutdown timeout to finish
+	work, cancelWork := graceful.WithGrace(ctx, cfg.ShutdownTimeout)
+	defer cancelWork()
+
+	// Notifications raised by the syncs are delivered in the background, so that a slow
+	// destination does not hold up the sync that raised them
 	wg.Add(1)
-	go func(cn *cron.Cron, done chan bool, wg *sync.WaitGroup) {
+	go func() {
 		defer wg.Done()
-		cn.Start()
-		<-done
-		entry := logger.WithField("service", "cron")
-		entry.Info("hold channel received value, closing session")
-
-		entry.Info("waiting for context to be marked done")
-		<-cn.Stop().Done()
-		entry.Info("context is done, terminating go routine")
+		s.notify.Run(ctx, work)
+	}()
 
-	}(cn, done, wg)
+	// Jobs that have never run are due straight away, so the initial syncs run in the
+	// background while commands answer with a warning until the data is complete
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		scheduler.Run(ctx, work)
+		logger.WithField("service", "scheduler").Info("running jobs finished, scheduler stopped")
+	}()
 
-	// The discord service is the root service of this application.
-	// It maintains a connection to the Discord Gateway and processes all commands
-	// that users may issue via that gateway
+	// The bot is the root service of this application. It maintains a connection
+	// to the Discord Gateway and processes all commands that users may issue via that gateway
 	wg.Add(1)
-	go discord.New(cfg.Discord.Token, cfg.Environment, logger, zkb, esi, wars, universe).Run(done, wg)
+	go bot.Run(ctx, work, wg)
 
-	sc := make(chan os.Signal, 1)
-	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
-	<-sc
+	<-ctx.Done()
+	// Restore the default signal behaviour so that a second signal terminates immediately
+	if stop, ok := c.App.Metadata[stopMetadata].(context.CancelFunc); ok {
+		stop()
+	}
+	logger.WithField("timeout", cfg.ShutdownTimeout).Info("shutting down, waiting for in flight work to finish")
+
+	if adminServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		err = adminServer.Shutdown(ctx)
+		cancel()
+		if err != nil {
+			logger.WithError(err).Error("failed to shutdown admin server")
+		}
+	}
 
-	for i := 0; i < 2; i++ {
-		done <- true
+	if ssoServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		err = ssoServer.Shutdown(ctx)
+		cancel()
+		if err != nil {
+			logger.WithError(err).Error("failed to shutdown sso callback server")
+		}
 	}
 
-	wg.Wait()
+	if apiServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		err = apiServer.Shutdown(ctx)
+		cancel()
+		if err != nil {
+			logger.WithError(err).Error("failed to shutdown api server")
+		}
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(stopped)
+	}()
+
+	// Work is cancelled once the shutdown timeout passes, so allow a
+	// little longer for cancelled work to unwind and notify users
+	select {
+	case <-stopped:
+		logger.Info("shutdown complete")
+	case <-time.After(cfg.ShutdownTimeout + time.Second*10):
+		logger.Warn("timed out waiting for services to stop")
+	}
+
+	return nil
 
 }
 
@@ -129,6 +261,11 @@ func buildRedis(ctx context.Context) *redis.Client {
 
 func buildMongo(ctx context.Context) *mongo.Client {
 
+	m := cfg.Mongo
+	if m.Host == "" || m.Database == "" || m.User == "" || m.Pass == "" {
+		logger.Fatal("MONGO_HOST, MONGO_DATABASE, MONGO_USER and MONGO_PASS must be set to use mongo")
+	}
+
 	clientOpts := options.Client()
 	clientOpts.SetAppName("krinder-discord-bot")
 	clientOpts.SetHosts([]string{cfg.Mongo.Host})
@@ -140,12 +277,12 @@ func buildMongo(ctx context.Context) *mongo.Client {
 
 	client, err := mongoDriver.Connect(ctx, clientOpts)
 	if err != nil {
-		logger.WithError(err).Error("failed to connect to mongo db")
+		logger.WithError(err).Fatal("failed to connect to mongo db")
 	}
 
 	err = client.Ping(ctx, nil)
 	if err != nil {
-		logger.WithError(err).Error("failed to ping mongo db")
+		logger.WithError(err).Fatal("failed to ping mongo db")
 	}
 
 	return client
@@ -154,6 +291,9 @@ func buildMongo(ctx context.Context) *mongo.Client {
 func buildMySQL() *sqlx.DB {
 
 	m := cfg.MySQL
+	if m.Host == "" || m.User == "" || m.DB == "" {
+		log.Panic("[MySQL Connect] MYSQL_HOST, MYSQL_USER and MYSQL_DB must be set to use mysql")
+	}
 
 	config := mysqlDriver.Config{
 		User:                 m.User,
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/eveisesi/krinder/internal/store"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+)
+
+func migrateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "migrate",
+		Usage: "Manages the schema of the MySQL store",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "up",
+				Usage:  "Applies all pending migrations",
+				Action: migrateUp,
+			},
+			{
+				Name:   "down",
+				Usage:  "Reverts the most recently applied migration",
+				Action: migrateDown,
+			},
+			{
+				Name:   "status",
+				Usage:  "Lists every migration and when it was applied",
+				Action: migrateStatus,
+			},
+		},
+	}
+}
+
+// prepareMySQL is called at startup when MySQL is the configured store. Pending migrations are applied when
+// MYSQL_AUTO_MIGRATE is enabled. Either way, startup fails if the schema is newer than this binary supports
+func prepareMySQL(db *sqlx.DB) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	migrator, err := store.NewMigrator(db)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize migrator")
+	}
+
+	if !cfg.MySQL.AutoMigrate {
+		return migrator.Check(ctx)
+	}
+
+	ran, err := migrator.Up(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to migrate mysql")
+	}
+
+	for _, migration := range ran {
+		logger.WithField("version", migration.Version).Infof("applied migration %s", migration.Name)
+	}
+
+	return nil
+
+}
+
+func migrateUp(c *cli.Context) error {
+
+	migrator, err := store.NewMigrator(buildMySQL())
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize migrator")
+	}
+
+	ran, err := migrator.Up(c.Context)
+	for _, migration := range ran {
+		logger.WithField("version", migration.Version).Infof("applied migration %s", migration.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(ran) == 0 {
+		logger.Info("no pending migrations")
+	}
+
+	return nil
+
+}
+
+func migrateDown(c *cli.Context) error {
+
+	migrator, err := store.NewMigrator(buildMySQL())
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize migrator")
+	}
+
+	migration, err := migrator.Down(c.Context)
+	if err != nil {
+		return err
+	}
+
+	if migration == nil {
+		logger.Info("no migrations to revert")
+		return nil
+	}
+
+	logger.WithField("version", migration.Version).Infof("reverted migration %s", migration.Name)
+
+	return nil
+
+}
+
+func migrateStatus(c *cli.Context) error {
+
+	migrator, err := store.NewMigrator(buildMySQL())
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize migrator")
+	}
+
+	statuses, err := migrator.Status(c.Context)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+
+	return w.Flush()
+
+}
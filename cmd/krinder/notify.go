@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/internal/notify"
+	"github.com/urfave/cli/v2"
+)
+
+func notifyCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "notify",
+		Usage: "Inspects the delivery of notifications routed by NOTIFY_CONFIG",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "test",
+				Usage:  "Sends a test notification to the webhooks routed for a kind",
+				Action: notifyTest,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "kind",
+						Usage: "kind of notification to send",
+						Value: notify.KindWarDeclared,
+					},
+				},
+			},
+			{
+				Name:   "dead-letters",
+				Usage:  "Lists the most recent notifications that could not be delivered",
+				Action: notifyDeadLetters,
+				Flags: []cli.Flag{
+					&cli.Int64Flag{
+						Name:  "limit",
+						Usage: "maximum number of dead letters to list",
+						Value: 20,
+					},
+				},
+			},
+		},
+	}
+}
+
+func notifyTest(c *cli.Context) error {
+
+	s, err := buildServices(c.Context)
+	if err != nil {
+		return err
+	}
+
+	err = routeNotifications(s, nil)
+	if err != nil {
+		return err
+	}
+
+	err = s.notify.Deliver(c.Context, &notify.Notification{
+		Kind:  c.String("kind"),
+		Title: "Test notification",
+		Body:  "Sent by krinder notify test",
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(c.App.Writer, "notification sent")
+	return err
+
+}
+
+func notifyDeadLetters(c *cli.Context) error {
+
+	s, err := buildServices(c.Context)
+	if err != nil {
+		return err
+	}
+
+	letters, err := s.deadLetters.DeadLetters(c.Context, krinder.NewOrderOperator("createdAt", krinder.SortDesc), krinder.NewLimitOperator(c.Int64("limit")))
+	if err != nil {
+		return err
+	}
+
+	lines := make([]string, 0, len(letters))
+	for _, letter := range letters {
+		lines = append(lines, fmt.Sprintf("%s %s %s after %d attempts: %s", letter.CreatedAt.Format(time.RFC3339), letter.Kind, letter.Sink, letter.Attempts, letter.Error))
+	}
+
+	return writeLines(c.App.Writer, fmt.Sprintf("%d dead letters", len(letters)), lines)
+
+}
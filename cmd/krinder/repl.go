@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+
+	"github.com/eveisesi/krinder/internal/bot"
+	"github.com/eveisesi/krinder/internal/chat"
+	"github.com/eveisesi/krinder/internal/repl"
+	"github.com/urfave/cli/v2"
+)
+
+func replCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "repl",
+		Usage:  "Reads bot commands from the terminal and prints the replies, using the configured backends. Set STORE=memory to run without databases",
+		Action: runREPL,
+	}
+}
+
+func runREPL(c *cli.Context) error {
+
+	s, err := buildServices(c.Context)
+	if err != nil {
+		return err
+	}
+
+	terminal := repl.New(os.Stdin, c.App.Writer)
+	bot := bot.New(logger, terminal, s.esi, s.killright, s.universe, s.wars, s.sso, s.paginations, s.clock)
+
+	// Commands are handled one at a time as they are read, so input piped
+	// into the REPL is handled in full before it stops
+	err = terminal.Open(c.Context, func(msg *chat.Message) {
+		bot.Handle(c.Context, msg)
+	})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-terminal.Done():
+	case <-c.Context.Done():
+	}
+
+	return terminal.Close()
+
+}
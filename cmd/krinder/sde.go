@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/eveisesi/krinder/internal/sde"
+	"github.com/eveisesi/krinder/pkg/clock"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+func importSDECommand() *cli.Command {
+	return &cli.Command{
+		Name:      "import-sde",
+		Usage:     "Seeds the groups and entities from a local copy of CCP's Static Data Export",
+		UsageText: "krinder import-sde [command options] <path to sde.zip or extracted sde directory>",
+		Flags: []cli.Flag{
+			&cli.DurationFlag{
+				Name:  "expires-after",
+				Usage: "how long imported records are considered fresh before the universe sync refreshes them from ESI",
+				Value: time.Hour * 24 * 30,
+			},
+		},
+		Action: importSDE,
+	}
+}
+
+func importSDE(c *cli.Context) error {
+
+	src := c.Args().First()
+	if src == "" {
+		return errors.New("path to the sde is required")
+	}
+
+	if universeStore() == "memory" {
+		return errors.New("importing the sde into the memory store has no effect, set STORE or UNIVERSE_STORE to a persistent store")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	mongodb := buildMongoDatabase(ctx, universeStore())
+	cancel()
+
+	now := clock.New()
+
+	universeRepo, err := buildUniverseRepository(mongodb, now)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize universe repository")
+	}
+
+	logger.WithField("src", src).Info("importing sde")
+
+	result, err := sde.New(logger, universeRepo, now).Import(c.Context, src, c.Duration("expires-after"))
+	if err != nil {
+		return errors.Wrap(err, "failed to import sde")
+	}
+
+	logger.WithFields(logrus.Fields{
+		"groupsCreated":     result.GroupsCreated,
+		"groupsUpdated":     result.GroupsUpdated,
+		"groupsUnchanged":   result.GroupsUnchanged,
+		"entitiesCreated":   result.EntitiesCreated,
+		"entitiesUpdated":   result.EntitiesUpdated,
+		"entitiesUnchanged": result.EntitiesUnchanged,
+	}).Info("sde import complete")
+
+	return nil
+
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/internal/chat"
+	"github.com/eveisesi/krinder/internal/esi"
+	"github.com/eveisesi/krinder/internal/killright"
+	"github.com/eveisesi/krinder/internal/notify"
+	"github.com/eveisesi/krinder/internal/sso"
+	"github.com/eveisesi/krinder/internal/universe"
+	"github.com/eveisesi/krinder/internal/wars"
+	"github.com/eveisesi/krinder/internal/zkillboard"
+	"github.com/eveisesi/krinder/pkg/clock"
+	"github.com/eveisesi/krinder/pkg/roundtripper"
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// services holds the dependencies and services shared by serve and the maintenance commands
+type services struct {
+	clock        clock.Clock
+	redis        *redis.Client
+	mongodb      *mongo.Database
+	universeRepo krinder.UniverseRepository
+	jobs         krinder.JobRepository
+	deadLetters  krinder.DeadLetterRepository
+	apiKeys      krinder.APIKeyRepository
+	paginations  krinder.PaginationRepository
+
+	notify    *notify.Router
+	esi       esi.API
+	zkb       *zkillboard.Service
+	wars      *wars.Service
+	universe  *universe.Service
+	killright *killright.Service
+	sso       *sso.Service
+}
+
+// buildServices connects to the configured stores and builds every service. Connections
+// must be established within 10 seconds
+func buildServices(ctx context.Context) (*services, error) {
+
+	connCtx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+
+	s := &services{clock: clock.New()}
+
+	s.redis = buildRedis(connCtx)
+
+	// A mongo database is only built if any of the repositories are backed by mongo
+	s.mongodb = buildMongoDatabase(connCtx, cfg.Store, universeStore())
+
+	warsRepo, err := buildWarRepository(s.mongodb, s.clock)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to initialize wars repository")
+	}
+
+	s.universeRepo, err = buildUniverseRepository(s.mongodb, s.clock)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to initialize universe repository")
+	}
+
+	s.jobs, err = buildJobRepository(s.mongodb)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to initialize job repository")
+	}
+
+	s.deadLetters, err = buildDeadLetterRepository(s.mongodb)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to initialize dead letter repository")
+	}
+
+	s.apiKeys, err = buildAPIKeyRepository(s.mongodb)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to initialize api key repository")
+	}
+
+	s.paginations, err = buildPaginationRepository(s.mongodb, s.clock)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to initialize pagination repository")
+	}
+
+	// Notifications are dropped until the commands that raise them add routes, see routeNotifications
+	s.notify = notify.NewRouter(logger, s.deadLetters)
+
+	esiOptions := []esi.Option{esi.WithBaseURL(cfg.Upstream.ESI)}
+	zkbOptions := []zkillboard.Option{zkillboard.WithBaseURL(cfg.Upstream.ZKillboard)}
+	if cfg.Upstream.Fixtures != "" {
+		mode, err := roundtripper.ParseMode(cfg.Upstream.FixturesMode)
+		if err != nil {
+			return nil, err
+		}
+
+		logger.WithField("dir", cfg.Upstream.Fixtures).WithField("mode", mode).Warn("upstream requests use fixtures")
+		recorder := roundtripper.NewRecorder(cfg.Upstream.Fixtures, mode, http.DefaultTransport)
+		esiOptions = append(esiOptions, esi.WithTransport(recorder))
+		zkbOptions = append(zkbOptions, zkillboard.WithTransport(recorder))
+	}
+
+	s.esi = esi.New(logger, cfg.UserAgent, s.redis, esiOptions...)
+	s.zkb = zkillboard.New(logger, cfg.UserAgent, zkbOptions...)
+	s.wars = wars.NewService(logger, s.esi, warsRepo, s.notify, s.clock)
+	s.universe = universe.New(logger, s.redis, s.esi, s.universeRepo, s.clock)
+
+	s.sso, err = buildSSO(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to initialize sso")
+	}
+
+	var tokens killright.TokenSource
+	if s.sso.Enabled() {
+		tokens = s.sso
+	}
+	s.killright = killright.New(logger, s.zkb, s.esi, s.wars, s.universe, tokens, s.clock)
+
+	// Data left by a sync that succeeded before is complete enough to answer commands with
+	if err = prime(connCtx, s.jobs, warsJob, s.wars.MarkComplete); err != nil {
+		return nil, err
+	}
+
+	if err = prime(connCtx, s.jobs, universeJob, s.universe.MarkComplete); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+
+}
+
+// routeNotifications adds the routes configured by NOTIFY_CONFIG to the router. Destinations
+// on transports missing from transports are skipped
+func routeNotifications(s *services, transports map[string]chat.Transport) error {
+
+	if cfg.Notify.Config == "" {
+		return nil
+	}
+
+	config, err := notify.LoadConfig(cfg.Notify.Config)
+	if err != nil {
+		return err
+	}
+
+	return config.Apply(s.notify, transports, cfg.UserAgent)
+
+}
+
+// buildSSO returns the sso service, which is disabled when SSO_CLIENT_ID is not set
+func buildSSO(s *services) (*sso.Service, error) {
+
+	credentials, err := buildCredentialRepository(s.mongodb)
+	if err != nil {
+		return nil, err
+	}
+
+	config := sso.Config{
+		ClientID:     cfg.SSO.ClientID,
+		ClientSecret: cfg.SSO.ClientSecret,
+		CallbackURL:  cfg.SSO.CallbackURL,
+		Scopes:       cfg.SSO.Scopes,
+	}
+
+	if config.ClientID == "" {
+		return sso.New(logger, config, nil, credentials, nil, s.clock), nil
+	}
+
+	if config.CallbackURL == "" || cfg.SSO.EncryptionKey == "" {
+		return nil, errors.New("SSO_CALLBACK_URL and SSO_ENCRYPTION_KEY must be set when SSO_CLIENT_ID is set")
+	}
+
+	cipher, err := sso.NewCipher(cfg.SSO.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return sso.New(logger, config, sso.NewRedisStateStore(s.redis), credentials, cipher, s.clock), nil
+
+}
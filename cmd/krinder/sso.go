@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/internal/chat"
+	"github.com/eveisesi/krinder/internal/sso"
+	"github.com/pkg/errors"
+)
+
+// buildSSOServer returns the server handling redirects from EVE SSO at the path of SSO_CALLBACK_URL.
+// Users are told in the channel they ran link in once their character is linked
+func buildSSOServer(s *services, transport chat.Transport) (*http.Server, error) {
+
+	callback, err := url.Parse(cfg.SSO.CallbackURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid SSO_CALLBACK_URL")
+	}
+
+	linked := func(ctx context.Context, credential *krinder.Credential, pending *sso.Pending) {
+		content := fmt.Sprintf("%s is now linked", credential.CharacterName)
+		if credential.Main {
+			content += " and is your main character"
+		}
+
+		_, err := transport.Reply(ctx, &chat.Message{ChannelID: pending.ChannelID, AuthorID: pending.UserID}, content)
+		if err != nil {
+			logger.WithContext(ctx).WithError(err).WithField("service", "sso").Error("failed to send message")
+		}
+	}
+
+	path := callback.Path
+	if path == "" {
+		path = "/"
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(path, s.sso.CallbackHandler(linked))
+
+	return &http.Server{
+		Addr:         cfg.SSO.Addr,
+		Handler:      mux,
+		ReadTimeout:  time.Second * 5,
+		WriteTimeout: time.Second * 45,
+	}, nil
+
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/eveisesi/krinder/internal/jobs"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+)
+
+func statusCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "status",
+		Usage:  "Shows the progress of the syncs of a running bot, read from its admin server",
+		Action: status,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "addr",
+				Usage: "address of the admin server, defaults to ADMIN_ADDR",
+			},
+		},
+	}
+}
+
+func status(c *cli.Context) error {
+
+	addr := c.String("addr")
+	if addr == "" {
+		addr = cfg.Admin.Addr
+	}
+	if addr == "" {
+		return errors.New("ADMIN_ADDR or --addr must be set to read the status of the bot")
+	}
+
+	// A listen address without a host refers to the local machine
+	if strings.HasPrefix(addr, ":") {
+		addr = "localhost" + addr
+	}
+
+	req, err := http.NewRequestWithContext(c.Context, http.MethodGet, fmt.Sprintf("http://%s/status", addr), nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to build status request")
+	}
+
+	client := &http.Client{Timeout: time.Second * 10}
+	res, err := client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to reach admin server")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status code %d from admin server", res.StatusCode)
+	}
+
+	var statuses []jobs.Status
+	err = json.NewDecoder(res.Body).Decode(&statuses)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode status")
+	}
+
+	lines := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		lines = append(lines, status.String())
+	}
+
+	return writeLines(c.App.Writer, "", lines)
+
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/internal/store"
+	"github.com/eveisesi/krinder/internal/store/memory"
+	"github.com/eveisesi/krinder/pkg/clock"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// buildMongoDatabase connects to mongo when any of the provided stores is mongo
+// and returns nil otherwise, so that the memory store can be used without a database
+func buildMongoDatabase(ctx context.Context, stores ...string) *mongo.Database {
+	for _, s := range stores {
+		if s == "mongo" {
+			return buildMongo(ctx).Database(cfg.Mongo.Database)
+		}
+	}
+	return nil
+}
+
+// buildWarRepository returns the war repository for the backend configured by STORE
+func buildWarRepository(mongodb *mongo.Database, clock clock.Clock) (krinder.WarRepository, error) {
+	switch cfg.Store {
+	case "mongo":
+		return store.NewWarRepository(mongodb, clock)
+	case "memory":
+		return memory.NewWarRepository(clock), nil
+	default:
+		return nil, errors.Errorf("unsupported store %s, expected one of mongo, memory", cfg.Store)
+	}
+}
+
+// buildJobRepository returns the job repository for the backend configured by STORE
+func buildJobRepository(mongodb *mongo.Database) (krinder.JobRepository, error) {
+	switch cfg.Store {
+	case "mongo":
+		return store.NewJobRepository(mongodb)
+	case "memory":
+		return memory.NewJobRepository(), nil
+	default:
+		return nil, errors.Errorf("unsupported store %s, expected one of mongo, memory", cfg.Store)
+	}
+}
+
+// buildDeadLetterRepository returns the dead letter repository for the backend configured by STORE
+func buildDeadLetterRepository(mongodb *mongo.Database) (krinder.DeadLetterRepository, error) {
+	switch cfg.Store {
+	case "mongo":
+		return store.NewDeadLetterRepository(mongodb)
+	case "memory":
+		return memory.NewDeadLetterRepository(), nil
+	default:
+		return nil, errors.Errorf("unsupported store %s, expected one of mongo, memory", cfg.Store)
+	}
+}
+
+// buildAPIKeyRepository returns the api key repository for the backend configured by STORE
+func buildAPIKeyRepository(mongodb *mongo.Database) (krinder.APIKeyRepository, error) {
+	switch cfg.Store {
+	case "mongo":
+		return store.NewAPIKeyRepository(mongodb)
+	case "memory":
+		return memory.NewAPIKeyRepository(), nil
+	default:
+		return nil, errors.Errorf("unsupported store %s, expected one of mongo, memory", cfg.Store)
+	}
+}
+
+// buildCredentialRepository returns the credential repository for the backend configured by STORE
+func buildCredentialRepository(mongodb *mongo.Database) (krinder.CredentialRepository, error) {
+	switch cfg.Store {
+	case "mongo":
+		return store.NewCredentialRepository(mongodb)
+	case "memory":
+		return memory.NewCredentialRepository(), nil
+	default:
+		return nil, errors.Errorf("unsupported store %s, expected one of mongo, memory", cfg.Store)
+	}
+}
+
+// buildPaginationRepository returns the pagination repository for the backend configured by STORE
+func buildPaginationRepository(mongodb *mongo.Database, clock clock.Clock) (krinder.PaginationRepository, error) {
+	switch cfg.Store {
+	case "mongo":
+		return store.NewPaginationRepository(mongodb, clock)
+	case "memory":
+		return memory.NewPaginationRepository(clock), nil
+	default:
+		return nil, errors.Errorf("unsupported store %s, expected one of mongo, memory", cfg.Store)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"github.com/eveisesi/krinder/internal/esi"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+func syncCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "sync",
+		Usage: "Runs one of the scheduled syncs once and exits",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "wars",
+				Usage:  "Checks ESI for new wars and refreshes the wars that are due for an update",
+				Action: syncWars,
+			},
+			{
+				Name:   "universe",
+				Usage:  "Refreshes the universe groups that have expired",
+				Action: syncUniverse,
+			},
+		},
+	}
+}
+
+func syncWars(c *cli.Context) error {
+
+	s, err := buildServices(c.Context)
+	if err != nil {
+		return err
+	}
+
+	// Discord is not connected outside of serve, so only webhooks are notified
+	err = routeNotifications(s, nil)
+	if err != nil {
+		return err
+	}
+
+	return runJob(s, c, warsJob)
+
+}
+
+func syncUniverse(c *cli.Context) error {
+
+	s, err := buildServices(c.Context)
+	if err != nil {
+		return err
+	}
+
+	return runJob(s, c, universeJob)
+
+}
+
+// runJob runs the scheduled job named name once. It takes the lock of scheduled runs, so it refuses to
+// run while a replica that is serving runs the job
+func runJob(s *services, c *cli.Context, name string) error {
+
+	scheduler, err := buildScheduler(s)
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrapf(scheduler.RunOnce(c.Context, name), "failed to sync %s", name)
+
+}
+
+func backfillCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "backfill",
+		Usage: "Fills in historical data that the scheduled syncs do not reach",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "wars",
+				Usage:  "Pages backwards through the wars known to ESI and creates any that are missing",
+				Action: backfillWars,
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "pages",
+						Usage: "maximum number of pages of 2000 war ids to read, 0 reads every page",
+						Value: 0,
+					},
+				},
+			},
+		},
+	}
+}
+
+func backfillWars(c *cli.Context) error {
+
+	s, err := buildServices(c.Context)
+	if err != nil {
+		return err
+	}
+
+	created, err := s.wars.Backfill(c.Context, c.Int("pages"))
+	logger.WithFields(logrus.Fields{
+		"service": "wars",
+		"created": created,
+	}).Info("backfill finished")
+
+	return errors.Wrap(err, "failed to backfill wars")
+
+}
+
+func cacheCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "cache",
+		Usage: "Manages the cache of ESI responses",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "flush",
+				Usage:  "Deletes every cached ESI response",
+				Action: cacheFlush,
+			},
+		},
+	}
+}
+
+func cacheFlush(c *cli.Context) error {
+
+	// Only redis is needed, so skip building the stores
+	cache := buildRedis(c.Context)
+	defer cache.Close()
+
+	deleted, err := esi.New(logger, cfg.UserAgent, cache).FlushCache(c.Context)
+	if err != nil {
+		return err
+	}
+
+	logger.WithField("deleted", deleted).Info("flushed esi cache")
+
+	return nil
+
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/internal/store"
+	"github.com/eveisesi/krinder/internal/store/memory"
+	"github.com/eveisesi/krinder/pkg/clock"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// universeStore returns the backend configured by UNIVERSE_STORE, falling back to STORE
+func universeStore() string {
+	if cfg.Universe.Store != "" {
+		return cfg.Universe.Store
+	}
+	return cfg.Store
+}
+
+// buildUniverseRepository returns the universe repository for the backend configured
+// by universeStore. mongodb may be nil when that backend is not mongo
+func buildUniverseRepository(mongodb *mongo.Database, clock clock.Clock) (krinder.UniverseRepository, error) {
+	switch universeStore() {
+	case "mongo":
+		return store.NewUniverseRepository(mongodb, clock)
+	case "memory":
+		return memory.NewUniverseRepository(clock), nil
+	case "mysql":
+		db := buildMySQL()
+		err := prepareMySQL(db)
+		if err != nil {
+			return nil, err
+		}
+		return store.NewMySQLUniverseRepository(db, clock)
+	default:
+		return nil, errors.Errorf("unsupported universe store %s, expected one of mongo, mysql, memory", universeStore())
+	}
+}
+
+func migrateGroupsCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "migrate-groups",
+		Usage:  "Copies the groups stored in MySQL into Mongo so that MySQL can be retired",
+		Action: migrateGroups,
+	}
+}
+
+func migrateGroups(c *cli.Context) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	mongoConn := buildMongo(ctx)
+	cancel()
+
+	source, err := store.NewMySQLUniverseRepository(buildMySQL(), clock.New())
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize mysql universe repository")
+	}
+
+	destination, err := store.NewUniverseRepository(mongoConn.Database(cfg.Mongo.Database), clock.New())
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize mongo universe repository")
+	}
+
+	groups, err := source.Groups(c.Context)
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch groups from mysql")
+	}
+
+	logger.WithField("groups", len(groups)).Info("copying groups from mysql to mongo")
+
+	var created, updated int
+	for _, group := range groups {
+		_, err := destination.Group(c.Context, group.GroupID)
+		switch {
+		case errors.Is(err, krinder.ErrNotFound):
+			_, err = destination.CreateGroup(c.Context, group)
+			created++
+		case err == nil:
+			_, err = destination.UpdateGroup(c.Context, group)
+			updated++
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to copy group %d", group.GroupID)
+		}
+	}
+
+	logger.WithFields(logrus.Fields{
+		"created": created,
+		"updated": updated,
+	}).Info("groups copied from mysql to mongo")
+
+	return nil
+
+}
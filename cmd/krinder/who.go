@@ -0,0 +1,44 @@
+package main
+
+import (
+	"github.com/urfave/cli/v2"
+)
+
+func whoCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "who",
+		Usage:     "Profiles a character for hunting and prints it to stdout",
+		ArgsUsage: "<characterID>",
+		Action:    who,
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  "limit",
+				Usage: "Number of entries listed in each section of the profile, 0 lists every entry",
+				Value: 10,
+			},
+		},
+	}
+}
+
+func who(c *cli.Context) error {
+
+	id, err := parseID(c, 0, "character id")
+	if err != nil {
+		return err
+	}
+
+	s, err := buildServices(c.Context)
+	if err != nil {
+		return err
+	}
+
+	profile, err := s.killright.Profile(c.Context, id, "", progress)
+	if err != nil {
+		return err
+	}
+
+	warn(profile.Warning)
+
+	return writeLines(c.App.Writer, "", profile.Lines(c.Int("limit")))
+
+}
@@ -0,0 +1,31 @@
+package krinder
+
+import (
+	"context"
+	"time"
+)
+
+// CredentialRepository stores the EVE SSO tokens of the characters chat users have linked
+type CredentialRepository interface {
+	Credential(ctx context.Context, userID string, characterID uint64) (*Credential, error)
+	Credentials(ctx context.Context, operators ...*Operator) ([]*Credential, error)
+	// SaveCredential creates or replaces the credential of the user and character
+	SaveCredential(ctx context.Context, credential *Credential) error
+	DeleteCredential(ctx context.Context, userID string, characterID uint64) error
+}
+
+type Credential struct {
+	// UserID is the id of the chat user that linked the character
+	UserID        string `bson:"userID" json:"userID"`
+	CharacterID   uint64 `bson:"characterID" json:"characterID"`
+	CharacterName string `bson:"characterName" json:"characterName"`
+	// Main marks the character commands default to when the user does not name one
+	Main   bool     `bson:"main" json:"main"`
+	Scopes []string `bson:"scopes" json:"scopes"`
+	// RefreshToken and AccessToken are stored encrypted
+	RefreshToken    string    `bson:"refreshToken" json:"-"`
+	AccessToken     string    `bson:"accessToken" json:"-"`
+	AccessExpiresAt time.Time `bson:"accessExpiresAt" json:"accessExpiresAt"`
+	CreatedAt       time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt       time.Time `bson:"updatedAt" json:"updatedAt"`
+}
@@ -0,0 +1,24 @@
+package krinder
+
+import (
+	"context"
+	"time"
+)
+
+// DeadLetterRepository keeps the notifications that could not be delivered, so that they
+// can be inspected and replayed
+type DeadLetterRepository interface {
+	CreateDeadLetter(ctx context.Context, letter *DeadLetter) error
+	DeadLetters(ctx context.Context, operators ...*Operator) ([]*DeadLetter, error)
+}
+
+type DeadLetter struct {
+	// Sink names the destination the notification could not be delivered to, e.g. webhook:dashboard
+	Sink string `bson:"sink" json:"sink"`
+	Kind string `bson:"kind" json:"kind"`
+	// Payload is the notification encoded as JSON
+	Payload   string    `bson:"payload" json:"payload"`
+	Error     string    `bson:"error" json:"error"`
+	Attempts  int       `bson:"attempts" json:"attempts"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,7 @@
+package krinder
+
+import "github.com/eveisesi/krinder/pkg/errorcode"
+
+// ErrNotFound is returned by repositories when the requested record does not exist,
+// regardless of which database is backing the repository
+var ErrNotFound = errorcode.New(errorcode.NotFound, "record not found")
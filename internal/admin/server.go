@@ -0,0 +1,160 @@
+// Package admin serves the operational endpoints of krinder: liveness, readiness, job status and metrics
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/eveisesi/krinder/internal/jobs"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+)
+
+// Check reports whether a dependency is ready. A nil error means ready
+type Check func(ctx context.Context) error
+
+type Server struct {
+	logger *logrus.Logger
+	server *http.Server
+
+	mu     sync.RWMutex
+	checks map[string]Check
+	jobs   []func() jobs.Status
+}
+
+func New(logger *logrus.Logger, addr string) *Server {
+	s := &Server{
+		logger: logger,
+		checks: make(map[string]Check),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.healthz)
+	mux.HandleFunc("/readyz", s.readyz)
+	mux.HandleFunc("/status", s.status)
+	mux.Handle("/metrics", promhttp.Handler())
+
+	s.server = &http.Server{
+		Addr:         addr,
+		Handler:      mux,
+		ReadTimeout:  time.Second * 5,
+		WriteTimeout: time.Second * 15,
+	}
+
+	return s
+}
+
+// AddCheck registers a readiness check under name, replacing any existing check with the same name
+func (s *Server) AddCheck(name string, check Check) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checks[name] = check
+}
+
+// AddJob registers a job whose status is reported by the status endpoint
+func (s *Server) AddJob(status func() jobs.Status) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, status)
+}
+
+// Run serves the admin endpoints until Shutdown is called
+func (s *Server) Run() {
+	s.logger.WithField("service", "admin").WithField("addr", s.server.Addr).Info("starting admin server")
+	err := s.server.ListenAndServe()
+	if err != nil && err != http.ErrServerClosed {
+		s.logger.WithError(err).WithField("service", "admin").Error("admin server stopped unexpectedly")
+	}
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+type checkResult struct {
+	Ready bool   `json:"ready"`
+	Error string `json:"error,omitempty"`
+}
+
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*5)
+	defer cancel()
+
+	s.mu.RLock()
+	checks := make(map[string]Check, len(s.checks))
+	for name, check := range s.checks {
+		checks[name] = check
+	}
+	s.mu.RUnlock()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var ready = true
+	results := make(map[string]checkResult, len(checks))
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			err := check(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				ready = false
+				results[name] = checkResult{Error: err.Error()}
+				return
+			}
+			results[name] = checkResult{Ready: true}
+		}(name, check)
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	if !ready {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(results)
+
+}
+
+func (s *Server) status(w http.ResponseWriter, r *http.Request) {
+
+	s.mu.RLock()
+	statuses := make([]jobs.Status, 0, len(s.jobs))
+	for _, status := range s.jobs {
+		statuses = append(statuses, status())
+	}
+	s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(statuses)
+
+}
+
+// Recent returns a Check that fails when last returns a time older than maxAge,
+// or the zero time because nothing has succeeded yet
+func Recent(last func() time.Time, maxAge time.Duration) Check {
+	return func(ctx context.Context) error {
+		t := last()
+		if t.IsZero() {
+			return errors.New("has not succeeded yet")
+		}
+		if age := time.Since(t); age > maxAge {
+			return errors.Errorf("last succeeded %s ago, expected within %s", age.Truncate(time.Second), maxAge)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,85 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/krinder/internal/jobs"
+	"github.com/sirupsen/logrus"
+)
+
+func TestReadyz(t *testing.T) {
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	s := New(logger, "")
+	s.AddCheck("ok", func(ctx context.Context) error { return nil })
+
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	s.AddCheck("broken", func(ctx context.Context) error { return errors.New("unreachable") })
+
+	rec = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "unreachable") {
+		t.Errorf("expected the failing check to be reported, got %s", rec.Body.String())
+	}
+
+}
+
+func TestStatus(t *testing.T) {
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	tracker := jobs.NewTracker("wars")
+	_ = tracker.Start()
+
+	s := New(logger, "")
+	s.AddJob(tracker.Status)
+
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"state":"running"`) {
+		t.Errorf("expected the running job to be reported, got %s", rec.Body.String())
+	}
+
+}
+
+func TestRecent(t *testing.T) {
+
+	var last time.Time
+	check := Recent(func() time.Time { return last }, time.Hour)
+
+	if check(context.Background()) == nil {
+		t.Error("expected an error before the first success")
+	}
+
+	last = time.Now().Add(-time.Minute)
+	if err := check(context.Background()); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	last = time.Now().Add(-time.Hour * 2)
+	if check(context.Background()) == nil {
+		t.Error("expected an error for a stale success")
+	}
+
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Limiter counts requests per key
+type Limiter interface {
+	// Allow counts a request against key and reports whether it is within limit requests per
+	// minute. When it is not, the duration until requests are allowed again is returned
+	Allow(ctx context.Context, key string, limit int) (bool, time.Duration, error)
+}
+
+const rateLimitPrefix = "api:ratelimit:"
+
+// RedisLimiter counts requests in fixed one minute windows in redis, so that the limit
+// of a key is shared by every replica serving the API
+type RedisLimiter struct {
+	client *redis.Client
+}
+
+var _ Limiter = new(RedisLimiter)
+
+func NewRedisLimiter(client *redis.Client) *RedisLimiter {
+	return &RedisLimiter{
+		client: client,
+	}
+}
+
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit int) (bool, time.Duration, error) {
+
+	now := time.Now()
+	window := now.Truncate(time.Minute)
+	counter := fmt.Sprintf("%s%s:%d", rateLimitPrefix, key, window.Unix())
+
+	var count *redis.IntCmd
+	_, err := l.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		count = pipe.Incr(ctx, counter)
+		pipe.Expire(ctx, counter, time.Minute*2)
+		return nil
+	})
+	if err != nil {
+		return false, 0, err
+	}
+
+	if count.Val() > int64(limit) {
+		return false, window.Add(time.Minute).Sub(now), nil
+	}
+
+	return true, 0, nil
+
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/eveisesi/krinder/pkg/errorcode"
+)
+
+const (
+	defaultWarsLimit = 50
+	maxWarsLimit     = 500
+)
+
+// pathID parses the last segment of the path of r, which follows prefix, as an id
+func pathID(r *http.Request, prefix, name string) (uint64, error) {
+	segment := strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
+	if segment == "" || strings.Contains(segment, "/") {
+		return 0, errorcode.Newf(errorcode.NotFound, "expected %s%s", prefix, "{"+name+"}")
+	}
+
+	id, err := strconv.ParseUint(segment, 10, 64)
+	if err != nil {
+		return 0, errorcode.Wrapf(err, errorcode.InvalidArgument, "%s must be an integer", name)
+	}
+
+	return id, nil
+}
+
+// queryID parses the query parameter name as an id. Zero is returned when it is absent and not required
+func queryID(r *http.Request, name string, required bool) (uint64, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		if required {
+			return 0, errorcode.Newf(errorcode.InvalidArgument, "%s is required", name)
+		}
+		return 0, nil
+	}
+
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, errorcode.Wrapf(err, errorcode.InvalidArgument, "%s must be an integer", name)
+	}
+
+	return id, nil
+}
+
+func queryBool(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errorcode.Wrapf(err, errorcode.InvalidArgument, "%s must be true or false", name)
+	}
+
+	return b, nil
+}
+
+func (s *Server) killrightAttacker(ctx context.Context, r *http.Request) (interface{}, error) {
+
+	id, err := pathID(r, "/killrights/attacker/", "id")
+	if err != nil {
+		return nil, err
+	}
+
+	return s.killright.Attacker(ctx, id, "", nil)
+
+}
+
+func (s *Server) killrightVictim(ctx context.Context, r *http.Request) (interface{}, error) {
+
+	id, err := pathID(r, "/killrights/victim/", "id")
+	if err != nil {
+		return nil, err
+	}
+
+	return s.killright.Victim(ctx, id, "", nil)
+
+}
+
+// killrightShip summarises a ship group, or lists the victims of a single ship type when ship is set
+func (s *Server) killrightShip(ctx context.Context, r *http.Request) (interface{}, error) {
+
+	groupID, err := pathID(r, "/killrights/ship/", "group")
+	if err != nil {
+		return nil, err
+	}
+
+	shipTypeID, err := queryID(r, "ship", false)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.killright.Ship(ctx, groupID, shipTypeID, nil)
+
+}
+
+// entityWars lists the wars an alliance or corporation is fighting, or has fought when finished is true
+func (s *Server) entityWars(ctx context.Context, r *http.Request) (interface{}, error) {
+
+	entityID, err := queryID(r, "entity", true)
+	if err != nil {
+		return nil, err
+	}
+
+	finished, err := queryBool(r, "finished")
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := queryID(r, "limit", false)
+	if err != nil {
+		return nil, err
+	}
+	if limit == 0 {
+		limit = defaultWarsLimit
+	}
+	if limit > maxWarsLimit {
+		return nil, errorcode.Newf(errorcode.InvalidArgument, "limit must not exceed %d", maxWarsLimit)
+	}
+
+	return s.wars.EntityWars(ctx, uint(entityID), finished, int64(limit))
+
+}
+
+func (s *Server) war(ctx context.Context, r *http.Request) (interface{}, error) {
+
+	id, err := pathID(r, "/wars/", "id")
+	if err != nil {
+		return nil, err
+	}
+
+	return s.wars.War(ctx, uint(id))
+
+}
+
+func (s *Server) search(ctx context.Context, r *http.Request) (interface{}, error) {
+
+	query := r.URL.Query()
+
+	term := query.Get("term")
+	if term == "" {
+		return nil, errorcode.New(errorcode.InvalidArgument, "term is required")
+	}
+
+	strict, err := queryBool(r, "strict")
+	if err != nil {
+		return nil, err
+	}
+
+	return s.universe.Search(ctx, query.Get("category"), term, strict)
+
+}
@@ -0,0 +1,244 @@
+// Package api serves a read-only JSON API answering the same kill right, war and search
+// queries as the bot. Requests are authenticated with API keys and rate limited per key
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/internal/killright"
+	"github.com/eveisesi/krinder/internal/metrics"
+	"github.com/eveisesi/krinder/internal/universe"
+	"github.com/eveisesi/krinder/internal/wars"
+	"github.com/eveisesi/krinder/pkg/correlation"
+	"github.com/eveisesi/krinder/pkg/errorcode"
+	"github.com/sirupsen/logrus"
+)
+
+// requestTimeout bounds the work done for a request. Kill right searches page through
+// zKillboard and may take a while
+const requestTimeout = time.Minute * 2
+
+// handler answers a request with a value that is encoded as JSON
+type handler func(ctx context.Context, r *http.Request) (interface{}, error)
+
+type Server struct {
+	logger *logrus.Logger
+	server *http.Server
+
+	keys         krinder.APIKeyRepository
+	limiter      Limiter
+	defaultLimit int
+
+	killright *killright.Service
+	wars      *wars.Service
+	universe  *universe.Service
+}
+
+// New returns a server listening on addr. Keys without a rate limit of their own
+// are allowed defaultLimit requests per minute
+func New(logger *logrus.Logger, addr string, keys krinder.APIKeyRepository, limiter Limiter, defaultLimit int, killright *killright.Service, wars *wars.Service, universe *universe.Service) *Server {
+	s := &Server{
+		logger:       logger,
+		keys:         keys,
+		limiter:      limiter,
+		defaultLimit: defaultLimit,
+		killright:    killright,
+		wars:         wars,
+		universe:     universe,
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/killrights/attacker/", s.handle("killrights_attacker", s.killrightAttacker))
+	mux.Handle("/killrights/victim/", s.handle("killrights_victim", s.killrightVictim))
+	mux.Handle("/killrights/ship/", s.handle("killrights_ship", s.killrightShip))
+	mux.Handle("/wars", s.handle("wars", s.entityWars))
+	mux.Handle("/wars/", s.handle("war", s.war))
+	mux.Handle("/search", s.handle("search", s.search))
+
+	s.server = &http.Server{
+		Addr:         addr,
+		Handler:      mux,
+		ReadTimeout:  time.Second * 5,
+		WriteTimeout: requestTimeout + time.Second*15,
+	}
+
+	return s
+}
+
+// Run serves the API until Shutdown is called
+func (s *Server) Run() {
+	s.logger.WithField("service", "api").WithField("addr", s.server.Addr).Info("starting api server")
+	err := s.server.ListenAndServe()
+	if err != nil && err != http.ErrServerClosed {
+		s.logger.WithError(err).WithField("service", "api").Error("api server stopped unexpectedly")
+	}
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+// GenerateKey returns a new random API key
+func GenerateKey() (string, error) {
+	b := make([]byte, 24)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return "krk_" + hex.EncodeToString(b), nil
+}
+
+// HashKey returns the hash a key is stored under
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+type errorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Reference string `json:"reference"`
+}
+
+// handle authenticates and rate limits requests before passing them to next, and encodes
+// its result. Every request gets a correlation id, which is returned in the X-Correlation-ID header
+func (s *Server) handle(route string, next handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		start := time.Now()
+
+		id := correlation.New()
+		ctx := correlation.WithID(r.Context(), id)
+		w.Header().Set("X-Correlation-ID", id)
+
+		entry := s.logger.WithContext(ctx).WithFields(logrus.Fields{
+			"service": "api",
+			"route":   route,
+		})
+
+		status := http.StatusOK
+		defer func() { metrics.ObserveRequest(route, status, start) }()
+
+		if r.Method != http.MethodGet {
+			status = http.StatusMethodNotAllowed
+			writeJSON(w, status, errorResponse{Code: "METHOD_NOT_ALLOWED", Message: "only GET is supported", Reference: id})
+			return
+		}
+
+		key, err := s.authenticate(ctx, r)
+		if err != nil {
+			status = http.StatusUnauthorized
+			if !errors.Is(err, errUnauthenticated) {
+				entry.WithError(err).Error("failed to authenticate request")
+				status = http.StatusInternalServerError
+			}
+			writeJSON(w, status, errorResponse{Code: "UNAUTHENTICATED", Message: "a valid api key is required", Reference: id})
+			return
+		}
+
+		entry = entry.WithField("key", key.Name)
+
+		limit := key.RateLimit
+		if limit <= 0 {
+			limit = s.defaultLimit
+		}
+
+		allowed, retryAfter, err := s.limiter.Allow(ctx, key.Name, limit)
+		if err != nil {
+			// Requests are let through rather than failing every request while the limiter is unavailable
+			entry.WithError(err).Error("failed to check rate limit")
+		} else if !allowed {
+			status = http.StatusTooManyRequests
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			writeJSON(w, status, errorResponse{Code: "RATE_LIMITED", Message: "rate limit exceeded", Reference: id})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+		defer cancel()
+
+		result, err := next(ctx, r)
+		if err != nil {
+			code := errorcode.Code(err)
+			status = httpStatus(code)
+			entry.WithError(err).WithField("code", code).Error("request failed")
+			writeJSON(w, status, errorResponse{Code: string(code), Message: publicMessage(err), Reference: id})
+			return
+		}
+
+		writeJSON(w, status, result)
+
+	})
+}
+
+var errUnauthenticated = errors.New("unauthenticated")
+
+// authenticate returns the key presented in the Authorization header as a bearer token,
+// or in the X-API-Key header
+func (s *Server) authenticate(ctx context.Context, r *http.Request) (*krinder.APIKey, error) {
+
+	presented := r.Header.Get("X-API-Key")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		presented = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if presented == "" {
+		return nil, errUnauthenticated
+	}
+
+	key, err := s.keys.APIKey(ctx, HashKey(presented))
+	if errors.Is(err, krinder.ErrNotFound) {
+		return nil, errUnauthenticated
+	}
+
+	return key, err
+
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func httpStatus(code errorcode.ErrorCode) int {
+	switch code {
+	case errorcode.InvalidArgument:
+		return http.StatusBadRequest
+	case errorcode.NotFound:
+		return http.StatusNotFound
+	case errorcode.UpstreamThrottled:
+		return http.StatusServiceUnavailable
+	case errorcode.UpstreamUnavailable:
+		return http.StatusBadGateway
+	case errorcode.Timeout:
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// publicMessage describes err to the caller. Like the bot, only the messages of invalid arguments
+// and missing records are returned, other errors may carry internal detail
+func publicMessage(err error) string {
+	switch errorcode.Code(err) {
+	case errorcode.InvalidArgument, errorcode.NotFound:
+		return errorcode.Message(err)
+	case errorcode.UpstreamThrottled:
+		return "ESI or zKillboard is rate limiting krinder, try again in a minute"
+	case errorcode.UpstreamUnavailable:
+		return "ESI or zKillboard is unavailable, try again in a few minutes"
+	case errorcode.Timeout:
+		return "the request took too long and was abandoned"
+	}
+	return "the request encountered an internal error"
+}
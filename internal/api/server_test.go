@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/internal/notify"
+	"github.com/eveisesi/krinder/internal/store/memory"
+	"github.com/eveisesi/krinder/internal/universe"
+	"github.com/eveisesi/krinder/internal/wars"
+	"github.com/eveisesi/krinder/pkg/clock"
+	"github.com/sirupsen/logrus"
+)
+
+const testKey = "krk_test"
+
+// countingLimiter allows limit requests per key over its lifetime
+type countingLimiter struct {
+	counts map[string]int
+}
+
+func (l *countingLimiter) Allow(ctx context.Context, key string, limit int) (bool, time.Duration, error) {
+	l.counts[key]++
+	if l.counts[key] > limit {
+		return false, time.Second * 30, nil
+	}
+	return true, 0, nil
+}
+
+func newTestServer(t *testing.T, limit int) *Server {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	ctx := context.Background()
+
+	keys := memory.NewAPIKeyRepository()
+	err := keys.CreateAPIKey(ctx, &krinder.APIKey{Name: "corp-tools", Hash: HashKey(testKey), RateLimit: limit})
+	if err != nil {
+		t.Fatalf("failed to create key: %s", err)
+	}
+
+	allianceID, corporationID := uint(99000001), uint(98000001)
+	warRepo := memory.NewWarRepository(clock.New())
+	err = warRepo.CreateWarBulk(ctx, []*krinder.MongoWar{
+		{ID: 1, Aggressor: &krinder.MongoWarAggressor{AllianceID: &allianceID}, Defender: &krinder.MongoWarDefender{CorporationID: &corporationID}},
+		{ID: 2, Aggressor: &krinder.MongoWarAggressor{CorporationID: &corporationID}, Defender: &krinder.MongoWarDefender{AllianceID: &allianceID}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create wars: %s", err)
+	}
+
+	return New(
+		logger,
+		"",
+		keys,
+		&countingLimiter{counts: make(map[string]int)},
+		60,
+		nil,
+		wars.NewService(logger, nil, warRepo, notify.NewRouter(logger, memory.NewDeadLetterRepository()), clock.New()),
+		universe.New(logger, nil, nil, memory.NewUniverseRepository(clock.New()), clock.New()),
+	)
+}
+
+func get(s *Server, path, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuthentication(t *testing.T) {
+
+	s := newTestServer(t, 0)
+
+	for _, key := range []string{"", "krk_unknown"} {
+		rec := get(s, "/wars/1", key)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d for key %q, got %d", http.StatusUnauthorized, key, rec.Code)
+		}
+	}
+
+	rec := get(s, "/wars/1", testKey)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("X-Correlation-ID") == "" {
+		t.Errorf("expected a correlation id")
+	}
+
+	var war krinder.MongoWar
+	if err := json.Unmarshal(rec.Body.Bytes(), &war); err != nil || war.ID != 1 {
+		t.Errorf("unexpected war %s", rec.Body.String())
+	}
+
+}
+
+func TestRateLimit(t *testing.T) {
+
+	s := newTestServer(t, 2)
+
+	for i := 0; i < 2; i++ {
+		if rec := get(s, "/wars/1", testKey); rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+	}
+
+	rec := get(s, "/wars/1", testKey)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "30" {
+		t.Errorf("expected Retry-After 30, got %q", rec.Header().Get("Retry-After"))
+	}
+
+}
+
+func TestWars(t *testing.T) {
+
+	s := newTestServer(t, 0)
+
+	rec := get(s, "/wars?entity=99000001", testKey)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var wars []*krinder.MongoWar
+	if err := json.Unmarshal(rec.Body.Bytes(), &wars); err != nil {
+		t.Fatalf("failed to decode wars: %s", err)
+	}
+	if len(wars) != 2 || wars[0].ID != 2 {
+		t.Errorf("expected both wars, most recent first, got %s", rec.Body.String())
+	}
+
+	cases := map[string]int{
+		"/wars":                             http.StatusBadRequest,
+		"/wars?entity=abc":                  http.StatusBadRequest,
+		"/wars/3":                           http.StatusNotFound,
+		"/wars/abc":                         http.StatusBadRequest,
+		"/search?term=rifter&category=ship": http.StatusBadRequest,
+	}
+	for path, status := range cases {
+		rec := get(s, path, testKey)
+		if rec.Code != status {
+			t.Errorf("expected status %d for %s, got %d: %s", status, path, rec.Code, rec.Body.String())
+		}
+
+		var body errorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Message == "" || body.Reference == "" {
+			t.Errorf("unexpected error body for %s: %s", path, rec.Body.String())
+		}
+	}
+
+}
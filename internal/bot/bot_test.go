@@ -0,0 +1,170 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/krinder/internal/chat"
+	"github.com/eveisesi/krinder/internal/notify"
+	"github.com/eveisesi/krinder/internal/repl"
+	"github.com/eveisesi/krinder/internal/sso"
+	"github.com/eveisesi/krinder/internal/store/memory"
+	"github.com/eveisesi/krinder/internal/universe"
+	"github.com/eveisesi/krinder/internal/wars"
+	"github.com/eveisesi/krinder/pkg/clock"
+	"github.com/sirupsen/logrus"
+)
+
+// run drives the bot with input through the terminal transport and returns everything it replied
+func run(t *testing.T, input string) string {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	var out bytes.Buffer
+	terminal := repl.New(strings.NewReader(input), &out)
+
+	s := New(
+		logger,
+		terminal,
+		nil,
+		nil,
+		universe.New(logger, nil, nil, memory.NewUniverseRepository(clock.New()), clock.New()),
+		wars.NewService(logger, nil, memory.NewWarRepository(clock.New()), notify.NewRouter(logger, memory.NewDeadLetterRepository()), clock.New()),
+		sso.New(logger, sso.Config{}, nil, memory.NewCredentialRepository(), nil, clock.New()),
+		memory.NewPaginationRepository(clock.New()),
+		clock.New(),
+	)
+
+	ctx := context.Background()
+	err := terminal.Open(ctx, func(msg *chat.Message) {
+		s.Handle(ctx, msg)
+	})
+	if err != nil {
+		t.Fatalf("failed to open transport: %s", err)
+	}
+	<-terminal.Done()
+
+	return out.String()
+}
+
+func TestHandle(t *testing.T) {
+
+	tests := map[string]struct {
+		input    string
+		contains []string
+	}{
+		"ping": {
+			input:    "ping",
+			contains: []string{"Pong!"},
+		},
+		"status": {
+			input:    "status",
+			contains: []string{"wars: pending, data incomplete", "universe: pending, data incomplete"},
+		},
+		"invalid argument": {
+			input:    "kr a notanid",
+			contains: []string{"failed to parse id to integer. Use help", "(reference "},
+		},
+		"linking disabled": {
+			input:    "link",
+			contains: []string{"linking characters is not enabled on this bot"},
+		},
+		"me with linking disabled": {
+			input:    "kr v me",
+			contains: []string{"linking characters is not enabled on this bot"},
+		},
+		"unknown flag": {
+			input:    "kr --nope a 1",
+			contains: []string{"flag provided but not defined", "(reference "},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			out := run(t, test.input)
+			for _, s := range test.contains {
+				if !strings.Contains(out, s) {
+					t.Errorf("expected output to contain %q, got %q", s, out)
+				}
+			}
+		})
+	}
+
+}
+
+// held is a terminal transport that keeps the deliver func it was opened with, so that messages can
+// be delivered after the bot has stopped, and records the replies it is asked to send
+type held struct {
+	*repl.Transport
+	deliver func(msg *chat.Message)
+
+	mu      sync.Mutex
+	replies []string
+}
+
+func (h *held) Open(ctx context.Context, deliver func(msg *chat.Message)) error {
+	h.deliver = deliver
+	return nil
+}
+
+func (h *held) Close() error {
+	return nil
+}
+
+func (h *held) Reply(ctx context.Context, msg *chat.Message, content string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.replies = append(h.replies, content)
+	return "", nil
+}
+
+func TestMessagesAfterShutdownAreCancelled(t *testing.T) {
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	transport := &held{Transport: repl.New(strings.NewReader(""), io.Discard)}
+	s := New(logger, transport, nil, nil, nil, nil, nil, memory.NewPaginationRepository(clock.New()), clock.New())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go s.Run(ctx, context.Background(), &wg)
+
+	cancel()
+	wg.Wait()
+
+	// More messages than the queue holds arrive after the bot stopped handling them
+	delivered := make(chan struct{})
+	go func() {
+		defer close(delivered)
+		for i := 0; i < cap(s.messages)*2; i++ {
+			transport.deliver(&chat.Message{ChannelID: "channel", Content: "ping"})
+		}
+	}()
+
+	select {
+	case <-delivered:
+	case <-time.After(time.Second):
+		t.Fatal("expected delivering messages after shutdown not to block")
+	}
+
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+	if len(transport.replies) != cap(s.messages)*2 {
+		t.Fatalf("expected every message to be cancelled, got %d replies", len(transport.replies))
+	}
+	for _, reply := range transport.replies {
+		if reply != restartingMessage {
+			t.Errorf("unexpected reply %q", reply)
+		}
+	}
+
+}
@@ -0,0 +1,224 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/eveisesi/krinder/internal/killright"
+	"github.com/eveisesi/krinder/internal/universe"
+	"github.com/eveisesi/krinder/pkg/errorcode"
+	"github.com/urfave/cli/v2"
+)
+
+var buf = &bytes.Buffer{}
+var CommandHelpTemplate = fmt.Sprintf("```%s```", cli.CommandHelpTemplate)
+var SubCommandHelpTemplate = fmt.Sprintf("```%s```", cli.SubcommandHelpTemplate)
+
+func (s *Service) initializeCLI() *cli.App {
+	app := &cli.App{
+		Name:                  "KRinder Discord Commands",
+		HelpName:              filepath.Base(os.Args[0]),
+		Usage:                 "<command>",
+		UsageText:             "command [command options] [arguments...]",
+		Action:                cli.ShowAppHelp,
+		Compiled:              time.Now(),
+		Writer:                buf,
+		CustomAppHelpTemplate: fmt.Sprintf("```%s```", cli.AppHelpTemplate),
+		Commands: []*cli.Command{
+			{
+				Name:               "ping",
+				HelpName:           "ping",
+				Usage:              "Play Ping Pong. Test Bot Connectivity and Latency",
+				UsageText:          "ping",
+				Action:             s.pingCommand,
+				CustomHelpTemplate: CommandHelpTemplate,
+			},
+			{
+				Name:               "status",
+				HelpName:           "status",
+				Usage:              "Show the progress of the war and universe syncs",
+				UsageText:          "status",
+				Action:             s.statusCommand,
+				CustomHelpTemplate: CommandHelpTemplate,
+			},
+			{
+				Name:               "help",
+				HelpName:           "help",
+				Usage:              "Display this help text",
+				UsageText:          "help",
+				Action:             cli.ShowAppHelp,
+				CustomHelpTemplate: CommandHelpTemplate,
+			},
+			{
+				Name:               "search",
+				Usage:              fmt.Sprintf("Searches an entity by name. Valid categories are (%s)", strings.Join(universe.SearchCategories, ",")),
+				HelpName:           "search",
+				UsageText:          "search <category> <term>",
+				Action:             s.searchCommand,
+				CustomHelpTemplate: CommandHelpTemplate,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "strict",
+						Usage: "enables strict searching. Should return a single result",
+					},
+				},
+			},
+			{
+				Name:               "killright",
+				Usage:              "",
+				HelpName:           "killright",
+				CustomHelpTemplate: SubCommandHelpTemplate,
+				Aliases:            []string{"kr"},
+				Action:             cli.ShowAppHelp,
+				Subcommands: []*cli.Command{
+					{
+						Name:               "attacker",
+						Usage:              "Search for Kill Rights by Killmail Attacker",
+						UsageText:          "kr a [characterID|me]",
+						HelpName:           "attacker",
+						Description:        "Search for Kill Rights by Killmail Attacker. Without a character id, your linked main character is searched",
+						Aliases:            []string{"a"},
+						Action:             s.killrightAttackerCommand,
+						CustomHelpTemplate: CommandHelpTemplate,
+					},
+					{
+						Name:               "victim",
+						Description:        "Search for Kill Rights by Killmail Victim. Without a character id, your linked main character is searched",
+						Usage:              "Search for Kill Rights by Killmail Victim",
+						UsageText:          "kr v [characterID|me]",
+						Aliases:            []string{"v"},
+						Action:             s.killrightVictimCommand,
+						CustomHelpTemplate: CommandHelpTemplate,
+					},
+					{
+						Name:               "reconcile",
+						Description:        "Import the kill rights your linked character holds in game and compare them with what kr v predicts. Kill rights are read from the KillRightEarned notifications of the character, so only those earned recently are known",
+						Usage:              "Compare the kill rights a linked character holds with those kr v predicts",
+						UsageText:          "kr reconcile [characterID|me]",
+						Aliases:            []string{"rc"},
+						Action:             s.killrightReconcileCommand,
+						CustomHelpTemplate: CommandHelpTemplate,
+					},
+					{
+						Name:               "ship",
+						Description:        "Search for Kill Rights by Ship Group and Type. If you don't know the ship group id, please use the search command with the invgroup command to find a group by name. Type ID is optional, so you can ommit it, but the output will be a count of kills by Group, rather than charaters you can prosue. Pass ship type id to get the summary for that ship. They are printed when in the group summary",
+						Usage:              "Search for Kill Rights by Ship Group and Type",
+						UsageText:          "kr s <shipGroupID> <shipTypeID>",
+						Aliases:            []string{"s"},
+						CustomHelpTemplate: CommandHelpTemplate,
+						Action:             s.killrightShipCommand,
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name: "verbose",
+							},
+						},
+					},
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "format",
+						Aliases: []string{"f"},
+						Usage:   "Format of the list outputted. Options include " + strings.Join(killright.Formats(), ", "),
+						Value:   "simple",
+					},
+					&cli.StringFlag{
+						Name:  "as-of",
+						Usage: "Search attackers, victims and ships as kill rights stood at a date (2021-11-15) or time (2021-11-15T18:00:00Z) within the last 90 days",
+					},
+				},
+			},
+			{
+				Name:               "link",
+				Usage:              "Link your EVE characters through EVE SSO",
+				HelpName:           "link",
+				UsageText:          "link",
+				Action:             s.linkCommand,
+				CustomHelpTemplate: SubCommandHelpTemplate,
+				Subcommands: []*cli.Command{
+					{
+						Name:               "list",
+						Usage:              "List your linked characters",
+						UsageText:          "link list",
+						Action:             s.linkListCommand,
+						CustomHelpTemplate: CommandHelpTemplate,
+					},
+					{
+						Name:               "main",
+						Usage:              "Make a linked character the default of commands such as kr v",
+						UsageText:          "link main <characterID>",
+						Action:             s.linkMainCommand,
+						CustomHelpTemplate: CommandHelpTemplate,
+					},
+					{
+						Name:               "remove",
+						Usage:              "Unlink a character and delete its tokens",
+						UsageText:          "link remove <characterID>",
+						Action:             s.linkRemoveCommand,
+						CustomHelpTemplate: CommandHelpTemplate,
+					},
+				},
+			},
+			{
+				Name:               "who",
+				Usage:              "Profile a character for hunting",
+				Description:        "Profile a character from ESI and their killmails: corporation and alliance history, security status, recent high sec kills and losses, ships, systems, activity by hour, active wars and outstanding kill rights. Without a character, your linked main character is profiled",
+				UsageText:          "who [characterID|name|me]",
+				HelpName:           "who",
+				Action:             s.whoCommand,
+				CustomHelpTemplate: CommandHelpTemplate,
+			},
+			{
+				Name:      "mail",
+				Usage:     "Summarises a killmail and the kill rights it likely generated",
+				HelpName:  "mail",
+				UsageText: "mail <killmailID>",
+				Action:    s.mailCommand,
+			},
+		},
+		Metadata: make(map[string]interface{}),
+	}
+
+	usageErrors(app.Commands)
+
+	return app
+}
+
+// usageErrors codes the errors of commands that are used incorrectly as invalid arguments,
+// so that they are shown to the user rather than treated as internal errors
+func usageErrors(commands []*cli.Command) {
+	for _, command := range commands {
+		command.OnUsageError = func(c *cli.Context, err error, isSubcommand bool) error {
+			return errorcode.Wrap(err, errorcode.InvalidArgument, err.Error())
+		}
+		usageErrors(command.Subcommands)
+	}
+}
+
+// commandName resolves the command, or one of its aliases, that words invokes. The boolean
+// is false when words do not invoke a command and should not be handled by the cli
+func (s *Service) commandName(cli *cli.App, words []string) (string, bool) {
+
+	if len(words) == 0 {
+		return "", false
+	}
+
+	for _, command := range cli.Commands {
+		if command.Name == words[0] {
+			return command.Name, true
+		}
+		if len(command.Aliases) > 0 {
+			for _, alias := range command.Aliases {
+				if alias == words[0] {
+					return command.Name, true
+				}
+			}
+		}
+	}
+
+	return "", false
+
+}
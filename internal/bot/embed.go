@@ -0,0 +1,312 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eveisesi/krinder/internal/chat"
+	"github.com/eveisesi/krinder/internal/killright"
+)
+
+const (
+	zkillboardURL = "https://zkillboard.com"
+	imageURL      = "https://images.evetech.net"
+
+	// embedColor is the color of the stripe beside kill right embeds
+	embedColor = 0xB22222
+
+	// fieldValueLimit is the length Discord allows the value of an embed field
+	fieldValueLimit = 1024
+
+	// embedLimit is the length Discord allows the text of an embed in total
+	embedLimit = 6000
+)
+
+func characterURL(id uint64) string {
+	return fmt.Sprintf("%s/character/%d/", zkillboardURL, id)
+}
+
+func portraitURL(id uint64) string {
+	return fmt.Sprintf("%s/characters/%d/portrait?size=128", imageURL, id)
+}
+
+// paginate splits count entries into pages of at most pageSize, building the embed of each with page.
+// A page holds fewer entries when they would take it over embedLimit
+func paginate(count int, page func(start, end int) *chat.Embed) []*chat.Embed {
+
+	embeds := make([]*chat.Embed, 0, (count+pageSize-1)/pageSize)
+	for start := 0; start < count; {
+		end := start + pageSize
+		if end > count {
+			end = count
+		}
+
+		embed := page(start, end)
+		for embedLength(embed) > embedLimit && end-start > 1 {
+			end--
+			embed = page(start, end)
+		}
+
+		embeds = append(embeds, embed)
+		start = end
+	}
+
+	return embeds
+
+}
+
+// embedLength is the length of the text of embed that counts towards embedLimit
+func embedLength(embed *chat.Embed) int {
+	length := len(embed.Title) + len(embed.Description) + len(embed.Footer)
+	for _, field := range embed.Fields {
+		length += len(field.Name) + len(field.Value)
+	}
+	return length
+}
+
+// codeBlock returns lines as a code block, which is how in game links are offered for copying
+func codeBlock(lines []string) string {
+	return fmt.Sprintf("```%s```", strings.Join(lines, "\n"))
+}
+
+// attackerEmbeds lists the victims of the report, linking each to the killmail that may have given
+// them a kill right. In the evelink format each page also carries its victims as in game links
+func attackerEmbeds(report *killright.AttackerReport, format killright.Format) []*chat.Embed {
+
+	lines := report.Lines(format)
+
+	return paginate(len(report.Killmails), func(start, end int) *chat.Embed {
+
+		embed := &chat.Embed{
+			Title:     fmt.Sprintf("Potential kill rights on %s", report.Character.Name),
+			URL:       characterURL(report.Character.ID),
+			Color:     embedColor,
+			Thumbnail: portraitURL(report.Character.ID),
+		}
+
+		for _, killmail := range report.Killmails[start:end] {
+			value := fmt.Sprintf("[Killmail %d](%s/kill/%d/) on %s", killmail.KillmailID, zkillboardURL, killmail.KillmailID, killmail.KillmailTime.Format("2006-01-02 15:04"))
+			if killmail.SolarSystem != nil {
+				value = fmt.Sprintf("%s in %s (%.1f)", value, killmail.SolarSystem.Name, killmail.SolarSystem.SecurityStatus)
+			}
+
+			embed.Fields = append(embed.Fields, &chat.EmbedField{
+				Name:  killmail.Victim.Character.Name,
+				Value: value,
+			})
+		}
+
+		if format == killright.FormatEveLink {
+			embed.Description = fmt.Sprintf("%s\n%s", format.Legend(), codeBlock(lines[start:end]))
+		}
+
+		return embed
+
+	})
+
+}
+
+// victimEmbeds lists the characters the victim of the report may hold a kill right on
+func victimEmbeds(report *killright.VictimReport, format killright.Format) []*chat.Embed {
+
+	lines := report.Lines(format)
+
+	return paginate(len(report.Aggressors), func(start, end int) *chat.Embed {
+
+		embed := &chat.Embed{
+			Title:     fmt.Sprintf("Potential kill rights held by %d", report.VictimID),
+			URL:       characterURL(report.VictimID),
+			Color:     embedColor,
+			Thumbnail: portraitURL(report.VictimID),
+		}
+
+		for _, aggressor := range report.Aggressors[start:end] {
+			embed.Fields = append(embed.Fields, &chat.EmbedField{
+				Name:   aggressor.Character.Name,
+				Value:  fmt.Sprintf("[zKillboard](%s) · %d killmail(s)", characterURL(aggressor.Character.ID), aggressor.Seen),
+				Inline: true,
+			})
+		}
+
+		if format == killright.FormatEveLink {
+			embed.Description = fmt.Sprintf("%s\n%s", format.Legend(), codeBlock(lines[start:end]))
+		}
+
+		return embed
+
+	})
+
+}
+
+// shipSummaryEmbeds lists the number of qualifying losses per ship of the group
+func shipSummaryEmbeds(report *killright.ShipReport) []*chat.Embed {
+
+	return paginate(len(report.Ships), func(start, end int) *chat.Embed {
+
+		lines := make([]string, 0, end-start)
+		for _, ship := range report.Ships[start:end] {
+			lines = append(lines, fmt.Sprintf("%d | [%s](%s/ship/%d/) (%d)", len(ship.Killmails), ship.Entity.Name, zkillboardURL, ship.Entity.ID, ship.Entity.ID))
+		}
+
+		return &chat.Embed{
+			Title:       fmt.Sprintf("Potential kill rights across ship group %d", report.GroupID),
+			Description: strings.Join(lines, "\n"),
+			Color:       embedColor,
+			Footer:      "Pass the ship type id in parenthesis to list the victims of that ship",
+		}
+
+	})
+
+}
+
+// shipVictimEmbeds lists the victims that lost the ship type of the report. When verbose is true each
+// victim lists the characters they may hold kill rights on
+func shipVictimEmbeds(report *killright.ShipReport, verbose bool) []*chat.Embed {
+
+	return paginate(len(report.Victims), func(start, end int) *chat.Embed {
+
+		embed := &chat.Embed{
+			Title:     fmt.Sprintf("Victims who lost ship type %d", report.ShipTypeID),
+			URL:       fmt.Sprintf("%s/ship/%d/", zkillboardURL, report.ShipTypeID),
+			Color:     embedColor,
+			Thumbnail: fmt.Sprintf("%s/types/%d/render?size=128", imageURL, report.ShipTypeID),
+		}
+
+		for _, victim := range report.Victims[start:end] {
+			value := fmt.Sprintf("[zKillboard](%s) · %d potential kill right(s)", characterURL(victim.Character.ID), len(victim.Aggressors))
+			if verbose {
+				for _, aggressor := range victim.Aggressors {
+					line := fmt.Sprintf("\n%s (%d) seen %d time(s)", aggressor.Character.Name, aggressor.Character.ID, aggressor.Seen)
+					if len(value)+len(line) > fieldValueLimit {
+						break
+					}
+					value += line
+				}
+			}
+
+			embed.Fields = append(embed.Fields, &chat.EmbedField{
+				Name:  victim.Character.Name,
+				Value: value,
+			})
+		}
+
+		return embed
+
+	})
+
+}
+
+func typeRenderURL(id uint) string {
+	return fmt.Sprintf("%s/types/%d/render?size=128", imageURL, id)
+}
+
+// killmailEmbed summarises the killmail, naming the characters its victim likely holds kill rights on
+func killmailEmbed(summary *killright.KillmailSummary) *chat.Embed {
+
+	killmail := summary.Killmail
+
+	victim := "Structure"
+	if killmail.Victim.Character != nil {
+		victim = fmt.Sprintf("[%s](%s)", killmail.Victim.Character.Name, characterURL(killmail.Victim.CharacterID))
+	}
+	ship := fmt.Sprintf("Type %d", killmail.Victim.ShipTypeID)
+	if killmail.Victim.Ship != nil {
+		ship = killmail.Victim.Ship.Name
+	}
+
+	embed := &chat.Embed{
+		Title:       fmt.Sprintf("Killmail %d: %s", killmail.KillmailID, ship),
+		URL:         fmt.Sprintf("%s/kill/%d/", zkillboardURL, killmail.KillmailID),
+		Description: fmt.Sprintf("%s lost a %s on %s", victim, ship, killmail.KillmailTime.UTC().Format("2006-01-02 15:04")),
+		Color:       embedColor,
+		Thumbnail:   typeRenderURL(killmail.Victim.ShipTypeID),
+		Fields: []*chat.EmbedField{
+			{
+				Name:   "System",
+				Value:  fmt.Sprintf("%s (%.1f)", killmail.SolarSystem.Name, killmail.SolarSystem.SecurityStatus),
+				Inline: true,
+			},
+			{Name: "Attackers", Value: fmt.Sprint(len(killmail.Attackers)), Inline: true},
+		},
+	}
+
+	if summary.Meta != nil {
+		embed.Fields = append(embed.Fields, &chat.EmbedField{Name: "Value", Value: fmt.Sprintf("%s ISK", killright.ISK(summary.Meta.TotalValue)), Inline: true})
+	}
+	if summary.FinalBlow != nil {
+		embed.Fields = append(embed.Fields, &chat.EmbedField{Name: "Final Blow", Value: summary.FinalBlow.String(), Inline: true})
+	}
+	if summary.TopDamage != nil {
+		embed.Fields = append(embed.Fields, &chat.EmbedField{
+			Name:   "Top Damage",
+			Value:  fmt.Sprintf("%s, %d damage", summary.TopDamage, summary.TopDamage.Attacker.DamageDone),
+			Inline: true,
+		})
+	}
+
+	warKill := "No"
+	if summary.WarKill {
+		warKill = "Yes"
+	}
+	embed.Fields = append(embed.Fields, &chat.EmbedField{Name: "War Kill", Value: warKill, Inline: true})
+
+	killRights := "None likely"
+	if len(summary.KillRights) > 0 {
+		names := make([]string, 0, len(summary.KillRights))
+		for _, character := range summary.KillRights {
+			names = append(names, fmt.Sprintf("[%s](%s)", character.Name, characterURL(character.ID)))
+		}
+		killRights = strings.Join(names, ", ")
+		if len(killRights) > fieldValueLimit {
+			killRights = fmt.Sprintf("%d attackers", len(summary.KillRights))
+		}
+	}
+	embed.Fields = append(embed.Fields, &chat.EmbedField{Name: "Likely Kill Rights", Value: killRights})
+
+	return embed
+
+}
+
+// profileLimit is the number of entries listed in each field of a profile embed
+const profileLimit = 5
+
+// profileEmbed describes the character in fields, each listing at most profileLimit entries
+func profileEmbed(profile *killright.Profile) *chat.Embed {
+
+	character := profile.Character
+
+	affiliation := profile.Corporation
+	if profile.Alliance != "" {
+		affiliation = fmt.Sprintf("%s / %s", profile.Corporation, profile.Alliance)
+	}
+
+	field := func(name string, lines []string, inline bool) *chat.EmbedField {
+		value := strings.Join(lines, "\n")
+		if value == "" {
+			value = "None"
+		}
+		return &chat.EmbedField{Name: name, Value: value, Inline: inline}
+	}
+
+	return &chat.Embed{
+		Title:       character.Name,
+		URL:         characterURL(character.ID),
+		Description: affiliation,
+		Color:       embedColor,
+		Thumbnail:   portraitURL(character.ID),
+		Fields: []*chat.EmbedField{
+			{Name: "Security Status", Value: fmt.Sprintf("%.1f", character.SecurityStatus), Inline: true},
+			{Name: "Outstanding Kill Rights", Value: fmt.Sprint(profile.KillRights), Inline: true},
+			field("Corporation History", killright.AffiliationLines(profile.CorporationHistory, profileLimit), false),
+			field("Alliance History of the Corporation", killright.AffiliationLines(profile.AllianceHistory, profileLimit), false),
+			field(fmt.Sprintf("High Sec Kills (%d)", len(profile.Kills)), killright.ProfileKillmailLines(profile.Kills, profileLimit), false),
+			field(fmt.Sprintf("High Sec Losses (%d)", len(profile.Losses)), killright.ProfileKillmailLines(profile.Losses, profileLimit), false),
+			field("Ships", killright.TallyLines(profile.Ships, profileLimit), true),
+			field("Systems", killright.TallyLines(profile.Systems, profileLimit), true),
+			{Name: "Activity (UTC)", Value: codeBlock([]string{profile.ActiveHours()})},
+			field(fmt.Sprintf("Active Wars (%d)", len(profile.Wars)), killright.WarLines(profile.Wars, profileLimit), false),
+		},
+		Footer: fmt.Sprintf("Kills, losses, ships, systems and activity cover the last %d days", killright.Lookback),
+	}
+
+}
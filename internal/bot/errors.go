@@ -0,0 +1,32 @@
+package bot
+
+import (
+	"fmt"
+
+	"github.com/eveisesi/krinder/pkg/errorcode"
+)
+
+// userMessage describes a failed command to the user that issued it. Only the messages of invalid
+// arguments and missing records are shown, as other errors may carry upstream responses and
+// internal detail. Those are logged under the correlation id quoted to the user instead
+func userMessage(err error, id string) string {
+
+	var message string
+	switch errorcode.Code(err) {
+	case errorcode.InvalidArgument:
+		message = fmt.Sprintf("%s. Use help to see how the command is used", errorcode.Message(err))
+	case errorcode.NotFound:
+		message = fmt.Sprintf("Nothing was found for your request: %s", errorcode.Message(err))
+	case errorcode.UpstreamThrottled:
+		message = "ESI or zKillboard is rate limiting the bot right now. Please try again in a minute"
+	case errorcode.UpstreamUnavailable:
+		message = "ESI or zKillboard is unavailable right now. Please try again in a few minutes"
+	case errorcode.Timeout:
+		message = "Your request took too long and was abandoned. Please try again in a few minutes"
+	default:
+		message = "Your request encountered an error. Please try again in a few seconds, if the error continues, contact the Bot Maintainer"
+	}
+
+	return fmt.Sprintf("%s (reference %s)", message, id)
+
+}
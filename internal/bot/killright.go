@@ -0,0 +1,287 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/eveisesi/krinder/internal/chat"
+	"github.com/eveisesi/krinder/internal/killright"
+	"github.com/eveisesi/krinder/pkg/errorcode"
+	"github.com/urfave/cli/v2"
+)
+
+// progress relays the progress of a kill right search to the channel the command was issued in
+func (s *Service) progress(ctx context.Context, msg *chat.Message) killright.Progress {
+	return func(message string) {
+		_, err := s.transport.Reply(ctx, msg, message)
+		if err != nil {
+			s.logger.WithContext(ctx).WithError(err).Errorln("failed to send message")
+		}
+	}
+}
+
+// searcher returns the kill right service to search with, evaluating kill rights as they stood at
+// the --as-of option when it is set
+func (s *Service) searcher(c *cli.Context) (*killright.Service, error) {
+	if c.String("as-of") == "" {
+		return s.killright, nil
+	}
+
+	asOf, err := killright.ParseAsOf(c.String("as-of"), s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return s.killright.AsOf(asOf), nil
+}
+
+// sendExport replies with rows written to a file in format, which must be Attached
+func (s *Service) sendExport(ctx context.Context, msg *chat.Message, content, name string, format killright.Format, rows []*killright.Row) error {
+
+	var buf bytes.Buffer
+	err := format.Export(&buf, rows)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.transport.Attach(ctx, msg, content, &chat.Attachment{
+		Name:        format.Filename(name),
+		ContentType: format.ContentType(),
+		Data:        &buf,
+	})
+	return err
+
+}
+
+func (s *Service) killrightAttackerCommand(c *cli.Context) error {
+
+	ctx := c.Context
+
+	msg, err := messageFromCLIContext(c)
+	if err != nil {
+		return err
+	}
+
+	format, err := killright.ParseFormat(c.String("format"))
+	if err != nil {
+		return err
+	}
+
+	id, err := s.characterID(ctx, msg, c.Args().Get(0))
+	if err != nil {
+		return err
+	}
+
+	kr, err := s.searcher(c)
+	if err != nil {
+		return err
+	}
+
+	report, err := kr.Attacker(ctx, id, msg.AuthorID, s.progress(ctx, msg))
+	if err != nil {
+		return err
+	}
+
+	s.warn(ctx, msg, report.Warning)
+
+	if len(report.Killmails) == 0 {
+		_, err := s.transport.Reply(ctx, msg, appendLatency(msg, "0 killmails remained after filtering....", true))
+		if err != nil {
+			s.logger.WithContext(ctx).WithError(err).Errorln("failed to send message")
+		}
+
+		return nil
+	}
+
+	content := appendLatency(msg, fmt.Sprintf("Found %d potential killrights", len(report.Killmails)), true)
+	if format.Attached() {
+		err = s.sendExport(ctx, msg, content, fmt.Sprintf("killrights-attacker-%d", id), format, report.Rows())
+	} else {
+		err = s.sendPages(ctx, msg, content, attackerEmbeds(report, format))
+	}
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("failed to send message")
+		return err
+	}
+
+	return nil
+
+}
+
+func (s *Service) killrightVictimCommand(c *cli.Context) error {
+
+	ctx := c.Context
+
+	msg, err := messageFromCLIContext(c)
+	if err != nil {
+		return err
+	}
+
+	format, err := killright.ParseFormat(c.String("format"))
+	if err != nil {
+		return err
+	}
+
+	id, err := s.characterID(ctx, msg, c.Args().Get(0))
+	if err != nil {
+		return err
+	}
+
+	kr, err := s.searcher(c)
+	if err != nil {
+		return err
+	}
+
+	report, err := kr.Victim(ctx, id, msg.AuthorID, s.progress(ctx, msg))
+	if err != nil {
+		return err
+	}
+
+	s.warn(ctx, msg, report.Warning)
+
+	if len(report.Aggressors) == 0 {
+		_, err := s.transport.Reply(ctx, msg, appendLatency(msg, "0 agressors remained after filtering....", true))
+		if err != nil {
+			s.logger.WithContext(ctx).WithError(err).Errorln("failed to send message")
+		}
+
+		return nil
+	}
+
+	content := appendLatency(msg, fmt.Sprintf("Found %d potential attacker(s) who this victim may have killrights for", len(report.Aggressors)), true)
+	if format.Attached() {
+		err = s.sendExport(ctx, msg, content, fmt.Sprintf("killrights-victim-%d", id), format, report.Rows())
+	} else {
+		err = s.sendPages(ctx, msg, content, victimEmbeds(report, format))
+	}
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("failed to send message")
+		return err
+	}
+
+	return nil
+
+}
+
+func (s *Service) killrightReconcileCommand(c *cli.Context) error {
+
+	ctx := c.Context
+
+	msg, err := messageFromCLIContext(c)
+	if err != nil {
+		return err
+	}
+
+	format, err := killright.ParseFormat(c.String("format"))
+	if err != nil {
+		return err
+	}
+
+	id, err := s.characterID(ctx, msg, c.Args().Get(0))
+	if err != nil {
+		return err
+	}
+
+	reconciliation, err := s.killright.Reconcile(ctx, id, msg.AuthorID, s.progress(ctx, msg))
+	if err != nil {
+		return err
+	}
+
+	s.warn(ctx, msg, reconciliation.Warning)
+
+	lines := reconciliation.Lines(format)
+	_, err = s.transport.Reply(ctx, msg, appendLatency(msg, fmt.Sprintf("%.0f%% of kill rights were predicted correctly:\n```%s```", reconciliation.Accuracy()*100, strings.Join(lines, "\n")), false))
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("failed to send message")
+		return err
+	}
+
+	return nil
+
+}
+
+func (s *Service) killrightShipCommand(c *cli.Context) error {
+
+	ctx := c.Context
+
+	msg, err := messageFromCLIContext(c)
+	if err != nil {
+		return err
+	}
+
+	format, err := killright.ParseFormat(c.String("format"))
+	if err != nil {
+		return err
+	}
+
+	args := c.Args()
+	if args.Len() > 2 {
+		return errorcode.Newf(errorcode.InvalidArgument, "expected no more than 2 args, got %d", args.Len())
+	}
+
+	groupID, err := strconv.ParseUint(args.Get(0), 10, 32)
+	if err != nil {
+		return errorcode.Wrap(err, errorcode.InvalidArgument, "failed to parse ship group id")
+	}
+
+	var shipTypeID uint64
+	if args.Get(1) != "" {
+		shipTypeID, err = strconv.ParseUint(args.Get(1), 10, 32)
+		if err != nil {
+			return errorcode.Wrap(err, errorcode.InvalidArgument, "failed to parse ship type id to a valid integer")
+		}
+	}
+
+	kr, err := s.searcher(c)
+	if err != nil {
+		return err
+	}
+
+	report, err := kr.Ship(ctx, groupID, shipTypeID, s.progress(ctx, msg))
+	if err != nil {
+		return err
+	}
+
+	s.warn(ctx, msg, report.Warning)
+
+	if report.Killmails == 0 {
+		_, err := s.transport.Reply(ctx, msg, appendLatency(msg, "0 killmails remained after filtering....", true))
+		if err != nil {
+			s.logger.WithContext(ctx).WithError(err).Errorln("failed to send message")
+		}
+
+		return nil
+	}
+
+	if format.Attached() {
+		content := appendLatency(msg, fmt.Sprintf("Found a total of %d potential kill rights across %d ships", report.Killmails, len(report.Ships)), true)
+		err = s.sendExport(ctx, msg, content, fmt.Sprintf("killrights-ship-%d", groupID), format, report.Rows())
+		if err != nil {
+			s.logger.WithContext(ctx).WithError(err).Errorln("failed to send message")
+		}
+
+		return nil
+	}
+
+	if shipTypeID == 0 {
+		content := appendLatency(msg, fmt.Sprintf("Found a total of %d potential kill rights across %d ships", report.Killmails, len(report.Ships)), true)
+		err = s.sendPages(ctx, msg, content, shipSummaryEmbeds(report))
+		if err != nil {
+			s.logger.WithContext(ctx).WithError(err).Errorln("failed to send message")
+		}
+
+		return nil
+	}
+
+	content := appendLatency(msg, fmt.Sprintf("Found %d victims who lost ship type %d", len(report.Victims), shipTypeID), true)
+	err = s.sendPages(ctx, msg, content, shipVictimEmbeds(report, c.Bool("verbose")))
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Errorln("failed to send message")
+	}
+
+	return nil
+
+}
@@ -0,0 +1,138 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/eveisesi/krinder/internal/chat"
+	"github.com/eveisesi/krinder/internal/sso"
+	"github.com/eveisesi/krinder/pkg/errorcode"
+	"github.com/urfave/cli/v2"
+)
+
+func (s *Service) linkCommand(c *cli.Context) error {
+
+	msg, err := messageFromCLIContext(c)
+	if err != nil {
+		return err
+	}
+
+	uri, err := s.sso.AuthorizeURL(c.Context, msg.AuthorID, msg.ChannelID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.transport.Reply(c.Context, msg, fmt.Sprintf("Log in with EVE Online to link a character. The link can be used once within 10 minutes: %s", uri))
+
+	return err
+
+}
+
+func (s *Service) linkListCommand(c *cli.Context) error {
+
+	msg, err := messageFromCLIContext(c)
+	if err != nil {
+		return err
+	}
+
+	credentials, err := s.sso.Characters(c.Context, msg.AuthorID)
+	if err != nil {
+		return err
+	}
+
+	if len(credentials) == 0 {
+		_, err = s.transport.Reply(c.Context, msg, "No characters are linked, use link to link one")
+		return err
+	}
+
+	lines := make([]string, 0, len(credentials))
+	for _, credential := range credentials {
+		line := fmt.Sprintf("%d: %s", credential.CharacterID, credential.CharacterName)
+		if credential.Main {
+			line += " (main)"
+		}
+		lines = append(lines, line)
+	}
+
+	_, err = s.transport.Reply(c.Context, msg, fmt.Sprintf("```%s```", strings.Join(lines, "\n")))
+
+	return err
+
+}
+
+func (s *Service) linkMainCommand(c *cli.Context) error {
+
+	msg, err := messageFromCLIContext(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := parseCharacterID(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	err = s.sso.SetMain(c.Context, msg.AuthorID, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.transport.Reply(c.Context, msg, fmt.Sprintf("%d is now your main character", id))
+
+	return err
+
+}
+
+func (s *Service) linkRemoveCommand(c *cli.Context) error {
+
+	msg, err := messageFromCLIContext(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := parseCharacterID(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	err = s.sso.Unlink(c.Context, msg.AuthorID, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.transport.Reply(c.Context, msg, fmt.Sprintf("%d has been unlinked", id))
+
+	return err
+
+}
+
+// characterID resolves the character a command is about. Without an argument, or with me,
+// the main character linked by the author of msg is used, which requires linking to be enabled
+func (s *Service) characterID(ctx context.Context, msg *chat.Message, arg string) (uint64, error) {
+
+	if arg != "" && arg != "me" {
+		return parseCharacterID(arg)
+	}
+
+	if !s.sso.Enabled() {
+		return 0, sso.ErrDisabled
+	}
+
+	credential, err := s.sso.Main(ctx, msg.AuthorID)
+	if err != nil {
+		return 0, err
+	}
+
+	return credential.CharacterID, nil
+
+}
+
+func parseCharacterID(arg string) (uint64, error) {
+	id, err := strconv.ParseUint(arg, 10, 64)
+	if err != nil {
+		return 0, errorcode.Wrap(err, errorcode.InvalidArgument, "failed to parse id to integer")
+	}
+	return id, nil
+}
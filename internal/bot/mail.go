@@ -0,0 +1,43 @@
+package bot
+
+import (
+	"strconv"
+
+	"github.com/eveisesi/krinder/pkg/errorcode"
+	"github.com/urfave/cli/v2"
+)
+
+func (s *Service) mailCommand(c *cli.Context) error {
+
+	ctx := c.Context
+
+	msg, err := messageFromCLIContext(c)
+	if err != nil {
+		return err
+	}
+
+	args := c.Args()
+	if args.Len() != 1 {
+		return errorcode.Newf(errorcode.InvalidArgument, "expected 1 arg, got %d", args.Len())
+	}
+	id, err := strconv.ParseUint(args.Get(0), 10, 32)
+	if err != nil {
+		return errorcode.Wrap(err, errorcode.InvalidArgument, "failed to parse killmail id to integer")
+	}
+
+	summary, err := s.killright.Killmail(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	s.warn(ctx, msg, summary.Warning)
+
+	_, err = s.transport.Embed(ctx, msg, killmailEmbed(summary))
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("failed to send message")
+		return err
+	}
+
+	return nil
+
+}
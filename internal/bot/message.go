@@ -0,0 +1,204 @@
+package bot
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/eveisesi/krinder/internal/chat"
+	"github.com/eveisesi/krinder/internal/metrics"
+	"github.com/eveisesi/krinder/pkg/correlation"
+	"github.com/eveisesi/krinder/pkg/errorcode"
+	"github.com/kballard/go-shellquote"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+var ErrMissingMsgIFace = errors.New("[ErrMissingMsgIFace] msg missing from metadata. contact application maintainer")
+var ErrMsgIFaceTypeInvalid = errors.New("[ErrMsgIFaceTypeInvalid] invalid type for msg iface. contact application mainter")
+
+const restartingMessage = "The bot is restarting and your request was cancelled. Please try again in a few minutes"
+
+// Run opens the transport and handles commands until ctx is cancelled. Commands that are in
+// flight when ctx is cancelled run until work is cancelled, after which the users that issued
+// them are notified that their request was cancelled
+func (s *Service) Run(ctx, work context.Context, wg *sync.WaitGroup) {
+
+	defer wg.Done()
+
+	entry := s.logger.WithField("service", "bot")
+
+	if pager, ok := s.transport.(chat.Pager); ok {
+		pager.HandlePageTurns(s.turnPage)
+	}
+
+	err := s.transport.Open(ctx, func(msg *chat.Message) {
+		s.enqueue(ctx, msg)
+	})
+	if err != nil {
+		entry.WithError(err).Error("failed to open transport")
+		return
+	}
+
+	handling := make(chan struct{})
+	go func() {
+		defer close(handling)
+		s.handleMessageChannel(ctx, work)
+	}()
+
+	<-ctx.Done()
+	entry.Info("context cancelled, waiting for in flight commands")
+
+	<-handling
+
+	err = s.transport.Close()
+	if err != nil {
+		entry.WithError(err).Error("failed to close transport")
+	}
+
+	// Messages that were queued but never handled are cancelled as well
+	for {
+		select {
+		case msg := <-s.messages:
+			s.sendCancelled(msg)
+		default:
+			return
+		}
+	}
+
+}
+
+// enqueue queues a message received by the transport to be handled. Once ctx is cancelled nothing
+// handles the queue, so the message is cancelled straight away rather than blocking the transport
+func (s *Service) enqueue(ctx context.Context, msg *chat.Message) {
+
+	if ctx.Err() != nil {
+		s.sendCancelled(msg)
+		return
+	}
+
+	select {
+	case s.messages <- msg:
+	case <-ctx.Done():
+		s.sendCancelled(msg)
+	}
+
+}
+
+func (s *Service) handleMessageChannel(ctx, work context.Context) {
+
+	for {
+		select {
+		case msg := <-s.messages:
+			s.Handle(work, msg)
+		case <-ctx.Done():
+			return
+		}
+	}
+
+}
+
+// Handle runs the command in msg and replies with its outcome. Failures are logged and
+// the user is told what went wrong; if ctx is cancelled the user is told to try again later
+func (s *Service) Handle(ctx context.Context, msg *chat.Message) {
+
+	// Every command gets its own correlation id, which is logged by everything
+	// the command does and quoted to the user if it fails
+	id := correlation.New()
+	ctx = correlation.WithID(ctx, id)
+
+	err := s.handleCommand(ctx, msg)
+	if err == nil {
+		return
+	}
+
+	if ctx.Err() != nil {
+		s.sendCancelled(msg)
+		return
+	}
+
+	s.logger.WithContext(ctx).WithError(err).WithFields(logrus.Fields{
+		"service": "bot",
+		"code":    errorcode.Code(err),
+	}).Error("command failed")
+
+	_, err = s.transport.Reply(ctx, msg, userMessage(err, id))
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("failed to send message")
+	}
+
+}
+
+func (s *Service) sendCancelled(msg *chat.Message) {
+	// The work context is already cancelled, so the notice is sent without one
+	_, err := s.transport.Reply(context.Background(), msg, restartingMessage)
+	if err != nil {
+		s.logger.WithError(err).Error("failed to send message")
+	}
+}
+
+func (s *Service) handleCommand(ctx context.Context, msg *chat.Message) (err error) {
+
+	defer buf.Reset()
+
+	entry := s.logger.WithContext(ctx).WithField("service", "bot")
+
+	words, err := shellquote.Split(msg.Content)
+	if err != nil {
+		entry.WithError(err).Error("failed to parse inputted command")
+		return nil
+	}
+
+	app := s.initializeCLI()
+
+	command, ok := s.commandName(app, words)
+	if !ok {
+		return nil
+	}
+
+	entry.WithFields(logrus.Fields{
+		"command": command,
+		"author":  msg.AuthorID,
+		"args":    msg.Content,
+	}).Info("handling command")
+
+	start := time.Now()
+	defer func() { metrics.ObserveCommand(command, start, err) }()
+
+	words = append([]string{"krinder"}, words...)
+
+	app.Metadata["msg"] = msg
+
+	err = app.RunContext(ctx, words)
+	if err != nil {
+		return err
+	}
+
+	if buf.String() == "" {
+		return nil
+	}
+
+	_, err = s.transport.Reply(ctx, msg, buf.String())
+	if err != nil {
+		return err
+	}
+
+	return nil
+
+}
+
+func messageFromCLIContext(c *cli.Context) (*chat.Message, error) {
+	msgIface, ok := c.App.Metadata["msg"]
+	if !ok {
+		return nil, ErrMissingMsgIFace
+	}
+
+	msg, ok := msgIface.(*chat.Message)
+	if !ok {
+		return nil, ErrMsgIFaceTypeInvalid
+	}
+
+	return msg, nil
+
+}
@@ -0,0 +1,150 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/internal/chat"
+	"github.com/eveisesi/krinder/pkg/correlation"
+	"github.com/eveisesi/krinder/pkg/errorcode"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// pageTTL is how long the buttons of a paged reply keep working
+const pageTTL = 7 * 24 * time.Hour
+
+// pageSize is the number of entries on each page of a paged reply. Discord allows 25 fields per embed
+const pageSize = 10
+
+var (
+	errPagesExpired  = errors.New("These results have expired. Run the command again to see them")
+	errPagesNotOwned = errors.New("Only the user that ran the command can turn its pages")
+)
+
+// sendPages replies to msg with content and the first of embeds, which the user pages through with
+// buttons. The pages are stored before the buttons are added, so that every press finds them, and
+// keep working after a restart. Transports that cannot page are sent every embed instead
+func (s *Service) sendPages(ctx context.Context, msg *chat.Message, content string, embeds []*chat.Embed) error {
+
+	if len(embeds) == 0 {
+		if content == "" {
+			content = "No results"
+		}
+		_, err := s.transport.Reply(ctx, msg, content)
+		return err
+	}
+
+	pager, ok := s.transport.(chat.Pager)
+	if !ok {
+		if content != "" {
+			_, err := s.transport.Reply(ctx, msg, content)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, embed := range embeds {
+			_, err := s.transport.Embed(ctx, msg, embed)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	first := &chat.Page{Embed: embeds[0], Count: len(embeds)}
+
+	messageID, err := pager.SendPage(ctx, msg, content, first)
+	if err != nil {
+		return err
+	}
+
+	if len(embeds) == 1 {
+		return nil
+	}
+
+	pages := make([]string, 0, len(embeds))
+	for _, embed := range embeds {
+		data, err := json.Marshal(embed)
+		if err != nil {
+			return errors.Wrap(err, "failed to encode page")
+		}
+		pages = append(pages, string(data))
+	}
+
+	err = s.paginations.CreatePagination(ctx, &krinder.Pagination{
+		MessageID: messageID,
+		ChannelID: msg.ChannelID,
+		AuthorID:  msg.AuthorID,
+		Pages:     pages,
+		ExpiresAt: s.clock.Now().Add(pageTTL).UTC(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to save pages")
+	}
+
+	return pager.AddPageButtons(ctx, msg.ChannelID, messageID, first)
+
+}
+
+// turnPage returns the page a paged reply shows after a button is pressed. Errors are described to
+// the user that pressed the button
+func (s *Service) turnPage(ctx context.Context, turn *chat.PageTurn) (*chat.Page, error) {
+
+	id := correlation.New()
+	ctx = correlation.WithID(ctx, id)
+
+	page, err := s.page(ctx, turn)
+	if err == nil || errors.Is(err, errPagesExpired) || errors.Is(err, errPagesNotOwned) {
+		return page, err
+	}
+
+	s.logger.WithContext(ctx).WithError(err).WithFields(logrus.Fields{
+		"service":   "bot",
+		"code":      errorcode.Code(err),
+		"messageID": turn.MessageID,
+	}).Error("failed to turn page")
+
+	return nil, errors.New(userMessage(err, id))
+
+}
+
+func (s *Service) page(ctx context.Context, turn *chat.PageTurn) (*chat.Page, error) {
+
+	pagination, err := s.paginations.Pagination(ctx, turn.MessageID)
+	if errors.Is(err, krinder.ErrNotFound) {
+		return nil, errPagesExpired
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch pages")
+	}
+
+	if len(pagination.Pages) == 0 || !s.clock.Now().Before(pagination.ExpiresAt) {
+		return nil, errPagesExpired
+	}
+	if pagination.AuthorID != turn.UserID {
+		return nil, errPagesNotOwned
+	}
+
+	index := turn.Action.Turn(pagination.Page, len(pagination.Pages))
+
+	var embed = new(chat.Embed)
+	err = json.Unmarshal([]byte(pagination.Pages[index]), embed)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode page")
+	}
+
+	if index != pagination.Page {
+		err = s.paginations.UpdatePaginationPage(ctx, turn.MessageID, index)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to save page")
+		}
+	}
+
+	return &chat.Page{Embed: embed, Index: index, Count: len(pagination.Pages)}, nil
+
+}
@@ -0,0 +1,207 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/internal/chat"
+	"github.com/eveisesi/krinder/internal/esi"
+	"github.com/eveisesi/krinder/internal/killright"
+	"github.com/eveisesi/krinder/internal/repl"
+	"github.com/eveisesi/krinder/internal/store/memory"
+	"github.com/eveisesi/krinder/pkg/clock"
+	"github.com/sirupsen/logrus"
+)
+
+// pager is a terminal transport that records the pages it is asked to send, and the messages it
+// added buttons to
+type pager struct {
+	*repl.Transport
+	sent    []*chat.Page
+	buttons []string
+}
+
+func (p *pager) SendPage(ctx context.Context, msg *chat.Message, content string, page *chat.Page) (string, error) {
+	p.sent = append(p.sent, page)
+	return fmt.Sprintf("message-%d", len(p.sent)), nil
+}
+
+func (p *pager) AddPageButtons(ctx context.Context, channelID, messageID string, page *chat.Page) error {
+	p.buttons = append(p.buttons, messageID)
+	return nil
+}
+
+func (p *pager) HandlePageTurns(turn func(ctx context.Context, t *chat.PageTurn) (*chat.Page, error)) {
+}
+
+func TestPages(t *testing.T) {
+
+	ctx := context.Background()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	now := clock.NewFake(time.Date(2021, 11, 15, 12, 0, 0, 0, time.UTC))
+	paginations := memory.NewPaginationRepository(now)
+	transport := &pager{Transport: repl.New(strings.NewReader(""), io.Discard)}
+
+	service := func() *Service {
+		return New(logger, transport, nil, nil, nil, nil, nil, paginations, now)
+	}
+
+	embeds := paginate(25, func(start, end int) *chat.Embed {
+		return &chat.Embed{Title: fmt.Sprintf("%d-%d", start, end)}
+	})
+	if len(embeds) != 3 {
+		t.Fatalf("expected 25 entries to make 3 pages, got %d", len(embeds))
+	}
+
+	msg := &chat.Message{ChannelID: "channel", AuthorID: "author"}
+	if err := service().sendPages(ctx, msg, "Found 25", embeds); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(transport.sent) != 1 || transport.sent[0].Embed.Title != "0-10" || transport.sent[0].Count != 3 {
+		t.Fatalf("expected the first of 3 pages to be sent, got %+v", transport.sent)
+	}
+
+	if len(transport.buttons) != 1 || transport.buttons[0] != "message-1" {
+		t.Errorf("expected buttons to be added to message-1, got %v", transport.buttons)
+	}
+
+	saved, err := paginations.Pagination(ctx, "message-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !saved.CreatedAt.Equal(now.Now()) || !saved.ExpiresAt.Equal(now.Now().Add(pageTTL)) {
+		t.Errorf("expected pages to be saved at %s, got %+v", now.Now(), saved)
+	}
+
+	// Pages are turned by a new service, as they would be after a restart
+	restarted := service()
+	turns := []struct {
+		action chat.PageAction
+		title  string
+		index  int
+	}{
+		{chat.PageNext, "10-20", 1},
+		{chat.PageLast, "20-25", 2},
+		{chat.PageNext, "20-25", 2},
+		{chat.PagePrevious, "10-20", 1},
+		{chat.PageFirst, "0-10", 0},
+	}
+	for _, turn := range turns {
+		page, err := restarted.turnPage(ctx, &chat.PageTurn{MessageID: "message-1", UserID: "author", Action: turn.action})
+		if err != nil {
+			t.Fatalf("unexpected error turning to %s: %s", turn.action, err)
+		}
+		if page.Embed.Title != turn.title || page.Index != turn.index || page.Count != 3 {
+			t.Errorf("expected %s to show page %d (%s), got page %d (%s)", turn.action, turn.index, turn.title, page.Index, page.Embed.Title)
+		}
+	}
+
+	_, err = restarted.turnPage(ctx, &chat.PageTurn{MessageID: "message-1", UserID: "someone", Action: chat.PageNext})
+	if !errors.Is(err, errPagesNotOwned) {
+		t.Errorf("expected other users to be refused, got %v", err)
+	}
+
+	_, err = restarted.turnPage(ctx, &chat.PageTurn{MessageID: "message-2", UserID: "author", Action: chat.PageNext})
+	if !errors.Is(err, errPagesExpired) {
+		t.Errorf("expected unknown messages to be expired, got %v", err)
+	}
+
+	now.Advance(pageTTL)
+	_, err = restarted.turnPage(ctx, &chat.PageTurn{MessageID: "message-1", UserID: "author", Action: chat.PageNext})
+	if !errors.Is(err, errPagesExpired) {
+		t.Errorf("expected pages to expire, got %v", err)
+	}
+
+}
+
+func TestPagesFitEmbedLimit(t *testing.T) {
+
+	report := &killright.ShipReport{ShipTypeID: 587}
+	for i := 0; i < 25; i++ {
+		victim := &killright.ShipVictim{Character: &esi.CharacterOk{ID: uint64(i), Name: fmt.Sprintf("Victim %d", i)}}
+		for j := 0; j < 50; j++ {
+			victim.Aggressors = append(victim.Aggressors, &killright.Aggressor{
+				Character: &esi.CharacterOk{ID: uint64(1000 + j), Name: fmt.Sprintf("Aggressor With A Long Name %d", j)},
+				Seen:      1,
+			})
+		}
+		report.Victims = append(report.Victims, victim)
+	}
+
+	victims := 0
+	for _, embed := range shipVictimEmbeds(report, true) {
+		if length := embedLength(embed); length > embedLimit {
+			t.Errorf("expected pages to fit in %d characters, got %d", embedLimit, length)
+		}
+		victims += len(embed.Fields)
+	}
+	if victims != len(report.Victims) {
+		t.Errorf("expected every victim to be paged, got %d", victims)
+	}
+
+}
+
+func TestSendPagesWithoutEmbeds(t *testing.T) {
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	var out strings.Builder
+	transport := &pager{Transport: repl.New(strings.NewReader(""), &out)}
+	s := New(logger, transport, nil, nil, nil, nil, nil, memory.NewPaginationRepository(clock.New()), clock.New())
+
+	err := s.sendPages(context.Background(), &chat.Message{ChannelID: "channel"}, "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(transport.sent) != 0 || !strings.Contains(out.String(), "No results") {
+		t.Errorf("expected a reply without pages, got %d pages and %q", len(transport.sent), out.String())
+	}
+
+}
+
+func TestSendPagesWithoutButtonsUntilSaved(t *testing.T) {
+
+	ctx := context.Background()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	paginations := memory.NewPaginationRepository(clock.New())
+	transport := &pager{Transport: repl.New(strings.NewReader(""), io.Discard)}
+	s := New(logger, transport, nil, nil, nil, nil, nil, paginations, clock.New())
+
+	// The pages of message-1 cannot be saved, as that message already has pages
+	err := paginations.CreatePagination(ctx, &krinder.Pagination{MessageID: "message-1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	embeds := []*chat.Embed{{Title: "first"}, {Title: "second"}}
+	err = s.sendPages(ctx, &chat.Message{ChannelID: "channel"}, "", embeds)
+	if !errors.Is(err, krinder.ErrDuplicate) {
+		t.Fatalf("expected the pages not to be saved, got %v", err)
+	}
+	if len(transport.sent) != 1 || len(transport.buttons) != 0 {
+		t.Errorf("expected the first page to be sent without buttons, got %d pages and buttons on %v", len(transport.sent), transport.buttons)
+	}
+
+	// A single page has nothing to turn to
+	err = s.sendPages(ctx, &chat.Message{ChannelID: "channel"}, "", embeds[:1])
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(transport.sent) != 2 || len(transport.buttons) != 0 {
+		t.Errorf("expected a single page to be sent without buttons, got %d pages and buttons on %v", len(transport.sent), transport.buttons)
+	}
+
+}
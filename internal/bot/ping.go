@@ -1,4 +1,4 @@
-package discord
+package bot
 
 import (
 	"github.com/urfave/cli/v2"
@@ -11,7 +11,7 @@ func (s *Service) pingCommand(c *cli.Context) error {
 		return err
 	}
 
-	_, err = s.session.ChannelMessageSend(msg.ChannelID, appendLatencyToMessageCreate(msg, "Pong!", true))
+	_, err = s.transport.Reply(c.Context, msg, appendLatency(msg, "Pong!", true))
 
 	return err
 
@@ -0,0 +1,81 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eveisesi/krinder/internal/chat"
+	"github.com/eveisesi/krinder/pkg/errorcode"
+	"github.com/urfave/cli/v2"
+)
+
+func (s *Service) searchCommand(c *cli.Context) error {
+
+	msg, err := messageFromCLIContext(c)
+	if err != nil {
+		return err
+	}
+
+	args := c.Args()
+	if args.Len() > 2 {
+		return errorcode.Newf(errorcode.InvalidArgument, "expected 2 args, got %d. Surround name in double quotes \"<name>\"", args.Len())
+	}
+
+	category := args.Get(0)
+	term := args.Get(1)
+
+	ctx, cancel := context.WithTimeout(c.Context, time.Second*10)
+	defer cancel()
+
+	results, err := s.universe.Search(ctx, category, term, c.Bool("strict"))
+	if err != nil {
+		return err
+	}
+
+	if category == "invgroup" {
+		s.warn(ctx, msg, s.universe.Warning())
+	}
+
+	if len(results) == 0 {
+		_, err := s.transport.Reply(ctx, msg, "search return 0 results")
+		if err != nil {
+			s.logger.WithContext(ctx).WithError(err).Error("failed to send message")
+			return err
+		}
+	}
+
+	var contentSlc = make([]string, 0, len(results))
+	var bLen = 0
+	for _, result := range results {
+		content := fmt.Sprintf("%d: %s", result.ID, result.Name)
+		conLen := len(content)
+		if bLen+conLen > 4000 {
+			break
+		}
+		bLen += conLen
+		contentSlc = append(contentSlc, content)
+
+	}
+
+	_, err = s.transport.Reply(ctx, msg, appendLatency(msg, fmt.Sprintf("Search Return %d Results: ```%s```", len(contentSlc), strings.Join(contentSlc, "\n")), false))
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("failed to send message")
+		return err
+	}
+
+	return nil
+
+}
+
+func appendLatency(msg *chat.Message, out string, useNewline bool) string {
+
+	latency := time.Since(msg.Received)
+	format := "%s_latency_: %v"
+	if useNewline {
+		format = "%s\n_latency_: %v"
+	}
+	return fmt.Sprintf(format, out, latency.String())
+
+}
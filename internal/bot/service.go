@@ -0,0 +1,52 @@
+// Package bot implements the commands of the bot independently of the chat platform they are
+// issued on. Replies are sent through a chat.Transport
+package bot
+
+import (
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/internal/chat"
+	"github.com/eveisesi/krinder/internal/esi"
+	"github.com/eveisesi/krinder/internal/killright"
+	"github.com/eveisesi/krinder/internal/sso"
+	"github.com/eveisesi/krinder/internal/universe"
+	"github.com/eveisesi/krinder/internal/wars"
+	"github.com/eveisesi/krinder/pkg/clock"
+	"github.com/sirupsen/logrus"
+)
+
+type Service struct {
+	logger *logrus.Logger
+
+	transport chat.Transport
+
+	esi esi.API
+
+	killright *killright.Service
+	universe  *universe.Service
+	wars      *wars.Service
+	sso       *sso.Service
+
+	paginations krinder.PaginationRepository
+	clock       clock.Clock
+
+	messages chan *chat.Message
+}
+
+func New(logger *logrus.Logger, transport chat.Transport, esi esi.API, killright *killright.Service, universe *universe.Service, wars *wars.Service, sso *sso.Service, paginations krinder.PaginationRepository, clock clock.Clock) *Service {
+	return &Service{
+		logger:    logger,
+		transport: transport,
+
+		esi: esi,
+
+		killright: killright,
+		universe:  universe,
+		wars:      wars,
+		sso:       sso,
+
+		paginations: paginations,
+		clock:       clock,
+
+		messages: make(chan *chat.Message, 5),
+	}
+}
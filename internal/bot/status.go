@@ -0,0 +1,40 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/eveisesi/krinder/internal/chat"
+	"github.com/urfave/cli/v2"
+)
+
+// warn tells the channel the command was issued in that results were built from incomplete data
+func (s *Service) warn(ctx context.Context, msg *chat.Message, warning string) {
+	if warning == "" {
+		return
+	}
+
+	_, err := s.transport.Reply(ctx, msg, fmt.Sprintf(":warning: %s", warning))
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Errorln("failed to send message")
+	}
+}
+
+func (s *Service) statusCommand(c *cli.Context) error {
+
+	msg, err := messageFromCLIContext(c)
+	if err != nil {
+		return err
+	}
+
+	lines := []string{
+		s.wars.Status().String(),
+		s.universe.Status().String(),
+	}
+
+	_, err = s.transport.Reply(c.Context, msg, appendLatency(msg, fmt.Sprintf("```%s```", strings.Join(lines, "\n")), false))
+
+	return err
+
+}
@@ -0,0 +1,62 @@
+package bot
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/eveisesi/krinder/internal/chat"
+	"github.com/eveisesi/krinder/pkg/errorcode"
+	"github.com/urfave/cli/v2"
+)
+
+func (s *Service) whoCommand(c *cli.Context) error {
+
+	ctx := c.Context
+
+	msg, err := messageFromCLIContext(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := s.whoCharacterID(ctx, msg, strings.Join(c.Args().Slice(), " "))
+	if err != nil {
+		return err
+	}
+
+	profile, err := s.killright.Profile(ctx, id, msg.AuthorID, s.progress(ctx, msg))
+	if err != nil {
+		return err
+	}
+
+	s.warn(ctx, msg, profile.Warning)
+
+	_, err = s.transport.Embed(ctx, msg, profileEmbed(profile))
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("failed to send message")
+		return err
+	}
+
+	return nil
+
+}
+
+// whoCharacterID resolves the argument of who, which is a character id, an exact character name or
+// me for the linked main character
+func (s *Service) whoCharacterID(ctx context.Context, msg *chat.Message, arg string) (uint64, error) {
+
+	if _, err := strconv.ParseUint(arg, 10, 64); err == nil || arg == "" || arg == "me" {
+		return s.characterID(ctx, msg, arg)
+	}
+
+	results, err := s.universe.Search(ctx, "character", arg, true)
+	if err != nil {
+		return 0, err
+	}
+	if len(results) == 0 {
+		return 0, errorcode.Newf(errorcode.NotFound, "no character is named %s", arg)
+	}
+
+	return uint64(results[0].ID), nil
+
+}
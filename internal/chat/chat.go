@@ -0,0 +1,123 @@
+// Package chat describes the chat platforms the bot can be driven from, so that commands
+// are written once and served over Discord, a local terminal or anything else
+package chat
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Message is a message received from a user of a chat platform
+type Message struct {
+	// ID identifies the message on its platform
+	ID string
+	// ChannelID identifies the conversation replies are sent to
+	ChannelID string
+	AuthorID  string
+	Content   string
+	// Received is when the platform received the message, which is used to report latency
+	Received time.Time
+}
+
+type Embed struct {
+	Title       string
+	Description string
+	URL         string
+	Color       int
+	Fields      []*EmbedField
+	Footer      string
+	// Thumbnail is the URL of an image shown beside the embed, such as a character portrait
+	Thumbnail string
+}
+
+type EmbedField struct {
+	Name   string
+	Value  string
+	Inline bool
+}
+
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        io.Reader
+}
+
+// Transport connects the bot to a chat platform. Replies are sent to the conversation
+// of the message they answer and return the id of the sent message, which can be edited
+type Transport interface {
+	// Open connects to the platform and passes every message received to deliver
+	// until Close is called. deliver must not block for long
+	Open(ctx context.Context, deliver func(msg *Message)) error
+	Close() error
+	// Ready reports whether the transport is connected
+	Ready(ctx context.Context) error
+
+	Reply(ctx context.Context, msg *Message, content string) (string, error)
+	Edit(ctx context.Context, msg *Message, messageID, content string) error
+	Attach(ctx context.Context, msg *Message, content string, attachments ...*Attachment) (string, error)
+	Embed(ctx context.Context, msg *Message, embed *Embed) (string, error)
+}
+
+// Page is one of the pages of a reply that is paged through with buttons
+type Page struct {
+	Embed *Embed
+	// Index is the zero based index of the page among Count pages
+	Index int
+	Count int
+}
+
+// PageAction is the button of a paged reply that was pressed
+type PageAction string
+
+const (
+	PageFirst    PageAction = "first"
+	PagePrevious PageAction = "previous"
+	PageNext     PageAction = "next"
+	PageLast     PageAction = "last"
+)
+
+// Turn returns the index of the page the action moves to from index, among count pages
+func (a PageAction) Turn(index, count int) int {
+	switch a {
+	case PageFirst:
+		index = 0
+	case PagePrevious:
+		index--
+	case PageNext:
+		index++
+	case PageLast:
+		index = count - 1
+	}
+
+	if index >= count {
+		index = count - 1
+	}
+	if index < 0 {
+		index = 0
+	}
+
+	return index
+}
+
+// PageTurn is a press of one of the buttons of a paged reply
+type PageTurn struct {
+	ChannelID string
+	MessageID string
+	// UserID is the user that pressed the button
+	UserID string
+	Action PageAction
+}
+
+// Pager is implemented by transports that can page through embeds with buttons in a single message
+type Pager interface {
+	// SendPage replies to msg with content and the page, and returns the id of the message it sent
+	SendPage(ctx context.Context, msg *Message, content string, page *Page) (string, error)
+	// AddPageButtons adds the buttons that turn page to a message SendPage sent. They are added
+	// separately so that the pages are saved before anyone can press them
+	AddPageButtons(ctx context.Context, channelID, messageID string, page *Page) error
+	// HandlePageTurns registers turn, which must be done before Open. turn is called for every press
+	// of a page button and returns the page the message shows next. The message of an error turn
+	// returns is shown to the user that pressed the button
+	HandlePageTurns(turn func(ctx context.Context, t *PageTurn) (*Page, error))
+}
@@ -1,62 +1,340 @@
 package discord
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/eveisesi/krinder/internal/esi"
-	"github.com/eveisesi/krinder/internal/universe"
-	"github.com/eveisesi/krinder/internal/wars"
-	"github.com/eveisesi/krinder/internal/zkillboard"
+	"github.com/eveisesi/krinder/internal/chat"
+	"github.com/eveisesi/krinder/pkg/clock"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
-// exported for testing
-type API interface {
-}
-
-type Service struct {
+// Transport serves the bot over the Discord Gateway
+type Transport struct {
 	environment string
 	logger      *logrus.Logger
+	clock       clock.Clock
 
 	session *discordgo.Session
 
-	zkb *zkillboard.Service
-	esi esi.API
-
-	wars     *wars.Service
-	universe *universe.Service
-
-	messages chan *discordgo.MessageCreate
+	mu      sync.RWMutex
+	deliver func(msg *chat.Message)
+	turn    func(ctx context.Context, t *chat.PageTurn) (*chat.Page, error)
 }
 
-func New(token, environment string, logger *logrus.Logger, zkb *zkillboard.Service, esi esi.API, wars *wars.Service, universe *universe.Service) *Service {
-	s := &Service{
+var _ chat.Transport = new(Transport)
+var _ chat.Pager = new(Transport)
+
+func New(token, environment string, logger *logrus.Logger, clock clock.Clock) *Transport {
+	t := &Transport{
 		environment: environment,
 		logger:      logger,
-
-		zkb: zkb,
-		esi: esi,
-
-		wars:     wars,
-		universe: universe,
-
-		messages: make(chan *discordgo.MessageCreate, 5),
+		clock:       clock,
 	}
 
-	s.session = s.newDiscordSession(token)
+	t.session = t.newDiscordSession(token)
 
-	return s
+	return t
 }
 
-func (s *Service) newDiscordSession(token string) *discordgo.Session {
+func (t *Transport) newDiscordSession(token string) *discordgo.Session {
 	dgo, err := discordgo.New(fmt.Sprintf("Bot %s", token))
 	if err != nil {
 		panic(fmt.Sprintf("failed to initialize discord service: %s", err))
 	}
 
-	dgo.AddHandler(s.ready)
-	dgo.AddHandler(s.handleMessageCreate)
+	dgo.AddHandler(t.ready)
+	dgo.AddHandler(t.handleMessageCreate)
+	dgo.AddHandler(t.handleInteractionCreate)
 
 	return dgo
 }
+
+// Open opens the session with the Discord Gateway
+func (t *Transport) Open(ctx context.Context, deliver func(msg *chat.Message)) error {
+	t.mu.Lock()
+	t.deliver = deliver
+	t.mu.Unlock()
+
+	err := t.session.Open()
+	if err != nil {
+		return errors.Wrap(err, "failed to open discord connection")
+	}
+
+	t.logger.WithField("service", "discord").Info("session initialize successfully, listening for messages")
+
+	return nil
+}
+
+func (t *Transport) Close() error {
+	t.logger.WithField("service", "discord").Info("closing session")
+	return t.session.Close()
+}
+
+// Ready reports whether the session with the Discord Gateway is open
+func (t *Transport) Ready(ctx context.Context) error {
+	t.session.RLock()
+	defer t.session.RUnlock()
+	if !t.session.DataReady {
+		return errors.New("discord session is not open")
+	}
+	return nil
+}
+
+func (t *Transport) ready(sess *discordgo.Session, event *discordgo.Ready) {
+	err := sess.UpdateGameStatus(0, "Kill! Kill! Kill!")
+	if err != nil {
+		t.logger.WithError(err).Error("failed to set bot status")
+	}
+}
+
+func (t *Transport) handleMessageCreate(sess *discordgo.Session, msg *discordgo.MessageCreate) {
+
+	// Ignore our own messages
+	if msg.Author.ID == sess.State.User.ID {
+		return
+	}
+
+	if t.environment == "production" {
+		channel, err := t.session.Channel(msg.ChannelID)
+		if err != nil {
+			t.logger.WithError(err).Error("failed to fetch channel infomation")
+			return
+		}
+
+		if channel.Type != discordgo.ChannelTypeDM {
+			return
+		}
+	}
+
+	received := msg.Timestamp
+	if received.IsZero() {
+		received = t.clock.Now()
+	}
+
+	t.mu.RLock()
+	deliver := t.deliver
+	t.mu.RUnlock()
+
+	deliver(&chat.Message{
+		ID:        msg.ID,
+		ChannelID: msg.ChannelID,
+		AuthorID:  msg.Author.ID,
+		Content:   msg.Content,
+		Received:  received,
+	})
+
+}
+
+func (t *Transport) Reply(ctx context.Context, msg *chat.Message, content string) (string, error) {
+	sent, err := t.session.ChannelMessageSend(msg.ChannelID, content)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to send message")
+	}
+	return sent.ID, nil
+}
+
+func (t *Transport) Edit(ctx context.Context, msg *chat.Message, messageID, content string) error {
+	_, err := t.session.ChannelMessageEdit(msg.ChannelID, messageID, content)
+	return errors.Wrap(err, "failed to edit message")
+}
+
+func (t *Transport) Attach(ctx context.Context, msg *chat.Message, content string, attachments ...*chat.Attachment) (string, error) {
+
+	files := make([]*discordgo.File, 0, len(attachments))
+	for _, attachment := range attachments {
+		files = append(files, &discordgo.File{
+			Name:        attachment.Name,
+			ContentType: attachment.ContentType,
+			Reader:      attachment.Data,
+		})
+	}
+
+	sent, err := t.session.ChannelMessageSendComplex(msg.ChannelID, &discordgo.MessageSend{
+		Content: content,
+		Files:   files,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to send attachments")
+	}
+
+	return sent.ID, nil
+
+}
+
+func (t *Transport) Embed(ctx context.Context, msg *chat.Message, embed *chat.Embed) (string, error) {
+
+	sent, err := t.session.ChannelMessageSendEmbed(msg.ChannelID, messageEmbed(embed))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to send embed")
+	}
+
+	return sent.ID, nil
+
+}
+
+func (t *Transport) SendPage(ctx context.Context, msg *chat.Message, content string, page *chat.Page) (string, error) {
+
+	sent, err := t.session.ChannelMessageSendComplex(msg.ChannelID, &discordgo.MessageSend{
+		Content: content,
+		Embeds:  []*discordgo.MessageEmbed{pageEmbed(page)},
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to send page")
+	}
+
+	return sent.ID, nil
+
+}
+
+func (t *Transport) AddPageButtons(ctx context.Context, channelID, messageID string, page *chat.Page) error {
+
+	edit := discordgo.NewMessageEdit(channelID, messageID)
+	edit.Components = pageButtons(page)
+
+	_, err := t.session.ChannelMessageEditComplex(edit)
+
+	return errors.Wrap(err, "failed to add page buttons")
+
+}
+
+func (t *Transport) HandlePageTurns(turn func(ctx context.Context, t *chat.PageTurn) (*chat.Page, error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.turn = turn
+}
+
+// pageButtonPrefix prefixes the custom id of page buttons, which is followed by the chat.PageAction
+const pageButtonPrefix = "page:"
+
+func (t *Transport) handleInteractionCreate(sess *discordgo.Session, interaction *discordgo.InteractionCreate) {
+
+	if interaction.Type != discordgo.InteractionMessageComponent || interaction.Message == nil {
+		return
+	}
+
+	customID := interaction.MessageComponentData().CustomID
+	if !strings.HasPrefix(customID, pageButtonPrefix) {
+		return
+	}
+
+	t.mu.RLock()
+	turn := t.turn
+	t.mu.RUnlock()
+	if turn == nil {
+		return
+	}
+
+	// The user is set for interactions in DMs and the member in guilds
+	user := interaction.User
+	if interaction.Member != nil {
+		user = interaction.Member.User
+	}
+	if user == nil {
+		return
+	}
+
+	response := &discordgo.InteractionResponse{Type: discordgo.InteractionResponseUpdateMessage}
+
+	page, err := turn(context.Background(), &chat.PageTurn{
+		ChannelID: interaction.ChannelID,
+		MessageID: interaction.Message.ID,
+		UserID:    user.ID,
+		Action:    chat.PageAction(strings.TrimPrefix(customID, pageButtonPrefix)),
+	})
+	if err != nil {
+		response = &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: err.Error(),
+				Flags:   uint64(discordgo.MessageFlagsEphemeral),
+			},
+		}
+	} else {
+		response.Data = &discordgo.InteractionResponseData{
+			Content:    interaction.Message.Content,
+			Embeds:     []*discordgo.MessageEmbed{pageEmbed(page)},
+			Components: pageButtons(page),
+		}
+	}
+
+	err = sess.InteractionRespond(interaction.Interaction, response)
+	if err != nil {
+		t.logger.WithError(err).WithField("service", "discord").Error("failed to respond to page turn")
+	}
+
+}
+
+func messageEmbed(embed *chat.Embed) *discordgo.MessageEmbed {
+
+	fields := make([]*discordgo.MessageEmbedField, 0, len(embed.Fields))
+	for _, field := range embed.Fields {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   field.Name,
+			Value:  field.Value,
+			Inline: field.Inline,
+		})
+	}
+
+	e := &discordgo.MessageEmbed{
+		Title:       embed.Title,
+		Description: embed.Description,
+		URL:         embed.URL,
+		Color:       embed.Color,
+		Fields:      fields,
+	}
+	if embed.Footer != "" {
+		e.Footer = &discordgo.MessageEmbedFooter{Text: embed.Footer}
+	}
+	if embed.Thumbnail != "" {
+		e.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: embed.Thumbnail}
+	}
+
+	return e
+
+}
+
+// pageEmbed returns the embed of the page with the page number in its footer
+func pageEmbed(page *chat.Page) *discordgo.MessageEmbed {
+
+	e := messageEmbed(page.Embed)
+
+	footer := fmt.Sprintf("Page %d of %d", page.Index+1, page.Count)
+	if e.Footer != nil {
+		footer = fmt.Sprintf("%s • %s", e.Footer.Text, footer)
+	}
+	e.Footer = &discordgo.MessageEmbedFooter{Text: footer}
+
+	return e
+
+}
+
+// pageButtons returns the buttons that turn the page, disabling those that would not move it
+func pageButtons(page *chat.Page) []discordgo.MessageComponent {
+
+	first, last := page.Index == 0, page.Index >= page.Count-1
+	button := func(label string, action chat.PageAction, disabled bool) discordgo.MessageComponent {
+		return discordgo.Button{
+			Label:    label,
+			Style:    discordgo.SecondaryButton,
+			Disabled: disabled,
+			CustomID: pageButtonPrefix + string(action),
+		}
+	}
+
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				button("« First", chat.PageFirst, first),
+				button("‹ Prev", chat.PagePrevious, first),
+				button("Next ›", chat.PageNext, last),
+				button("Last »", chat.PageLast, last),
+			},
+		},
+	}
+
+}
@@ -0,0 +1,17 @@
+package esi
+
+import "context"
+
+type accessTokenKey struct{}
+
+// WithAccessToken returns a context whose requests to ESI are authenticated with token, which is
+// required by character scoped endpoints. Authenticated responses are private to the character
+// and are never cached
+func WithAccessToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, accessTokenKey{}, token)
+}
+
+func accessToken(ctx context.Context) string {
+	token, _ := ctx.Value(accessTokenKey{}).(string)
+	return token
+}
@@ -0,0 +1,59 @@
+package esi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type CorporationHistoryOk struct {
+	CorporationID uint      `json:"corporation_id"`
+	RecordID      int       `json:"record_id"`
+	StartDate     time.Time `json:"start_date"`
+	IsDeleted     bool      `json:"is_deleted,omitempty"`
+}
+
+// HTTP Get /v2/characters/{character_id}/corporationhistory/
+// The most recent corporation is first
+func (s *service) CharacterCorporationHistory(ctx context.Context, characterID uint64) ([]*CorporationHistoryOk, error) {
+
+	var history = make([]*CorporationHistoryOk, 0)
+	var out = &Out{Data: &history}
+	path := fmt.Sprintf("/v2/characters/%d/corporationhistory/", characterID)
+
+	err := s.request(ctx, http.MethodGet, path, nil, http.StatusOK, time.Duration(-1), out, nil, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch character corporation history")
+	}
+
+	return history, nil
+
+}
+
+type AllianceHistoryOk struct {
+	// AllianceID is zero for the periods the corporation was not in an alliance
+	AllianceID uint      `json:"alliance_id,omitempty"`
+	RecordID   int       `json:"record_id"`
+	StartDate  time.Time `json:"start_date"`
+	IsDeleted  bool      `json:"is_deleted,omitempty"`
+}
+
+// HTTP Get /v3/corporations/{corporation_id}/alliancehistory/
+// The most recent alliance is first
+func (s *service) CorporationAllianceHistory(ctx context.Context, corporationID uint) ([]*AllianceHistoryOk, error) {
+
+	var history = make([]*AllianceHistoryOk, 0)
+	var out = &Out{Data: &history}
+	path := fmt.Sprintf("/v3/corporations/%d/alliancehistory/", corporationID)
+
+	err := s.request(ctx, http.MethodGet, path, nil, http.StatusOK, time.Duration(-1), out, nil, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch corporation alliance history")
+	}
+
+	return history, nil
+
+}
@@ -0,0 +1,353 @@
+// Package esitest provides an in-process fake ESI for tests. Point a client at it with esi.WithBaseURL.
+//
+// The fake serves the characters, wars, killmails, systems, groups and types it is given, and resolves
+// their names. Like ESI it sets ETag and Expires headers and answers conditional requests with 304 Not
+// Modified, pages /universe/groups/ with X-Pages and reports the error limit with X-Esi-Error-Limit-Remain.
+// Every error response lowers the remaining limit, and once it reaches zero every request is answered
+// with 420 until ResetErrorLimit is called. Fault injects error responses into the next requests for a path
+package esitest
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/internal/esi"
+)
+
+const (
+	// ErrorLimit is the number of error responses allowed before requests are answered with 420
+	ErrorLimit = 100
+
+	errorLimitReset = 60
+)
+
+// Server is a fake ESI. The zero value is not usable, use New
+type Server struct {
+	*httptest.Server
+
+	// TTL is how far in the future the Expires header of every response is set
+	TTL time.Duration
+	// PageSize is the number of ids on each page of /universe/groups/
+	PageSize int
+
+	mu         sync.Mutex
+	characters map[uint64]*esi.CharacterOk
+	wars       map[int]*krinder.ESIWar
+	killmails  map[int]*killmail
+	systems    map[uint]*esi.SystemOk
+	groups     map[uint]*krinder.ESIGroup
+	types      map[uint]*krinder.ESIEntity
+
+	faults     map[string][]int
+	requests   map[string]int
+	errorLimit int
+}
+
+type killmail struct {
+	hash     string
+	killmail *esi.KillmailOk
+}
+
+// New starts a fake ESI, which is closed when the test finishes
+func New(t testing.TB) *Server {
+
+	s := &Server{
+		TTL:        5 * time.Minute,
+		PageSize:   1000,
+		characters: make(map[uint64]*esi.CharacterOk),
+		wars:       make(map[int]*krinder.ESIWar),
+		killmails:  make(map[int]*killmail),
+		systems:    make(map[uint]*esi.SystemOk),
+		groups:     make(map[uint]*krinder.ESIGroup),
+		types:      make(map[uint]*krinder.ESIEntity),
+		faults:     make(map[string][]int),
+		requests:   make(map[string]int),
+		errorLimit: ErrorLimit,
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+
+	return s
+
+}
+
+func (s *Server) SetCharacter(character *esi.CharacterOk) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.characters[character.ID] = character
+}
+
+func (s *Server) SetWar(war *krinder.ESIWar) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.wars[war.ID] = war
+}
+
+func (s *Server) SetKillmail(hash string, km *esi.KillmailOk) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.killmails[km.KillmailID] = &killmail{hash: hash, killmail: km}
+}
+
+func (s *Server) SetSystem(system *esi.SystemOk) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.systems[system.ID] = system
+}
+
+func (s *Server) SetGroup(group *krinder.ESIGroup) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.groups[group.GroupID] = group
+}
+
+func (s *Server) SetType(entity *krinder.ESIEntity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.types[entity.ID] = entity
+}
+
+// Fault answers the next requests for uri, a path with its query such as /v1/wars/1/, with statuses
+// in order. Statuses queue behind those of earlier calls for the same uri
+func (s *Server) Fault(uri string, statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[uri] = append(s.faults[uri], statuses...)
+}
+
+// Requests returns the number of requests received for uri, a path with its query
+func (s *Server) Requests(uri string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[uri]
+}
+
+// ErrorLimitRemain returns the number of error responses left before requests are answered with 420
+func (s *Server) ErrorLimitRemain() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.errorLimit
+}
+
+// ResetErrorLimit restores the error limit, as ESI does once the window resets
+func (s *Server) ResetErrorLimit() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errorLimit = ErrorLimit
+}
+
+var (
+	characterPath = regexp.MustCompile(`^/v5/characters/(\d+)/$`)
+	warsPath      = regexp.MustCompile(`^/v1/wars/$`)
+	warPath       = regexp.MustCompile(`^/v1/wars/(\d+)/$`)
+	killmailPath  = regexp.MustCompile(`^/v1/killmails/(\d+)/([0-9a-zA-Z]+)/$`)
+	systemPath    = regexp.MustCompile(`^/v4/universe/systems/(\d+)/$`)
+	groupsPath    = regexp.MustCompile(`^/v1/universe/groups/$`)
+	groupPath     = regexp.MustCompile(`^/v1/universe/groups/(\d+)/$`)
+	typePath      = regexp.MustCompile(`^/v3/universe/types/(\d+)/$`)
+	namesPath     = regexp.MustCompile(`^/v3/universe/names/?$`)
+)
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	uri := r.URL.RequestURI()
+	s.requests[uri]++
+
+	if s.errorLimit <= 0 {
+		s.error(w, 420, "This software has exceeded the error limit for ESI. If you are a user, please contact the maintainer of this software. If you are a developer/maintainer, please make a greater effort in the future to receive valid responses.")
+		return
+	}
+
+	if faults := s.faults[uri]; len(faults) > 0 {
+		s.faults[uri] = faults[1:]
+		s.error(w, faults[0], http.StatusText(faults[0]))
+		return
+	}
+
+	if r.Method == http.MethodPost && namesPath.MatchString(r.URL.Path) {
+		s.names(w, r)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		s.error(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var data interface{}
+	var found bool
+	switch path := r.URL.Path; {
+	case characterPath.MatchString(path):
+		data, found = s.characters[uint64(id(characterPath, path))]
+	case warsPath.MatchString(path):
+		data, found = s.warIDs(r), true
+	case warPath.MatchString(path):
+		data, found = s.wars[id(warPath, path)]
+	case killmailPath.MatchString(path):
+		var km *killmail
+		km, found = s.killmails[id(killmailPath, path)]
+		if found && km.hash != killmailPath.FindStringSubmatch(path)[2] {
+			s.error(w, http.StatusUnprocessableEntity, "Invalid killmail_id and/or killmail_hash")
+			return
+		}
+		if found {
+			data = km.killmail
+		}
+	case systemPath.MatchString(path):
+		data, found = s.systems[uint(id(systemPath, path))]
+	case groupsPath.MatchString(path):
+		page, err := strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil || page < 1 {
+			page = 1
+		}
+		ids, pages := s.groupIDs(page)
+		w.Header().Set("X-Pages", strconv.Itoa(pages))
+		data, found = ids, page <= pages
+	case groupPath.MatchString(path):
+		data, found = s.groups[uint(id(groupPath, path))]
+	case typePath.MatchString(path):
+		data, found = s.types[uint(id(typePath, path))]
+	}
+
+	if !found {
+		s.error(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		s.error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256(body))
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Expires", time.Now().Add(s.TTL).UTC().Format(http.TimeFormat))
+	s.limitHeaders(w)
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+
+}
+
+// names resolves the ids in the body to the characters, systems and types that are set. Like ESI,
+// the whole request fails with 404 when one of the ids is unknown
+func (s *Server) names(w http.ResponseWriter, r *http.Request) {
+
+	var ids []int
+	if err := json.NewDecoder(r.Body).Decode(&ids); err != nil {
+		s.error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	names := make([]*esi.NamesOk, 0, len(ids))
+	for _, i := range ids {
+		if character, ok := s.characters[uint64(i)]; ok {
+			names = append(names, &esi.NamesOk{Category: "character", ID: i, Name: character.Name})
+		} else if system, ok := s.systems[uint(i)]; ok {
+			names = append(names, &esi.NamesOk{Category: "solar_system", ID: i, Name: system.Name})
+		} else if entity, ok := s.types[uint(i)]; ok {
+			names = append(names, &esi.NamesOk{Category: "inventory_type", ID: i, Name: entity.Name})
+		} else {
+			s.error(w, http.StatusNotFound, "Ensure all IDs are valid before resolving")
+			return
+		}
+	}
+
+	s.limitHeaders(w)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	_ = json.NewEncoder(w).Encode(names)
+
+}
+
+// warIDs returns up to 2000 war ids in descending order, lower than the max_war_id query parameter when it is set
+func (s *Server) warIDs(r *http.Request) []int {
+
+	max, err := strconv.Atoi(r.URL.Query().Get("max_war_id"))
+	if err != nil {
+		max = 0
+	}
+
+	ids := make([]int, 0, len(s.wars))
+	for warID := range s.wars {
+		if max == 0 || warID < max {
+			ids = append(ids, warID)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+
+	if len(ids) > 2000 {
+		ids = ids[:2000]
+	}
+
+	return ids
+
+}
+
+func (s *Server) groupIDs(page int) ([]uint, int) {
+
+	ids := make([]uint, 0, len(s.groups))
+	for groupID := range s.groups {
+		ids = append(ids, groupID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	pages := (len(ids) + s.PageSize - 1) / s.PageSize
+	if pages == 0 {
+		pages = 1
+	}
+
+	start, end := (page-1)*s.PageSize, page*s.PageSize
+	if start > len(ids) {
+		start = len(ids)
+	}
+	if end > len(ids) {
+		end = len(ids)
+	}
+
+	return ids[start:end], pages
+
+}
+
+func (s *Server) error(w http.ResponseWriter, status int, message string) {
+
+	if s.errorLimit > 0 {
+		s.errorLimit--
+	}
+	s.limitHeaders(w)
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+
+}
+
+func (s *Server) limitHeaders(w http.ResponseWriter) {
+	w.Header().Set("X-Esi-Error-Limit-Remain", strconv.Itoa(s.errorLimit))
+	w.Header().Set("X-Esi-Error-Limit-Reset", strconv.Itoa(errorLimitReset))
+}
+
+func id(pattern *regexp.Regexp, path string) int {
+	i, _ := strconv.Atoi(pattern.FindStringSubmatch(path)[1])
+	return i
+}
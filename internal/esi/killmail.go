@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/eveisesi/krinder"
 	"github.com/pkg/errors"
 )
 
@@ -46,7 +48,8 @@ type KillmailVictim struct {
 	Items         []*KillmailVictimItem `json:"items"`
 	ShipTypeID    uint                  `json:"ship_type_id"`
 
-	Character *CharacterOk `json:"character,omitempty"`
+	Character *CharacterOk         `json:"character,omitempty"`
+	Ship      *krinder.MongoEntity `json:"ship,omitempty"`
 }
 
 // HTTP Get /v1/killmails/{id}/{hash}/
@@ -64,3 +67,43 @@ func (s *service) KillmailByIDHash(ctx context.Context, id int64, hash string) (
 	return killmailOk, nil
 
 }
+
+type KillmailRefOk struct {
+	KillmailID   int    `json:"killmail_id"`
+	KillmailHash string `json:"killmail_hash"`
+}
+
+type KillmailsRecentOk struct {
+	Pages uint
+	Refs  []*KillmailRefOk
+}
+
+// HTTP Get /v1/characters/{character_id}/killmails/recent/
+// Requires an access token with the esi-killmails.read_killmails.v1 scope, see WithAccessToken.
+// The pages hold the killmails of the last 90 days, the most recent first
+func (s *service) CharacterKillmailsRecent(ctx context.Context, characterID uint64, page uint) (*KillmailsRecentOk, error) {
+
+	var refs = make([]*KillmailRefOk, 0)
+	var out = &Out{Data: &refs}
+
+	if page == 0 {
+		page = 1
+	}
+
+	path := fmt.Sprintf("/v1/characters/%d/killmails/recent/?page=%d", characterID, page)
+	err := s.request(ctx, http.MethodGet, path, nil, http.StatusOK, 0, out, nil, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch recent killmails")
+	}
+
+	var pages uint64 = 1
+	if xpages := out.Headers.Get("x-pages"); xpages != "" {
+		ppages, err := strconv.ParseUint(xpages, 10, 32)
+		if err == nil {
+			pages = ppages
+		}
+	}
+
+	return &KillmailsRecentOk{Pages: uint(pages), Refs: refs}, nil
+
+}
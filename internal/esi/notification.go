@@ -0,0 +1,38 @@
+package esi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type NotificationOk struct {
+	NotificationID int64     `json:"notification_id"`
+	SenderID       int64     `json:"sender_id"`
+	SenderType     string    `json:"sender_type"`
+	Text           string    `json:"text"`
+	Timestamp      time.Time `json:"timestamp"`
+	Type           string    `json:"type"`
+	IsRead         bool      `json:"is_read"`
+}
+
+// HTTP Get /v6/characters/{character_id}/notifications/
+// Requires an access token with the esi-characters.read_notifications.v1 scope, see WithAccessToken.
+// Text holds the details of the notification as YAML, its fields depend on Type
+func (s *service) CharacterNotifications(ctx context.Context, characterID uint64) ([]*NotificationOk, error) {
+
+	var notifications = make([]*NotificationOk, 0)
+	var out = &Out{Data: &notifications}
+	path := fmt.Sprintf("/v6/characters/%d/notifications/", characterID)
+
+	err := s.request(ctx, http.MethodGet, path, nil, http.StatusOK, 0, out, nil, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch character notifications")
+	}
+
+	return notifications, nil
+
+}
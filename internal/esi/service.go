@@ -1,25 +1,38 @@
 package esi
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/internal/metrics"
+	"github.com/eveisesi/krinder/pkg/errorcode"
 	"github.com/eveisesi/krinder/pkg/roundtripper"
 	"github.com/go-redis/redis/v8"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 )
 
 type API interface {
 	// Characters
 	Character(ctx context.Context, id uint64) (*CharacterOk, error)
+	CharacterCorporationHistory(ctx context.Context, characterID uint64) ([]*CorporationHistoryOk, error)
+	// Corporations
+	CorporationAllianceHistory(ctx context.Context, corporationID uint) ([]*AllianceHistoryOk, error)
 	// Killmails
 	KillmailByIDHash(ctx context.Context, id int64, hash string) (*KillmailOk, error)
+	// CharacterKillmailsRecent requires an access token, see WithAccessToken
+	CharacterKillmailsRecent(ctx context.Context, characterID uint64, page uint) (*KillmailsRecentOk, error)
+	// Notifications
+	// CharacterNotifications requires an access token, see WithAccessToken
+	CharacterNotifications(ctx context.Context, characterID uint64) ([]*NotificationOk, error)
 	// Search
 	Search(ctx context.Context, category, term string, strict bool) (*SearchOk, error)
 	// Universe
@@ -34,28 +47,73 @@ type API interface {
 	// Wars
 	War(ctx context.Context, id uint, reqFuncs ...RequestFunc) (*krinder.ESIWar, error)
 	Wars(ctx context.Context) ([]int, error)
+	WarsBefore(ctx context.Context, maxWarID int) ([]int, error)
 }
 
 type service struct {
-	url    string
-	client *http.Client
-	cache  *redis.Client
+	url       string
+	transport http.RoundTripper
+	client    *http.Client
+	backoff   time.Duration
+	cache     *redis.Client
+	logger    *logrus.Logger
 }
 
 const (
 	HeaderTimestampFormat = "Mon, 02 Jan 2006 15:04:05 MST"
+
+	// cachePrefix namespaces the responses cached in redis so that they can be flushed
+	// without disturbing other keys
+	cachePrefix = "esi:"
 )
 
 var _ API = new(service)
 
-func New(userAgent string, cache *redis.Client) *service {
-	return &service{
-		url: "https://esi.evetech.net",
-		client: &http.Client{
-			Transport: roundtripper.UserAgent(userAgent, http.DefaultTransport),
-		},
-		cache: cache,
+// Option customises the client returned by New
+type Option func(s *service)
+
+// WithBaseURL points the client at an ESI other than https://esi.evetech.net, such as a local fake
+func WithBaseURL(url string) Option {
+	return func(s *service) {
+		s.url = strings.TrimSuffix(url, "/")
+	}
+}
+
+// WithTransport sends requests through transport rather than http.DefaultTransport, such as a
+// roundtripper.Recorder replaying fixtures
+func WithTransport(transport http.RoundTripper) Option {
+	return func(s *service) {
+		s.transport = transport
+	}
+}
+
+// WithBackoff sets how long a request that failed with a server error waits before it is retried,
+// one second by default. Requests that were throttled wait ten times as long
+func WithBackoff(backoff time.Duration) Option {
+	return func(s *service) {
+		s.backoff = backoff
+	}
+}
+
+// New returns an ESI client. Responses are cached in cache, or not at all when cache is nil
+func New(logger *logrus.Logger, userAgent string, cache *redis.Client, options ...Option) *service {
+	s := &service{
+		logger:    logger,
+		url:       "https://esi.evetech.net",
+		transport: http.DefaultTransport,
+		backoff:   time.Second,
+		cache:     cache,
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	s.client = &http.Client{
+		Transport: metrics.InstrumentRoundTripper("esi", roundtripper.UserAgent(userAgent, s.transport)),
 	}
+
+	return s
 }
 
 // Execute a request to the ESI API using the provided Method, Path, and Body. If the response status != the exepected status
@@ -71,14 +129,45 @@ func (s *service) request(ctx context.Context, method, path string, body io.Read
 
 	url := fmt.Sprintf("%s%s", s.url, path)
 
+	entry := s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"service": "esi",
+		"method":  method,
+		"path":    path,
+	})
+
+	token := accessToken(ctx)
+	if token != "" || s.cache == nil {
+		cacheDuration = 0
+	}
+
 	if cacheDuration != 0 {
-		if err := s.getResponseCache(ctx, url, out); err == nil {
+		err := s.getResponseCache(ctx, url, out)
+		metrics.ObserveCache("esi", err == nil)
+		if err == nil {
+			entry.Debug("response served from cache")
 			return nil
 		}
 	}
+
+	// The body is buffered so that it can be sent again when the request is retried
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = io.ReadAll(body)
+		if err != nil {
+			return errors.Wrap(err, "failed to read request body")
+		}
+	}
+
+	start := time.Now()
 	var res = new(http.Response)
 	for i := 0; i < 3; i++ {
-		req, err := http.NewRequestWithContext(ctx, method, url, body)
+		var reqBody io.Reader
+		if payload != nil {
+			reqBody = bytes.NewReader(payload)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 		if err != nil {
 			return errors.Wrap(err, "failed to create request")
 		}
@@ -87,41 +176,57 @@ func (s *service) request(ctx context.Context, method, path string, body io.Read
 			mod(req)
 		}
 
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
 		res, err = s.client.Do(req)
 		if err != nil {
-			return errors.Wrap(err, "failed to execute request")
+			return upstreamError(err)
 		}
 
-		if res.StatusCode == http.StatusTooManyRequests {
-			fmt.Println("received 429, sleeping for 10 seconds")
-			time.Sleep(time.Second * 10)
-			continue
+		if (res.StatusCode < http.StatusInternalServerError && res.StatusCode != http.StatusTooManyRequests) || i == 2 {
+			break
 		}
 
-		if res.StatusCode < http.StatusInternalServerError {
-			break
+		wait := s.backoff
+		if res.StatusCode == http.StatusTooManyRequests {
+			wait = s.backoff * 10
 		}
+		entry.WithField("status", res.StatusCode).WithField("wait", wait).Warn("request failed, retrying")
 
-		time.Sleep(time.Second)
+		_ = res.Body.Close()
+		err = sleep(ctx, wait)
+		if err != nil {
+			return err
+		}
 	}
 
-	defer func(requestID string, body io.ReadCloser) {
+	defer func(body io.ReadCloser) {
 		err := body.Close()
 		if err != nil {
-			fmt.Printf("failed to close requst body for %s\n", requestID)
+			entry.WithError(err).Error("failed to close response body")
 		}
-	}(fmt.Sprintf("%s %s", method, path), res.Body)
+	}(res.Body)
+
+	entry.WithFields(logrus.Fields{
+		"status":   res.StatusCode,
+		"duration": time.Since(start),
+	}).Debug("request complete")
 
 	out.Status = res.StatusCode
 	out.Headers = res.Header
 
 	if res.StatusCode > 399 || (res.StatusCode != expected && res.StatusCode != http.StatusNotModified) {
+		code := errorcode.FromHTTPStatus(res.StatusCode)
+		message := fmt.Sprintf("esi responded with %d %s", res.StatusCode, http.StatusText(res.StatusCode))
+
 		data, err := io.ReadAll(res.Body)
 		if err != nil {
-			return errors.Wrapf(err, "expected status %d, got %d: unable to parse request body", expected, res.StatusCode)
+			return errorcode.Wrap(errors.Wrapf(err, "expected status %d, got %d: unable to parse request body", expected, res.StatusCode), code, message)
 		}
 
-		return errors.Errorf("expected status %d, got %d: %s", expected, res.StatusCode, string(data))
+		return errorcode.Wrap(errors.Errorf("expected status %d, got %d: %s", expected, res.StatusCode, string(data)), code, message)
 	}
 
 	if out.Status == http.StatusOK {
@@ -153,6 +258,16 @@ func (s *service) request(ctx context.Context, method, path string, body io.Read
 
 }
 
+// sleep pauses for d, returning early with an error if ctx is cancelled first
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "request cancelled while waiting to retry")
+	case <-time.After(d):
+		return nil
+	}
+}
+
 // if duration == -2, results will be cached permenantly with no duration,
 // if duration == -1, results will be cached temporarily according to the Expires header on the result if the result response code == expected
 // if duration == 0, results will not be cached
@@ -175,7 +290,7 @@ func (s *service) setResponseCache(ctx context.Context, url string, duration tim
 		return errors.Wrap(err, "failed to encode payload to json")
 	}
 
-	_, err = s.cache.Set(ctx, s.hashString(url), string(payload), d).Result()
+	_, err = s.cache.Set(ctx, cachePrefix+s.hashString(url), string(payload), d).Result()
 
 	return errors.Wrap(err, "failed to cache response")
 
@@ -187,7 +302,7 @@ func (s *service) hashString(i string) string {
 
 func (s *service) getResponseCache(ctx context.Context, url string, out *Out) error {
 
-	b, err := s.cache.Get(ctx, s.hashString(url)).Bytes()
+	b, err := s.cache.Get(ctx, cachePrefix+s.hashString(url)).Bytes()
 	if err != nil {
 		return err
 	}
@@ -197,3 +312,48 @@ func (s *service) getResponseCache(ctx context.Context, url string, out *Out) er
 	return err
 
 }
+
+// FlushCache deletes every cached response, returning the number of responses deleted
+func (s *service) FlushCache(ctx context.Context) (int64, error) {
+
+	var deleted int64
+	iter := s.cache.Scan(ctx, 0, cachePrefix+"*", 1000).Iterator()
+	keys := make([]string, 0, 1000)
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) < 1000 {
+			continue
+		}
+
+		n, err := s.cache.Del(ctx, keys...).Result()
+		if err != nil {
+			return deleted, errors.Wrap(err, "failed to delete cached responses")
+		}
+		deleted += n
+		keys = keys[:0]
+	}
+	if err := iter.Err(); err != nil {
+		return deleted, errors.Wrap(err, "failed to scan cached responses")
+	}
+
+	if len(keys) > 0 {
+		n, err := s.cache.Del(ctx, keys...).Result()
+		if err != nil {
+			return deleted, errors.Wrap(err, "failed to delete cached responses")
+		}
+		deleted += n
+	}
+
+	return deleted, nil
+
+}
+
+// upstreamError codes an error returned while executing a request to esi, which
+// either timed out or could not reach it
+func upstreamError(err error) error {
+	code := errorcode.UpstreamUnavailable
+	if errorcode.Code(err) == errorcode.Timeout {
+		code = errorcode.Timeout
+	}
+	return errorcode.Wrap(err, code, "esi could not be reached")
+}
@@ -0,0 +1,166 @@
+package esi_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/internal/esi"
+	"github.com/eveisesi/krinder/internal/esi/esitest"
+	"github.com/eveisesi/krinder/pkg/errorcode"
+	"github.com/sirupsen/logrus"
+)
+
+func client(t *testing.T) (esi.API, *esitest.Server) {
+	t.Helper()
+
+	server := esitest.New(t)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	return esi.New(logger, "krinder-test", nil, esi.WithBaseURL(server.URL), esi.WithBackoff(time.Millisecond)), server
+}
+
+func TestRequestRetries(t *testing.T) {
+
+	tests := map[string]struct {
+		faults   []int
+		code     errorcode.ErrorCode
+		requests int
+	}{
+		"server errors are retried":    {faults: []int{http.StatusBadGateway, http.StatusServiceUnavailable}, requests: 3},
+		"throttled requests retried":   {faults: []int{http.StatusTooManyRequests}, requests: 2},
+		"retries are exhausted":        {faults: []int{500, 500, 500}, code: errorcode.UpstreamUnavailable, requests: 3},
+		"error limited is not retried": {faults: []int{420}, code: errorcode.UpstreamThrottled, requests: 1},
+		"timeouts keep their code":     {faults: []int{504, 504, 504}, code: errorcode.Timeout, requests: 3},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+
+			api, server := client(t)
+			server.SetCharacter(&esi.CharacterOk{ID: 90000001, Name: "Hunter"})
+			server.Fault("/v5/characters/90000001/", test.faults...)
+
+			character, err := api.Character(context.Background(), 90000001)
+			if code := errorcode.Code(err); err != nil && code != test.code || err == nil && test.code != "" {
+				t.Fatalf("expected code %q, got %v", test.code, err)
+			}
+			if err == nil && character.Name != "Hunter" {
+				t.Errorf("expected the character after retrying, got %+v", character)
+			}
+			if got := server.Requests("/v5/characters/90000001/"); got != test.requests {
+				t.Errorf("expected %d requests, got %d", test.requests, got)
+			}
+
+		})
+	}
+
+}
+
+func TestRequestRetriesResendBody(t *testing.T) {
+
+	api, server := client(t)
+	server.SetCharacter(&esi.CharacterOk{ID: 90000001, Name: "Hunter"})
+	server.SetSystem(&esi.SystemOk{ID: 30000142, Name: "Jita"})
+	server.Fault("/v3/universe/names", http.StatusBadGateway)
+
+	names, err := api.Names(context.Background(), []int{90000001, 30000142})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(names) != 2 || names[0].Name != "Hunter" || names[1].Category != "solar_system" {
+		t.Errorf("unexpected names %+v", names)
+	}
+
+}
+
+func TestConditionalRequests(t *testing.T) {
+
+	api, server := client(t)
+	server.SetWar(&krinder.ESIWar{ID: 1, Declared: time.Now().Add(-time.Hour).UTC().Truncate(time.Second)})
+	server.SetType(&krinder.ESIEntity{ID: 587, Name: "Rifter", Published: true})
+
+	war, err := api.War(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if war.IntegrityHash == "" || !war.ExpiresAt.Valid {
+		t.Fatalf("expected the etag and expiry of the war to be kept, got %q and %v", war.IntegrityHash, war.ExpiresAt)
+	}
+
+	war, err = api.War(context.Background(), 1, esi.AddIfNoneMatchHeader(war.IntegrityHash))
+	if err != nil || war != nil {
+		t.Errorf("expected an unmodified war to be nil, got %+v and %v", war, err)
+	}
+
+	entity, err := api.Type(context.Background(), 587)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if entity.Type.Name != "Rifter" || entity.Etag == "" || time.Until(entity.Expires) < time.Minute {
+		t.Errorf("expected the type with its etag and expiry, got %+v", entity)
+	}
+
+	unmodified, err := api.Type(context.Background(), 587, esi.AddIfNoneMatchHeader(entity.Etag))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if unmodified.Etag != entity.Etag || unmodified.Type.Name != "" {
+		t.Errorf("expected an unmodified type to only carry its etag, got %+v", unmodified)
+	}
+
+}
+
+func TestGroupsPagination(t *testing.T) {
+
+	api, server := client(t)
+	server.PageSize = 2
+	for _, id := range []uint{25, 26, 27} {
+		server.SetGroup(&krinder.ESIGroup{GroupID: id})
+	}
+
+	first, err := api.Groups(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	second, err := api.Groups(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if first.Pages != 2 || len(first.IDs) != 2 || len(second.IDs) != 1 || second.IDs[0] != 27 {
+		t.Errorf("unexpected pages %+v and %+v", first, second)
+	}
+
+}
+
+func TestErrorLimit(t *testing.T) {
+
+	api, server := client(t)
+	server.SetCharacter(&esi.CharacterOk{ID: 90000001, Name: "Hunter"})
+
+	for i := 0; i < esitest.ErrorLimit; i++ {
+		_, err := api.Character(context.Background(), 1)
+		if errorcode.Code(err) != errorcode.NotFound {
+			t.Fatalf("expected an unknown character to not be found, got %v", err)
+		}
+	}
+
+	_, err := api.Character(context.Background(), 90000001)
+	if errorcode.Code(err) != errorcode.UpstreamThrottled {
+		t.Fatalf("expected requests to be throttled once the error limit is reached, got %v", err)
+	}
+
+	server.ResetErrorLimit()
+
+	_, err = api.Character(context.Background(), 90000001)
+	if err != nil {
+		t.Errorf("expected requests to succeed once the error limit resets, got %v", err)
+	}
+
+}
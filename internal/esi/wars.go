@@ -22,6 +22,20 @@ func (s *service) Wars(ctx context.Context) ([]int, error) {
 
 }
 
+// WarsBefore returns up to 2000 war ids lower than maxWarID, in descending order.
+// It is used to page backwards through wars older than those returned by Wars
+func (s *service) WarsBefore(ctx context.Context, maxWarID int) ([]int, error) {
+
+	var warIDs = make([]int, 0)
+	var out = &Out{Data: &warIDs}
+
+	path := fmt.Sprintf("/v1/wars/?max_war_id=%d", maxWarID)
+	err := s.request(ctx, http.MethodGet, path, nil, http.StatusOK, time.Duration(time.Hour), out, nil, nil)
+
+	return warIDs, errors.Wrapf(err, "failed to fetch wars before %d", maxWarID)
+
+}
+
 func (s *service) War(ctx context.Context, id uint, reqFuncs ...RequestFunc) (*krinder.ESIWar, error) {
 
 	var war = new(krinder.ESIWar)
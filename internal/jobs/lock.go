@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+)
+
+// Locker grants expiring, exclusive leases on keys, so that a job only runs on one replica at a time
+type Locker interface {
+	// Acquire takes the lease on key for ttl, returning false if it is held by someone else
+	Acquire(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Refresh extends a lease that is already held, returning false if it has been lost
+	Refresh(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, key string) error
+}
+
+const lockPrefix = "jobs:lock:"
+
+var (
+	refreshScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// RedisLocker implements Locker with keys in redis holding the identity of the owning process
+type RedisLocker struct {
+	client *redis.Client
+	owner  string
+}
+
+var _ Locker = new(RedisLocker)
+
+func NewRedisLocker(client *redis.Client) *RedisLocker {
+	host, _ := os.Hostname()
+
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+
+	return &RedisLocker{
+		client: client,
+		owner:  fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b)),
+	}
+}
+
+func (l *RedisLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	ok, err := l.client.SetNX(ctx, lockPrefix+key, l.owner, ttl).Result()
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to acquire lock %s", key)
+	}
+	return ok, nil
+}
+
+func (l *RedisLocker) Refresh(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	n, err := refreshScript.Run(ctx, l.client, []string{lockPrefix + key}, l.owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to refresh lock %s", key)
+	}
+	return n == 1, nil
+}
+
+func (l *RedisLocker) Release(ctx context.Context, key string) error {
+	err := releaseScript.Run(ctx, l.client, []string{lockPrefix + key}, l.owner).Err()
+	return errors.Wrapf(err, "failed to release lock %s", key)
+}
@@ -0,0 +1,332 @@
+package jobs
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/pkg/correlation"
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// Job is a unit of background work run by the Scheduler
+type Job struct {
+	Name string
+	// Source names the upstream the job reads from. Jobs sharing a source never run at
+	// the same time, on this or any other replica. It defaults to the name of the job
+	Source   string
+	Schedule cron.Schedule
+	// Jitter is the upper bound of a random delay added to every scheduled run, so that
+	// replicas and jobs on the same schedule do not wake up at the same instant
+	Jitter time.Duration
+	Run    func(ctx context.Context) error
+	// Succeeded, when set, is called whenever the job is seen to have succeeded on this or
+	// another replica, so that replicas which did not run it know its data is complete
+	Succeeded func()
+}
+
+// Scheduler runs jobs on their schedules. Run times are persisted, so a restart does not
+// re-run a job that has already run, and a lock per source ensures a single replica runs it
+type Scheduler struct {
+	logger *logrus.Logger
+	repo   krinder.JobRepository
+	locker Locker
+
+	jobs []*Job
+
+	// poll is how long to wait before trying again when a job cannot be run
+	poll    time.Duration
+	lockTTL time.Duration
+}
+
+func NewScheduler(logger *logrus.Logger, repo krinder.JobRepository, locker Locker) *Scheduler {
+	return &Scheduler{
+		logger:  logger,
+		repo:    repo,
+		locker:  locker,
+		poll:    time.Minute,
+		lockTTL: time.Minute,
+	}
+}
+
+// Add registers a job. Jobs must be added before Run is called
+func (s *Scheduler) Add(job Job) error {
+	if job.Name == "" {
+		return errors.New("job name is required")
+	}
+	if job.Schedule == nil || job.Run == nil {
+		return errors.Errorf("job %s requires a schedule and a run func", job.Name)
+	}
+	if job.Source == "" {
+		job.Source = job.Name
+	}
+
+	s.jobs = append(s.jobs, &job)
+	return nil
+}
+
+// Run schedules the jobs until ctx is cancelled, then waits for running jobs to return.
+// Jobs are run with work, so they are only cancelled once work is
+func (s *Scheduler) Run(ctx, work context.Context) {
+
+	var wg sync.WaitGroup
+	for _, job := range s.jobs {
+		wg.Add(1)
+		go func(job *Job) {
+			defer wg.Done()
+			s.loop(ctx, work, job)
+		}(job)
+	}
+
+	wg.Wait()
+
+}
+
+func (s *Scheduler) loop(ctx, work context.Context, job *Job) {
+
+	entry := s.logger.WithFields(logrus.Fields{
+		"service": "scheduler",
+		"job":     job.Name,
+	})
+
+	// notBefore guards against running a job again straight away when its state could not be saved
+	var notBefore time.Time
+	for {
+		state, err := s.state(ctx, job)
+		if err != nil {
+			entry.WithError(err).Error("failed to fetch job state")
+		}
+
+		wait := s.poll
+		if err == nil {
+			if !state.LastSuccess.IsZero() && job.Succeeded != nil {
+				job.Succeeded()
+			}
+
+			wait = time.Until(s.next(job, state))
+			if until := time.Until(notBefore); until > wait {
+				wait = until
+			}
+		}
+
+		if wait > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+			continue
+		}
+
+		ok, err := s.locker.Acquire(ctx, job.Source, s.lockTTL)
+		if err != nil {
+			entry.WithError(err).Error("failed to acquire job lock")
+		}
+		if !ok {
+			// Another replica, or another job on the same source, is running
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(s.poll):
+			}
+			continue
+		}
+
+		notBefore = s.run(ctx, work, job, entry)
+	}
+
+}
+
+// state returns the persisted state of job, which is empty if it has never run
+func (s *Scheduler) state(ctx context.Context, job *Job) (*krinder.Job, error) {
+	state, err := s.repo.Job(ctx, job.Name)
+	if err != nil && !errors.Is(err, krinder.ErrNotFound) {
+		return nil, err
+	}
+	state.Name = job.Name
+	return state, nil
+}
+
+// LastSuccess returns when the job named name last succeeded on any replica, or the zero time if it never has
+func (s *Scheduler) LastSuccess(ctx context.Context, name string) (time.Time, error) {
+	state, err := s.repo.Job(ctx, name)
+	if err != nil && !errors.Is(err, krinder.ErrNotFound) {
+		return time.Time{}, errors.Wrapf(err, "failed to fetch state of job %s", name)
+	}
+	return state.LastSuccess, nil
+}
+
+// next returns when job is due. The persisted next run is capped by the current schedule,
+// so shortening a schedule takes effect without waiting out the previous one
+func (s *Scheduler) next(job *Job, state *krinder.Job) time.Time {
+	if state.UpdatedAt.IsZero() {
+		return state.NextRun
+	}
+
+	limit := job.Schedule.Next(state.UpdatedAt).Add(job.Jitter)
+	if state.NextRun.After(limit) {
+		return limit
+	}
+	return state.NextRun
+}
+
+// run runs job while holding the lock on its source, and persists the outcome.
+// It returns when the job is next due
+func (s *Scheduler) run(ctx, work context.Context, job *Job, entry *logrus.Entry) time.Time {
+
+	defer func() {
+		releaseCtx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		if err := s.locker.Release(releaseCtx, job.Source); err != nil {
+			entry.WithError(err).Error("failed to release job lock")
+		}
+	}()
+
+	// Another replica may have run the job between reading its state and acquiring the lock
+	state, err := s.state(ctx, job)
+	if err != nil {
+		entry.WithError(err).Error("failed to fetch job state")
+		return time.Now().Add(s.poll)
+	}
+	if next := s.next(job, state); time.Until(next) > 0 {
+		return next
+	}
+
+	next, _ := s.execute(work, job, state, entry)
+	return next
+
+}
+
+// RunOnce runs the job named name straight away, outside of its schedule, and persists the outcome
+// as a scheduled run would. It holds the same lock as scheduled runs, so it fails rather than
+// overlap with a run on this or another replica
+func (s *Scheduler) RunOnce(ctx context.Context, name string) error {
+
+	var job *Job
+	for _, j := range s.jobs {
+		if j.Name == name {
+			job = j
+			break
+		}
+	}
+	if job == nil {
+		return errors.Errorf("unknown job %s", name)
+	}
+
+	entry := s.logger.WithFields(logrus.Fields{
+		"service": "scheduler",
+		"job":     job.Name,
+	})
+
+	ok, err := s.locker.Acquire(ctx, job.Source, s.lockTTL)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.Errorf("job %s, or another job reading from %s, is already running", job.Name, job.Source)
+	}
+	defer func() {
+		releaseCtx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		if err := s.locker.Release(releaseCtx, job.Source); err != nil {
+			entry.WithError(err).Error("failed to release job lock")
+		}
+	}()
+
+	state, err := s.state(ctx, job)
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch job state")
+	}
+
+	_, err = s.execute(ctx, job, state, entry)
+	return err
+
+}
+
+// execute runs job, which the caller holds the lock for, and persists its outcome onto state.
+// It returns when the job is next due along with the error of the run
+func (s *Scheduler) execute(work context.Context, job *Job, state *krinder.Job, entry *logrus.Entry) (time.Time, error) {
+
+	// Each run gets its own correlation id so that its log lines can be told apart
+	runCtx, cancel := context.WithCancel(correlation.WithID(work, correlation.New()))
+	entry = entry.WithContext(runCtx)
+
+	refreshed := make(chan struct{})
+	go func() {
+		defer close(refreshed)
+		s.refresh(runCtx, cancel, job, entry)
+	}()
+
+	entry.Info("running job")
+	start := time.Now()
+	err := job.Run(runCtx)
+	cancel()
+	<-refreshed
+
+	state.LastRun = start
+	state.LastError = ""
+	if err != nil {
+		state.LastError = err.Error()
+	} else {
+		state.LastSuccess = time.Now()
+	}
+
+	// A job cut short by shutdown is left due, so that it runs again after a restart
+	if err == nil || work.Err() == nil {
+		state.NextRun = job.Schedule.Next(time.Now()).Add(jitter(job.Jitter))
+	}
+
+	saveCtx, cancelSave := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelSave()
+	if err := s.repo.SaveJob(saveCtx, state); err != nil {
+		entry.WithError(err).Error("failed to save job state")
+	}
+
+	entry.WithFields(logrus.Fields{
+		"duration": time.Since(start).Truncate(time.Second),
+		"nextRun":  state.NextRun,
+	}).Info("job finished")
+
+	return state.NextRun, err
+
+}
+
+// refresh extends the lock on the source of job until ctx is cancelled. If the lock is
+// lost the job is cancelled, as another replica may now run it
+func (s *Scheduler) refresh(ctx context.Context, cancel context.CancelFunc, job *Job, entry *logrus.Entry) {
+
+	ticker := time.NewTicker(s.lockTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ok, err := s.locker.Refresh(ctx, job.Source, s.lockTTL)
+		if err != nil {
+			// A single failure is tolerated as the lock outlives a couple of refreshes
+			entry.WithError(err).Error("failed to refresh job lock")
+			continue
+		}
+		if !ok {
+			entry.Error("job lock lost, cancelling job")
+			cancel()
+			return
+		}
+	}
+
+}
+
+func jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
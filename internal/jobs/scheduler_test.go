@@ -0,0 +1,208 @@
+package jobs
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/internal/store/memory"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// locker is an in process Locker shared by the schedulers of a test, standing in for redis
+type locker struct {
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+func (l *locker) Acquire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held[key] {
+		return false, nil
+	}
+	l.held[key] = true
+	return true, nil
+}
+
+func (l *locker) Refresh(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.held[key], nil
+}
+
+func (l *locker) Release(ctx context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.held, key)
+	return nil
+}
+
+func newTestScheduler(repo krinder.JobRepository, l Locker) *Scheduler {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	s := NewScheduler(logger, repo, l)
+	s.poll = time.Millisecond * 10
+	s.lockTTL = time.Millisecond * 30
+	return s
+}
+
+func TestSchedulerPersistsRuns(t *testing.T) {
+
+	repo := memory.NewJobRepository()
+	l := &locker{held: make(map[string]bool)}
+
+	var runs int32
+	job := Job{
+		Name:     "wars",
+		Schedule: cron.Every(time.Hour),
+		Run: func(ctx context.Context) error {
+			atomic.AddInt32(&runs, 1)
+			return nil
+		},
+	}
+
+	// Two replicas share the repository and the lock, but only one may run the job
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		s := newTestScheduler(repo, l)
+		if err := s.Add(job); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Run(ctx, context.Background())
+		}()
+	}
+
+	time.Sleep(time.Millisecond * 100)
+	cancel()
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Fatalf("expected the job to run once, ran %d times", n)
+	}
+
+	state, err := repo.Job(context.Background(), "wars")
+	if err != nil {
+		t.Fatalf("failed to fetch job state: %s", err)
+	}
+	if state.LastSuccess.IsZero() || time.Until(state.NextRun) < time.Minute*59 {
+		t.Errorf("unexpected job state %+v", state)
+	}
+
+	// A restart does not run the job again before it is due
+	ctx, cancel = context.WithCancel(context.Background())
+	s := newTestScheduler(repo, l)
+	_ = s.Add(job)
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		cancel()
+	}()
+	s.Run(ctx, context.Background())
+
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Errorf("expected the job not to run again after a restart, ran %d times", n)
+	}
+
+}
+
+func TestSchedulerSerializesSource(t *testing.T) {
+
+	repo := memory.NewJobRepository()
+	l := &locker{held: make(map[string]bool)}
+	s := newTestScheduler(repo, l)
+
+	var running, overlapped int32
+	run := func(ctx context.Context) error {
+		if atomic.AddInt32(&running, 1) > 1 {
+			atomic.StoreInt32(&overlapped, 1)
+		}
+		time.Sleep(time.Millisecond * 30)
+		atomic.AddInt32(&running, -1)
+		return nil
+	}
+
+	for _, name := range []string{"wars", "universe"} {
+		err := s.Add(Job{Name: name, Source: "esi", Schedule: cron.Every(time.Hour), Run: run})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(time.Millisecond * 200)
+		cancel()
+	}()
+	s.Run(ctx, context.Background())
+
+	if atomic.LoadInt32(&overlapped) == 1 {
+		t.Error("expected jobs on the same source not to overlap")
+	}
+
+	for _, name := range []string{"wars", "universe"} {
+		state, err := repo.Job(context.Background(), name)
+		if err != nil || state.LastSuccess.IsZero() {
+			t.Errorf("expected %s to have run, got %+v, %v", name, state, err)
+		}
+	}
+
+}
+
+func TestRunOnce(t *testing.T) {
+
+	repo := memory.NewJobRepository()
+	l := &locker{held: make(map[string]bool)}
+
+	var runs int32
+	s := newTestScheduler(repo, l)
+	err := s.Add(Job{
+		Name:     "wars",
+		Source:   "esi:wars",
+		Schedule: cron.Every(time.Hour),
+		Run: func(ctx context.Context) error {
+			atomic.AddInt32(&runs, 1)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// A scheduled run on another replica holds the lock
+	_, _ = l.Acquire(context.Background(), "esi:wars", time.Minute)
+	if err := s.RunOnce(context.Background(), "wars"); err == nil {
+		t.Error("expected an error while the job is running elsewhere")
+	}
+	_ = l.Release(context.Background(), "esi:wars")
+
+	if err := s.RunOnce(context.Background(), "wars"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if runs != 1 {
+		t.Errorf("expected 1 run, got %d", runs)
+	}
+	if l.held["esi:wars"] {
+		t.Error("expected the lock to be released")
+	}
+
+	state, err := repo.Job(context.Background(), "wars")
+	if err != nil || state.LastSuccess.IsZero() {
+		t.Errorf("expected the run to be persisted, got %+v and %v", state, err)
+	}
+
+	if err := s.RunOnce(context.Background(), "universe"); err == nil {
+		t.Error("expected an error for an unknown job")
+	}
+
+}
@@ -0,0 +1,163 @@
+// Package jobs tracks the progress of the long running background syncs
+package jobs
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type State string
+
+const (
+	StatePending   State = "pending"
+	StateRunning   State = "running"
+	StateSucceeded State = "succeeded"
+	StateFailed    State = "failed"
+)
+
+// ErrRunning is returned by Tracker.Start when the job is already running
+var ErrRunning = errors.New("job is already running")
+
+// Status is a snapshot of the state of a job
+type Status struct {
+	Name  string `json:"name"`
+	State State  `json:"state"`
+	// Done and Total count the units of work of the current, or most recent, run
+	Done  int `json:"done"`
+	Total int `json:"total"`
+	// Complete is true once the data maintained by the job is complete enough to be relied on,
+	// either because a run has succeeded or because the data was already present
+	Complete    bool      `json:"complete"`
+	StartedAt   time.Time `json:"startedAt,omitempty"`
+	FinishedAt  time.Time `json:"finishedAt,omitempty"`
+	LastSuccess time.Time `json:"lastSuccess,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Percent returns how much of the current run is done, from 0 to 100
+func (s Status) Percent() int {
+	if s.Total <= 0 {
+		if s.State == StateSucceeded {
+			return 100
+		}
+		return 0
+	}
+	p := s.Done * 100 / s.Total
+	if p > 100 {
+		p = 100
+	}
+	return p
+}
+
+// Warning returns a message describing how incomplete the data maintained by the job is,
+// or an empty string once the data is complete. subject names the data, consequence
+// describes how results depending on it are affected
+func (s Status) Warning(subject, consequence string) string {
+	if s.Complete {
+		return ""
+	}
+	return fmt.Sprintf("%s %d%% synced, %s", subject, s.Percent(), consequence)
+}
+
+// String describes the status in a single line
+func (s Status) String() string {
+	var line string
+	switch s.State {
+	case StateRunning:
+		line = fmt.Sprintf("%s: running, %d%% (%d/%d), started %s", s.Name, s.Percent(), s.Done, s.Total, s.StartedAt.UTC().Format(time.RFC3339))
+	case StateSucceeded:
+		line = fmt.Sprintf("%s: succeeded %s", s.Name, s.FinishedAt.UTC().Format(time.RFC3339))
+	case StateFailed:
+		line = fmt.Sprintf("%s: failed %s: %s", s.Name, s.FinishedAt.UTC().Format(time.RFC3339), s.Error)
+	default:
+		line = fmt.Sprintf("%s: %s", s.Name, s.State)
+	}
+
+	if !s.Complete {
+		line += ", data incomplete"
+	}
+
+	return line
+}
+
+// Tracker records the progress of a job. It is safe for concurrent use
+type Tracker struct {
+	mu     sync.RWMutex
+	status Status
+}
+
+func NewTracker(name string) *Tracker {
+	return &Tracker{
+		status: Status{
+			Name:  name,
+			State: StatePending,
+		},
+	}
+}
+
+// Start marks the job as running, returning ErrRunning if it already is
+func (t *Tracker) Start() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.status.State == StateRunning {
+		return ErrRunning
+	}
+
+	t.status.State = StateRunning
+	t.status.Done = 0
+	t.status.Total = 0
+	t.status.StartedAt = time.Now()
+	t.status.FinishedAt = time.Time{}
+	t.status.Error = ""
+
+	return nil
+}
+
+// AddTotal increases the units of work of the current run
+func (t *Tracker) AddTotal(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.status.Total += n
+}
+
+// Advance records n units of work as done
+func (t *Tracker) Advance(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.status.Done += n
+}
+
+// MarkComplete records that the data maintained by the job can be relied on
+// even though the current run has not finished
+func (t *Tracker) MarkComplete() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.status.Complete = true
+}
+
+// Finish marks the current run as succeeded when err is nil and failed otherwise
+func (t *Tracker) Finish(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.status.FinishedAt = time.Now()
+	if err != nil {
+		t.status.State = StateFailed
+		t.status.Error = err.Error()
+		return
+	}
+
+	t.status.State = StateSucceeded
+	t.status.Complete = true
+	t.status.LastSuccess = t.status.FinishedAt
+}
+
+func (t *Tracker) Status() Status {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.status
+}
@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTracker(t *testing.T) {
+
+	tracker := NewTracker("wars")
+
+	if status := tracker.Status(); status.State != StatePending || status.Complete {
+		t.Fatalf("unexpected initial status %+v", status)
+	}
+
+	if err := tracker.Start(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := tracker.Start(); !errors.Is(err, ErrRunning) {
+		t.Fatalf("expected ErrRunning, got %v", err)
+	}
+
+	tracker.AddTotal(200)
+	tracker.Advance(86)
+
+	status := tracker.Status()
+	if status.Percent() != 43 {
+		t.Errorf("expected 43 percent, got %d", status.Percent())
+	}
+	if warning := status.Warning("war data", "results may include war kills"); warning != "war data 43% synced, results may include war kills" {
+		t.Errorf("unexpected warning %q", warning)
+	}
+
+	tracker.Finish(errors.New("boom"))
+	status = tracker.Status()
+	if status.State != StateFailed || status.Error != "boom" || status.Complete {
+		t.Errorf("unexpected status after failure %+v", status)
+	}
+
+	_ = tracker.Start()
+	tracker.Finish(nil)
+	status = tracker.Status()
+	if status.State != StateSucceeded || !status.Complete || status.LastSuccess.IsZero() {
+		t.Errorf("unexpected status after success %+v", status)
+	}
+	if status.Warning("war data", "results may include war kills") != "" {
+		t.Errorf("expected no warning once complete")
+	}
+
+}
@@ -0,0 +1,190 @@
+package killright
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/eveisesi/krinder/internal/esi"
+)
+
+// Row is the evidence of a kill right: the character it involves and the killmail it comes from
+type Row struct {
+	CharacterID   uint64    `json:"characterID"`
+	CharacterName string    `json:"characterName"`
+	KillmailID    int       `json:"killmailID"`
+	Time          time.Time `json:"time"`
+	SystemID      int       `json:"systemID"`
+	System        string    `json:"system"`
+	Security      float64   `json:"security"`
+	ShipTypeID    uint      `json:"shipTypeID"`
+	Ship          string    `json:"ship"`
+	URL           string    `json:"url"`
+}
+
+func newRow(character *esi.CharacterOk, killmail *esi.KillmailOk) *Row {
+
+	row := &Row{
+		CharacterID:   character.ID,
+		CharacterName: character.Name,
+		KillmailID:    killmail.KillmailID,
+		Time:          killmail.KillmailTime,
+		SystemID:      killmail.SolarSystemID,
+		ShipTypeID:    killmail.Victim.ShipTypeID,
+		URL:           fmt.Sprintf("https://zkillboard.com/kill/%d/", killmail.KillmailID),
+	}
+	if killmail.SolarSystem != nil {
+		row.System = killmail.SolarSystem.Name
+		row.Security = killmail.SolarSystem.SecurityStatus
+	}
+	if killmail.Victim.Ship != nil {
+		row.Ship = killmail.Victim.Ship.Name
+	}
+
+	return row
+
+}
+
+// Rows returns a row per victim that may hold a kill right on the attacker, with the killmail that gave it to them
+func (r *AttackerReport) Rows() []*Row {
+
+	rows := make([]*Row, 0, len(r.Killmails))
+	for _, killmail := range r.Killmails {
+		rows = append(rows, newRow(killmail.Victim.Character, killmail))
+	}
+
+	return rows
+
+}
+
+// Rows returns a row per character the victim may hold a kill right on and killmail they were seen on
+func (r *VictimReport) Rows() []*Row {
+
+	rows := make([]*Row, 0, len(r.Aggressors))
+	for _, aggressor := range r.Aggressors {
+		for _, killmail := range aggressor.Killmails {
+			rows = append(rows, newRow(aggressor.Character, killmail))
+		}
+	}
+
+	return rows
+
+}
+
+// Rows returns a row per qualifying loss of the searched ship type, or of every ship in the group
+// when no type was searched
+func (r *ShipReport) Rows() []*Row {
+
+	rows := make([]*Row, 0, r.Killmails)
+	for _, ship := range r.Ships {
+		if r.ShipTypeID != 0 && uint64(ship.Entity.ID) != r.ShipTypeID {
+			continue
+		}
+		for _, killmail := range ship.Killmails {
+			rows = append(rows, newRow(killmail.Victim.Character, killmail))
+		}
+	}
+
+	return rows
+
+}
+
+type exporter struct {
+	extension   string
+	contentType string
+	write       func(w io.Writer, rows []*Row) error
+}
+
+// exporters holds the formats that are written to a file attached to the reply, rather than inlined
+var exporters = map[Format]exporter{
+	FormatCSV:     {"csv", "text/csv", writeCSV},
+	FormatJSON:    {"json", "application/json", writeJSON},
+	FormatNotepad: {"txt", "text/plain; charset=utf-8", writeNotepad},
+}
+
+// Attached reports whether the format is written to a file with Export rather than inlined with Lines
+func (f Format) Attached() bool {
+	_, ok := exporters[f]
+	return ok
+}
+
+// Filename returns name with the extension of the format
+func (f Format) Filename(name string) string {
+	return fmt.Sprintf("%s.%s", name, exporters[f].extension)
+}
+
+func (f Format) ContentType() string {
+	return exporters[f].contentType
+}
+
+// Export writes rows to w in the format, which must be Attached
+func (f Format) Export(w io.Writer, rows []*Row) error {
+	exporter, ok := exporters[f]
+	if !ok {
+		return fmt.Errorf("format %s is not exported to a file", f)
+	}
+	return exporter.write(w, rows)
+}
+
+func writeCSV(w io.Writer, rows []*Row) error {
+
+	c := csv.NewWriter(w)
+	err := c.Write([]string{"character_id", "character_name", "killmail_id", "time", "system_id", "system", "security", "ship_type_id", "ship", "zkillboard_url"})
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		err = c.Write([]string{
+			strconv.FormatUint(row.CharacterID, 10),
+			row.CharacterName,
+			strconv.Itoa(row.KillmailID),
+			row.Time.UTC().Format(time.RFC3339),
+			strconv.Itoa(row.SystemID),
+			row.System,
+			strconv.FormatFloat(row.Security, 'f', 2, 64),
+			strconv.FormatUint(uint64(row.ShipTypeID), 10),
+			row.Ship,
+			row.URL,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	c.Flush()
+	return c.Error()
+
+}
+
+func writeJSON(w io.Writer, rows []*Row) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+// writeNotepad writes a line per row for the in game notepad, where the character, system and ship
+// link to their showinfo windows and the killmail links to zKillboard
+func writeNotepad(w io.Writer, rows []*Row) error {
+
+	for _, row := range rows {
+		_, err := fmt.Fprintf(
+			w,
+			"%s %s <url=showinfo:5//%d>%s</url> (%.1f) <url=showinfo:%d>%s</url> <url=%s>Killmail %d</url>\n",
+			eveLink(row.CharacterID, row.CharacterName),
+			row.Time.UTC().Format("2006-01-02 15:04"),
+			row.SystemID, row.System, row.Security,
+			row.ShipTypeID, row.Ship,
+			row.URL, row.KillmailID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
@@ -0,0 +1,307 @@
+package killright
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/internal/esi"
+	"github.com/eveisesi/krinder/internal/store/memory"
+	"github.com/eveisesi/krinder/internal/universe"
+	"github.com/eveisesi/krinder/internal/wars"
+	"github.com/eveisesi/krinder/internal/zkillboard"
+	"github.com/eveisesi/krinder/pkg/clock"
+	"github.com/eveisesi/krinder/pkg/errorcode"
+	"github.com/eveisesi/krinder/pkg/roundtripper"
+	"github.com/sirupsen/logrus"
+)
+
+// record captures new fixtures from the live APIs, e.g. go test ./internal/killright -run Fixture -record.
+// The fixtures checked in are synthetic, written by hand around made up ids, so recording refuses to
+// overwrite them. Delete a fixture to record it, and revisit the expectations of the scenarios after
+var record = flag.Bool("record", false, "record fixtures from ESI and zKillboard instead of replaying them")
+
+const (
+	fixtureVictim   uint64 = 2112000001
+	fixtureAttacker uint64 = 2112000002
+	// fixtureWarTarget is in the corporation at war with fixtureVictim's
+	fixtureWarTarget uint64 = 2112000003
+	fixtureGanker    uint64 = 2112000004
+	fixtureSecond    uint64 = 2112000005
+)
+
+// fixtureService returns a Service answering from testdata/fixtures as of the 15th of November 2021.
+// The corporation of fixtureVictim is at war with 98000003, so kills by its members never qualify.
+// The war has been retracted and finishes on the 20th, so it is still running
+func fixtureService(t *testing.T) *Service {
+	t.Helper()
+
+	mode := roundtripper.ModeReplay
+	if *record {
+		mode = roundtripper.ModeRecord
+	}
+	recorder := roundtripper.NewRecorder("testdata/fixtures", mode, nil)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	api := esi.New(logger, "krinder-test", nil, esi.WithTransport(recorder))
+	zkb := zkillboard.New(logger, "krinder-test", zkillboard.WithTransport(recorder))
+
+	now := clock.Fixed(time.Date(2021, 11, 15, 12, 0, 0, 0, time.UTC))
+
+	aggressor, defender := uint(98000003), uint(98000001)
+	finished := time.Date(2021, 11, 20, 12, 0, 0, 0, time.UTC)
+	warRepo := memory.NewWarRepository(now)
+	_, err := warRepo.CreateWar(context.Background(), &krinder.MongoWar{
+		ID:        700001,
+		Declared:  time.Date(2021, 9, 30, 12, 0, 0, 0, time.UTC),
+		Started:   time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC),
+		Finished:  &finished,
+		Aggressor: &krinder.MongoWarAggressor{CorporationID: &aggressor},
+		Defender:  &krinder.MongoWarDefender{CorporationID: &defender},
+	})
+	if err != nil {
+		t.Fatalf("failed to create war: %s", err)
+	}
+
+	return New(
+		logger,
+		zkb,
+		api,
+		wars.NewService(logger, api, warRepo, nil, now),
+		universe.New(logger, nil, api, memory.NewUniverseRepository(now), now),
+		nil,
+		now,
+	)
+}
+
+func characterIDs(characters ...*esi.CharacterOk) string {
+	ids := make([]uint64, 0, len(characters))
+	for _, character := range characters {
+		ids = append(ids, character.ID)
+	}
+	return fmt.Sprint(ids)
+}
+
+func TestFixtureAttacker(t *testing.T) {
+
+	report, err := fixtureService(t).Attacker(context.Background(), fixtureAttacker, "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if report.Character.Name != "Attacker Pilot" {
+		t.Errorf("expected the searched character to be resolved, got %q", report.Character.Name)
+	}
+
+	// 95000004 is older than the lookback
+	killmails := make([]int, 0, len(report.Killmails))
+	for _, killmail := range report.Killmails {
+		killmails = append(killmails, killmail.KillmailID)
+	}
+	if fmt.Sprint(killmails) != "[95000005 95000001]" {
+		t.Errorf("unexpected killmails %v", killmails)
+	}
+
+	for _, row := range report.Rows() {
+		if row.Ship == "" || row.System == "" {
+			t.Errorf("expected the ship and system of killmail %d to be resolved, got %q in %q", row.KillmailID, row.Ship, row.System)
+		}
+	}
+
+}
+
+func TestFixtureAttackerAsOf(t *testing.T) {
+
+	s := fixtureService(t).AsOf(time.Date(2021, 11, 11, 23, 59, 59, 0, time.UTC))
+
+	report, err := s.Attacker(context.Background(), fixtureAttacker, "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// 95000005 happened after the search and 95000004 is still older than the lookback
+	if len(report.Killmails) != 1 || report.Killmails[0].KillmailID != 95000001 {
+		t.Errorf("expected only killmail 95000001, got %d killmails", len(report.Killmails))
+	}
+
+}
+
+func TestFixtureVictim(t *testing.T) {
+
+	report, err := fixtureService(t).Victim(context.Background(), fixtureVictim, "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The war target on 95000001 is excluded, as is the ganker's kill of a Rifter in low sec on 95000003
+	aggressors := make([]*esi.CharacterOk, 0, len(report.Aggressors))
+	for _, aggressor := range report.Aggressors {
+		if aggressor.Seen != 1 {
+			t.Errorf("expected %s to be seen once, got %d", aggressor.Character.Name, aggressor.Seen)
+		}
+		aggressors = append(aggressors, aggressor.Character)
+	}
+	if got, expected := characterIDs(aggressors...), fmt.Sprint([]uint64{fixtureAttacker, fixtureGanker}); got != expected {
+		t.Errorf("expected aggressors %s, got %s", expected, got)
+	}
+
+	rows := report.Rows()
+	if len(rows) != len(report.Aggressors) {
+		t.Fatalf("expected a row of evidence per aggressor, got %d", len(rows))
+	}
+	for _, row := range rows {
+		if row.Ship == "" {
+			t.Errorf("expected the ship of killmail %d to be resolved", row.KillmailID)
+		}
+	}
+
+}
+
+func TestFixtureShip(t *testing.T) {
+
+	s := fixtureService(t)
+
+	summary, err := s.Ship(context.Background(), 25, 0, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if summary.Killmails != 2 || len(summary.Ships) != 1 || summary.Ships[0].Entity.Name != "Rifter" {
+		t.Fatalf("expected 2 Rifter killmails, got %d across %d ships", summary.Killmails, len(summary.Ships))
+	}
+
+	report, err := s.Ship(context.Background(), 25, 587, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Ship searches list every attacker, including the war target on 95000001
+	expected := map[uint64]string{
+		fixtureSecond: fmt.Sprint([]uint64{fixtureAttacker}),
+		fixtureVictim: fmt.Sprint([]uint64{fixtureAttacker, fixtureWarTarget}),
+	}
+	victims := make([]*esi.CharacterOk, 0, len(report.Victims))
+	for _, victim := range report.Victims {
+		victims = append(victims, victim.Character)
+		aggressors := make([]*esi.CharacterOk, 0, len(victim.Aggressors))
+		for _, aggressor := range victim.Aggressors {
+			aggressors = append(aggressors, aggressor.Character)
+		}
+		if got := characterIDs(aggressors...); got != expected[victim.Character.ID] {
+			t.Errorf("expected %s to hold kill rights on %s, got %s", victim.Character.Name, expected[victim.Character.ID], got)
+		}
+	}
+	if got, expected := characterIDs(victims...), fmt.Sprint([]uint64{fixtureSecond, fixtureVictim}); got != expected {
+		t.Errorf("expected victims %s, got %s", expected, got)
+	}
+
+	_, err = s.Ship(context.Background(), 25, 670, nil)
+	if err == nil {
+		t.Error("expected an error searching for a ship without qualifying losses")
+	}
+
+}
+
+func TestFixtureKillmail(t *testing.T) {
+
+	summary, err := fixtureService(t).Killmail(context.Background(), 95000001)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if summary.Killmail.Victim.Ship == nil || summary.Killmail.Victim.Ship.Name != "Rifter" {
+		t.Errorf("expected the victim's ship to be resolved to a Rifter, got %+v", summary.Killmail.Victim.Ship)
+	}
+	if summary.Meta == nil || summary.Meta.TotalValue != 1200000.5 {
+		t.Errorf("expected the zkillboard meta to be attached, got %+v", summary.Meta)
+	}
+	if got := summary.FinalBlow.String(); got != "Attacker Pilot (Myrmidon)" {
+		t.Errorf("unexpected final blow %q", got)
+	}
+	if summary.TopDamage.Attacker.CharacterID != fixtureAttacker {
+		t.Errorf("expected the first of the attackers tied on damage to top it, got %d", summary.TopDamage.Attacker.CharacterID)
+	}
+
+	// 98000003 is at war with the victim, so only the attacker is left holding a kill right
+	if !summary.WarKill {
+		t.Error("expected a war kill")
+	}
+	if got, expected := characterIDs(summary.KillRights...), fmt.Sprint([]uint64{fixtureAttacker}); got != expected {
+		t.Errorf("expected kill rights on %s, got %s", expected, got)
+	}
+
+	_, err = fixtureService(t).Killmail(context.Background(), 95000009)
+	if err == nil {
+		t.Error("expected an error for a killmail without a fixture")
+	}
+
+	// zKillboard answers 95000002 without its zkb meta
+	_, err = fixtureService(t).Killmail(context.Background(), 95000002)
+	if errorcode.Code(err) != errorcode.UpstreamUnavailable {
+		t.Errorf("expected %s for a killmail without a hash, got %v", errorcode.UpstreamUnavailable, err)
+	}
+
+}
+
+func TestFixtureProfile(t *testing.T) {
+
+	profile, err := fixtureService(t).Profile(context.Background(), fixtureVictim, "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if profile.Corporation != "Victim Corp" || profile.Alliance != "" {
+		t.Errorf("unexpected affiliation %q / %q", profile.Corporation, profile.Alliance)
+	}
+
+	names := func(affiliations []*Affiliation) string {
+		out := make([]string, 0, len(affiliations))
+		for _, affiliation := range affiliations {
+			out = append(out, affiliation.Name)
+		}
+		return fmt.Sprintf("%q", out)
+	}
+	if got := names(profile.CorporationHistory); got != `["Victim Corp" "Imperial Academy"]` {
+		t.Errorf("unexpected corporation history %s", got)
+	}
+	if got := names(profile.AllianceHistory); got != `["" "Former Alliance"]` {
+		t.Errorf("unexpected alliance history %s", got)
+	}
+
+	// 95000002 and 95000003 were lost in low sec and 95000004 is older than the lookback
+	if len(profile.Kills) != 0 || len(profile.Losses) != 1 || profile.Losses[0].KillmailID != 95000001 {
+		t.Errorf("expected only the high sec loss 95000001, got %d kills and %d losses", len(profile.Kills), len(profile.Losses))
+	}
+
+	tallies := func(tallies []*Tally) string {
+		out := make([]string, 0, len(tallies))
+		for _, tally := range tallies {
+			out = append(out, fmt.Sprintf("%s:%d", tally.Name, tally.Count))
+		}
+		return fmt.Sprint(out)
+	}
+	if got := tallies(profile.Ships); got != "[Rifter:2 Capsule:1]" {
+		t.Errorf("unexpected ships %s", got)
+	}
+	if got := tallies(profile.Systems); got != "[Tama:2 Jita:1]" {
+		t.Errorf("unexpected systems %s", got)
+	}
+	if profile.Hours[9] != 1 || profile.Hours[18] != 1 || profile.Hours[20] != 1 {
+		t.Errorf("unexpected activity by hour %v", profile.Hours)
+	}
+
+	if len(profile.Wars) != 1 || profile.Wars[0].Aggressor != "Aggressor Corp" || profile.Wars[0].Defender != "Victim Corp" {
+		t.Errorf("expected the war against Aggressor Corp, got %d wars", len(profile.Wars))
+	}
+
+	if profile.KillRights != 0 {
+		t.Errorf("expected no kill rights on a character without kills, got %d", profile.KillRights)
+	}
+
+}
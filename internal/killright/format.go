@@ -0,0 +1,373 @@
+package killright
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eveisesi/krinder/internal/esi"
+	"github.com/eveisesi/krinder/pkg/errorcode"
+)
+
+type Format string
+
+const (
+	FormatSimple   Format = "simple"
+	FormatDetailed Format = "detailed"
+	FormatEveLink  Format = "evelink"
+	FormatCSV      Format = "csv"
+	FormatJSON     Format = "json"
+	FormatNotepad  Format = "notepad"
+)
+
+// formats lists every format in the order they are offered to users, along with the other
+// spellings that have been advertised in help text over time
+var formats = []struct {
+	format  Format
+	aliases []string
+}{
+	{FormatSimple, nil},
+	{FormatDetailed, []string{"details"}},
+	{FormatEveLink, []string{"evelinks"}},
+	{FormatCSV, nil},
+	{FormatJSON, nil},
+	{FormatNotepad, []string{"eve-notepad"}},
+}
+
+// Formats returns the name of every format
+func Formats() []string {
+	names := make([]string, 0, len(formats))
+	for _, f := range formats {
+		names = append(names, string(f.format))
+	}
+	return names
+}
+
+// ParseFormat resolves a user supplied format by its name or one of its aliases
+func ParseFormat(s string) (Format, error) {
+	s = strings.ToLower(s)
+	if s == "" {
+		return FormatSimple, nil
+	}
+
+	for _, f := range formats {
+		if s == string(f.format) {
+			return f.format, nil
+		}
+		for _, alias := range f.aliases {
+			if s == alias {
+				return f.format, nil
+			}
+		}
+	}
+
+	return "", errorcode.Newf(errorcode.InvalidArgument, "unknown format %s, expected one of %s", s, strings.Join(Formats(), ", "))
+}
+
+// Legend returns an explanation of the lines of an attacker report in the format, if one is needed
+func (f Format) Legend() string {
+	switch f {
+	case FormatEveLink:
+		return "Copy and Paste this text into the in game notepad. The text will link to the characters showinfo window"
+	case FormatDetailed:
+		return "<Attacker> killed <Victim> (<Killmail ID>) on <Date> in <System> (<System Sec>)"
+	}
+	return ""
+}
+
+func eveLink(id uint64, name string) string {
+	return fmt.Sprintf("<url=showinfo:1373//%d>%s</url>", id, name)
+}
+
+// Lines returns one line per victim that may hold a kill right on the attacker
+func (r *AttackerReport) Lines(format Format) []string {
+
+	lines := make([]string, 0, len(r.Killmails))
+	for _, killmail := range r.Killmails {
+		victim := killmail.Victim.Character
+		switch format {
+		case FormatEveLink:
+			lines = append(lines, eveLink(killmail.Victim.CharacterID, victim.Name))
+		case FormatDetailed:
+			lines = append(lines, fmt.Sprintf(
+				"%s killed %s (%d) on %s in %s (%.2f)",
+				r.Character.Name,
+				victim.Name,
+				killmail.KillmailID,
+				killmail.KillmailTime.Format("2006-01-02"),
+				killmail.SolarSystem.Name,
+				killmail.SolarSystem.SecurityStatus,
+			))
+		default:
+			lines = append(lines, victim.Name)
+		}
+	}
+
+	return lines
+
+}
+
+// Lines returns one line per character the victim may hold a kill right on
+func (r *VictimReport) Lines(format Format) []string {
+
+	lines := make([]string, 0, len(r.Aggressors))
+	for _, aggressor := range r.Aggressors {
+		switch format {
+		case FormatEveLink:
+			lines = append(lines, eveLink(aggressor.Character.ID, aggressor.Character.Name))
+		default:
+			lines = append(lines, fmt.Sprintf("%s (%d)", aggressor.Character.Name, aggressor.Character.ID))
+		}
+	}
+
+	return lines
+
+}
+
+// Lines returns a section per outcome of the reconciliation, listing the characters in it
+func (r *Reconciliation) Lines(format Format) []string {
+
+	lines := make([]string, 0, len(r.Confirmed)+len(r.Missing)+len(r.Unexpected)+3)
+	sections := []struct {
+		title      string
+		characters []*esi.CharacterOk
+	}{
+		{"Confirmed", r.Confirmed},
+		{"Missing (predicted, not held)", r.Missing},
+		{"Unexpected (held, not predicted)", r.Unexpected},
+	}
+
+	for _, section := range sections {
+		lines = append(lines, fmt.Sprintf("%s: %d", section.title, len(section.characters)))
+		for _, character := range section.characters {
+			switch format {
+			case FormatEveLink:
+				lines = append(lines, "  "+eveLink(character.ID, character.Name))
+			default:
+				lines = append(lines, fmt.Sprintf("  %s (%d)", character.Name, character.ID))
+			}
+		}
+	}
+
+	return lines
+
+}
+
+// SummaryLines returns the number of qualifying losses per ship in the group
+func (r *ShipReport) SummaryLines() []string {
+
+	lines := make([]string, 0, len(r.Ships))
+	for _, ship := range r.Ships {
+		lines = append(lines, fmt.Sprintf("%d | %s (%d)", len(ship.Killmails), ship.Entity.Name, ship.Entity.ID))
+	}
+
+	return lines
+
+}
+
+// VictimLines returns one entry per victim of the searched ship type. When verbose is
+// true each entry lists the characters the victim may hold kill rights on
+func (r *ShipReport) VictimLines(verbose bool) []string {
+
+	lines := make([]string, 0, len(r.Victims))
+	for _, victim := range r.Victims {
+		if !verbose {
+			lines = append(lines, fmt.Sprintf("%s (%d) - %d Potential Kill Rights ", victim.Character.Name, victim.Character.ID, len(victim.Aggressors)))
+			continue
+		}
+
+		aggressors := make([]string, 0, len(victim.Aggressors))
+		for _, aggressor := range victim.Aggressors {
+			aggressors = append(aggressors, fmt.Sprintf("%d: %s (%d)", aggressor.Character.ID, aggressor.Character.Name, aggressor.Seen))
+		}
+
+		lines = append(lines, fmt.Sprintf("%s (%d)\n%s\n%s\n", victim.Character.Name, victim.Character.ID, "============", strings.Join(aggressors, "\n")))
+	}
+
+	return lines
+
+}
+
+// Lines describes the killmail, its attackers and the kill rights it likely generated
+func (r *KillmailSummary) Lines() []string {
+
+	killmail := r.Killmail
+
+	victim := "Structure"
+	if killmail.Victim.Character != nil {
+		victim = killmail.Victim.Character.Name
+	}
+	ship := fmt.Sprintf("type %d", killmail.Victim.ShipTypeID)
+	if killmail.Victim.Ship != nil {
+		ship = killmail.Victim.Ship.Name
+	}
+
+	lines := []string{
+		fmt.Sprintf("Killmail %d: %s lost a %s", killmail.KillmailID, victim, ship),
+		fmt.Sprintf("Time: %s", killmail.KillmailTime.UTC().Format("2006-01-02 15:04")),
+		fmt.Sprintf("System: %s (%.1f)", killmail.SolarSystem.Name, killmail.SolarSystem.SecurityStatus),
+	}
+	if r.FinalBlow != nil {
+		lines = append(lines, fmt.Sprintf("Final Blow: %s", r.FinalBlow))
+	}
+	if r.TopDamage != nil {
+		lines = append(lines, fmt.Sprintf("Top Damage: %s, %d damage", r.TopDamage, r.TopDamage.Attacker.DamageDone))
+	}
+	lines = append(lines, fmt.Sprintf("Attackers: %d", len(killmail.Attackers)))
+	if r.Meta != nil {
+		lines = append(lines, fmt.Sprintf("Value: %s ISK", ISK(r.Meta.TotalValue)))
+	}
+	lines = append(lines, fmt.Sprintf("War Kill: %s", yesNo(r.WarKill)))
+
+	if len(r.KillRights) == 0 {
+		return append(lines, "Kill Rights: none likely")
+	}
+
+	lines = append(lines, fmt.Sprintf("Kill Rights: %s likely holds kill rights on", victim))
+	for _, character := range r.KillRights {
+		lines = append(lines, fmt.Sprintf("  %s (%d)", character.Name, character.ID))
+	}
+
+	return lines
+
+}
+
+// ISK abbreviates an amount of ISK the way zKillboard does, e.g. 1.20m
+func ISK(value float64) string {
+	switch {
+	case value >= 1e12:
+		return fmt.Sprintf("%.2ft", value/1e12)
+	case value >= 1e9:
+		return fmt.Sprintf("%.2fb", value/1e9)
+	case value >= 1e6:
+		return fmt.Sprintf("%.2fm", value/1e6)
+	case value >= 1e3:
+		return fmt.Sprintf("%.2fk", value/1e3)
+	}
+	return fmt.Sprintf("%.2f", value)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// Lines describes the profile, listing at most limit entries of each history, killmail and tally
+func (p *Profile) Lines(limit int) []string {
+
+	character := p.Character
+	affiliation := p.Corporation
+	if p.Alliance != "" {
+		affiliation = fmt.Sprintf("%s / %s", p.Corporation, p.Alliance)
+	}
+
+	lines := []string{
+		fmt.Sprintf("%s (%d), %s", character.Name, character.ID, affiliation),
+		fmt.Sprintf("Security Status: %.1f", character.SecurityStatus),
+		fmt.Sprintf("Outstanding Kill Rights: %d", p.KillRights),
+		"Corporation History:",
+	}
+	lines = append(lines, indent(AffiliationLines(p.CorporationHistory, limit))...)
+	lines = append(lines, "Alliance History of the Corporation:")
+	lines = append(lines, indent(AffiliationLines(p.AllianceHistory, limit))...)
+
+	lines = append(lines, fmt.Sprintf("High Sec Kills: %d", len(p.Kills)))
+	lines = append(lines, indent(ProfileKillmailLines(p.Kills, limit))...)
+	lines = append(lines, fmt.Sprintf("High Sec Losses: %d", len(p.Losses)))
+	lines = append(lines, indent(ProfileKillmailLines(p.Losses, limit))...)
+
+	lines = append(lines, "Ships:")
+	lines = append(lines, indent(TallyLines(p.Ships, limit))...)
+	lines = append(lines, "Systems:")
+	lines = append(lines, indent(TallyLines(p.Systems, limit))...)
+	lines = append(lines, "Activity (UTC):")
+	lines = append(lines, indent(strings.Split(p.ActiveHours(), "\n"))...)
+
+	lines = append(lines, fmt.Sprintf("Active Wars: %d", len(p.Wars)))
+	lines = append(lines, indent(WarLines(p.Wars, limit))...)
+
+	return lines
+
+}
+
+// AffiliationLines returns a line per affiliation with the date it was joined
+func AffiliationLines(affiliations []*Affiliation, limit int) []string {
+	lines := make([]string, 0, len(affiliations))
+	for _, a := range affiliations[:head(len(affiliations), limit)] {
+		name := a.Name
+		switch {
+		case a.ID == 0:
+			name = "No Alliance"
+		case name == "":
+			name = fmt.Sprint(a.ID)
+		}
+		lines = append(lines, fmt.Sprintf("%s since %s", name, a.StartDate.Format("2006-01-02")))
+	}
+	return more(lines, len(affiliations), limit)
+}
+
+// ProfileKillmailLines returns a line per killmail with the ship lost and where
+func ProfileKillmailLines(killmails []*esi.KillmailOk, limit int) []string {
+	lines := make([]string, 0, len(killmails))
+	for _, killmail := range killmails[:head(len(killmails), limit)] {
+		ship := fmt.Sprintf("type %d", killmail.Victim.ShipTypeID)
+		if killmail.Victim.Ship != nil {
+			ship = killmail.Victim.Ship.Name
+		}
+		lines = append(lines, fmt.Sprintf(
+			"%d: %s on %s in %s (%.1f)",
+			killmail.KillmailID,
+			ship,
+			killmail.KillmailTime.UTC().Format("2006-01-02 15:04"),
+			killmail.SolarSystem.Name,
+			killmail.SolarSystem.SecurityStatus,
+		))
+	}
+	return more(lines, len(killmails), limit)
+}
+
+// TallyLines returns a line per tally with its count
+func TallyLines(tallies []*Tally, limit int) []string {
+	lines := make([]string, 0, len(tallies))
+	for _, tally := range tallies[:head(len(tallies), limit)] {
+		name := tally.Name
+		if name == "" {
+			name = fmt.Sprint(tally.ID)
+		}
+		lines = append(lines, fmt.Sprintf("%d | %s", tally.Count, name))
+	}
+	return more(lines, len(tallies), limit)
+}
+
+// WarLines returns a line per war naming its sides
+func WarLines(wars []*ProfileWar, limit int) []string {
+	lines := make([]string, 0, len(wars))
+	for _, war := range wars[:head(len(wars), limit)] {
+		lines = append(lines, fmt.Sprintf("%d: %s vs %s since %s", war.War.ID, war.Aggressor, war.Defender, war.War.Started.Format("2006-01-02")))
+	}
+	return more(lines, len(wars), limit)
+}
+
+// head returns the number of count entries to list, at most limit when limit is positive
+func head(count, limit int) int {
+	if limit > 0 && count > limit {
+		return limit
+	}
+	return count
+}
+
+// more notes the number of entries left out by head
+func more(lines []string, count, limit int) []string {
+	if limit > 0 && count > limit {
+		lines = append(lines, fmt.Sprintf("and %d more", count-limit))
+	}
+	return lines
+}
+
+func indent(lines []string) []string {
+	for i, line := range lines {
+		lines[i] = "  " + line
+	}
+	return lines
+}
@@ -0,0 +1,147 @@
+package killright
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/krinder/internal/esi"
+)
+
+func TestParseFormat(t *testing.T) {
+
+	tests := map[string]Format{
+		"":            FormatSimple,
+		"simple":      FormatSimple,
+		"details":     FormatDetailed,
+		"detailed":    FormatDetailed,
+		"evelink":     FormatEveLink,
+		"EVELINKS":    FormatEveLink,
+		"CSV":         FormatCSV,
+		"json":        FormatJSON,
+		"eve-notepad": FormatNotepad,
+	}
+
+	for input, expected := range tests {
+		format, err := ParseFormat(input)
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %s", input, err)
+			continue
+		}
+		if format != expected {
+			t.Errorf("expected %q to parse to %s, got %s", input, expected, format)
+		}
+	}
+
+	_, err := ParseFormat("xml")
+	if err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+
+}
+
+func TestAttackerReportLines(t *testing.T) {
+
+	report := &AttackerReport{
+		Character: &esi.CharacterOk{ID: 1, Name: "Attacker"},
+		Killmails: []*esi.KillmailOk{
+			{
+				KillmailID:   100,
+				KillmailTime: time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC),
+				Victim:       &esi.KillmailVictim{CharacterID: 2, Character: &esi.CharacterOk{ID: 2, Name: "Victim"}},
+				SolarSystem:  &esi.SystemOk{Name: "Jita", SecurityStatus: 0.946},
+			},
+		},
+	}
+
+	tests := map[Format][]string{
+		FormatSimple:   {"Victim"},
+		FormatDetailed: {"Attacker killed Victim (100) on 2021-10-01 in Jita (0.95)"},
+		FormatEveLink:  {"<url=showinfo:1373//2>Victim</url>"},
+	}
+
+	for format, expected := range tests {
+		lines := report.Lines(format)
+		if !reflect.DeepEqual(lines, expected) {
+			t.Errorf("expected %s lines %v, got %v", format, expected, lines)
+		}
+	}
+
+}
+
+func TestExport(t *testing.T) {
+
+	rows := []*Row{
+		{
+			CharacterID:   2,
+			CharacterName: "Victim, Jr",
+			KillmailID:    100,
+			Time:          time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC),
+			SystemID:      30000142,
+			System:        "Jita",
+			Security:      0.946,
+			ShipTypeID:    587,
+			Ship:          "Rifter",
+			URL:           "https://zkillboard.com/kill/100/",
+		},
+	}
+
+	tests := map[Format]string{
+		FormatCSV: "character_id,character_name,killmail_id,time,system_id,system,security,ship_type_id,ship,zkillboard_url\n" +
+			"2,\"Victim, Jr\",100,2021-10-01T12:00:00Z,30000142,Jita,0.95,587,Rifter,https://zkillboard.com/kill/100/\n",
+		FormatNotepad: "<url=showinfo:1373//2>Victim, Jr</url> 2021-10-01 12:00 <url=showinfo:5//30000142>Jita</url> (0.9) " +
+			"<url=showinfo:587>Rifter</url> <url=https://zkillboard.com/kill/100/>Killmail 100</url>\n",
+	}
+
+	for format, expected := range tests {
+		var buf bytes.Buffer
+		err := format.Export(&buf, rows)
+		if err != nil {
+			t.Fatalf("unexpected error exporting %s: %s", format, err)
+		}
+		if buf.String() != expected {
+			t.Errorf("unexpected %s export:\n%s", format, buf.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	err := FormatJSON.Export(&buf, rows)
+	if err != nil {
+		t.Fatalf("unexpected error exporting json: %s", err)
+	}
+	var decoded []*Row
+	err = json.Unmarshal(buf.Bytes(), &decoded)
+	if err != nil {
+		t.Fatalf("failed to decode json export: %s", err)
+	}
+	if !reflect.DeepEqual(decoded, rows) {
+		t.Errorf("expected json export to round trip, got %+v", decoded[0])
+	}
+
+	if FormatSimple.Attached() || FormatSimple.Export(&buf, rows) == nil {
+		t.Error("expected the simple format to be inlined")
+	}
+	if name := FormatNotepad.Filename("killrights"); name != "killrights.txt" {
+		t.Errorf("unexpected filename %s", name)
+	}
+
+}
+
+func TestISK(t *testing.T) {
+
+	tests := map[float64]string{
+		950:           "950.00",
+		1200000.5:     "1.20m",
+		3450000000:    "3.45b",
+		1250000000000: "1.25t",
+	}
+
+	for value, expected := range tests {
+		if got := ISK(value); got != expected {
+			t.Errorf("expected %f to be %s, got %s", value, expected, got)
+		}
+	}
+
+}
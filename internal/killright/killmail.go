@@ -0,0 +1,187 @@
+package killright
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/internal/esi"
+	"github.com/eveisesi/krinder/internal/zkillboard"
+	"github.com/eveisesi/krinder/pkg/errorcode"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Participant is an attacker on a killmail with their character and ship resolved. Character is
+// nil for NPCs and Ship is nil when the type could not be resolved
+type Participant struct {
+	Character *esi.CharacterOk      `json:"character,omitempty"`
+	Ship      *krinder.MongoEntity  `json:"ship,omitempty"`
+	Attacker  *esi.KillmailAttacker `json:"attacker"`
+}
+
+// String names the participant and their ship, e.g. Pilot (Rifter)
+func (p *Participant) String() string {
+
+	name := "NPC"
+	if p.Character != nil {
+		name = p.Character.Name
+	} else if p.Attacker.CharacterID != 0 {
+		name = fmt.Sprintf("Character %d", p.Attacker.CharacterID)
+	}
+
+	ship := fmt.Sprintf("type %d", p.Attacker.ShipTypeID)
+	if p.Ship != nil {
+		ship = p.Ship.Name
+	}
+
+	return fmt.Sprintf("%s (%s)", name, ship)
+
+}
+
+// KillmailSummary describes a single killmail and the kill rights it likely generated
+type KillmailSummary struct {
+	Killmail *esi.KillmailOk `json:"killmail"`
+	// Meta is zKillboard's meta of the killmail, such as its value
+	Meta      *zkillboard.Meta `json:"zkb"`
+	FinalBlow *Participant     `json:"finalBlow,omitempty"`
+	TopDamage *Participant     `json:"topDamage,omitempty"`
+	// WarKill is set when any attacker was at war with the victim
+	WarKill bool `json:"warKill"`
+	// KillRights are the attackers the victim likely holds a kill right on
+	KillRights []*esi.CharacterOk `json:"killRights"`
+	// Warning is set when the summary was built from incomplete war data
+	Warning string `json:"warning,omitempty"`
+}
+
+// Killmail summarises the killmail, which is looked up on zKillboard for its hash, along with
+// the attackers its victim likely holds kill rights on
+func (s *Service) Killmail(ctx context.Context, killmailID uint64) (*KillmailSummary, error) {
+
+	zmail, err := s.zkb.Killmail(ctx, killmailID)
+	if err != nil {
+		return nil, err
+	}
+	if zmail.Meta == nil {
+		return nil, errorcode.Newf(errorcode.UpstreamUnavailable, "zKillboard returned killmail %d without its hash", killmailID)
+	}
+
+	killmail, err := s.esi.KillmailByIDHash(ctx, int64(zmail.KillmailID), zmail.Meta.Hash)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch killmail from ESI")
+	}
+
+	qualifies, err := s.qualifyingSystem(ctx, killmail)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch killmail solar system from ESI")
+	}
+
+	if killmail.Victim.CharacterID != 0 {
+		killmail.Victim.Character, err = s.esi.Character(ctx, killmail.Victim.CharacterID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to fetch killmail victim character from ESI")
+		}
+	}
+	s.resolveShip(ctx, killmail)
+
+	summary := &KillmailSummary{
+		Killmail:   killmail,
+		Meta:       zmail.Meta,
+		KillRights: make([]*esi.CharacterOk, 0),
+		Warning:    s.wars.Warning(),
+	}
+
+	var finalBlow, topDamage *esi.KillmailAttacker
+	for _, attacker := range killmail.Attackers {
+		if attacker.FinalBlow {
+			finalBlow = attacker
+		}
+		if topDamage == nil || attacker.DamageDone > topDamage.DamageDone {
+			topDamage = attacker
+		}
+	}
+
+	characters := make(map[uint64]*esi.CharacterOk)
+	summary.FinalBlow = s.participant(ctx, killmail, finalBlow, characters)
+	summary.TopDamage = s.participant(ctx, killmail, topDamage, characters)
+
+	// The victim holds kill rights on the attackers whose corporation was not at war with them
+	eligible := make(map[uint]bool)
+	for _, attacker := range uniqueAttackersByCorporation(killmail) {
+		atWar, err := s.atWar(ctx, killmail, attacker)
+		if err != nil {
+			return nil, err
+		}
+		if atWar {
+			summary.WarKill = true
+			continue
+		}
+		eligible[attacker.CorporationID] = true
+	}
+
+	// Structures do not hold kill rights and neither does anyone outside of qualifying systems
+	if !qualifies || killmail.Victim.CharacterID == 0 {
+		return summary, nil
+	}
+
+	for _, attacker := range killmail.Attackers {
+		if attacker.CharacterID == 0 || !eligible[attacker.CorporationID] {
+			continue
+		}
+
+		character, ok := characters[attacker.CharacterID]
+		if !ok {
+			character, err = s.esi.Character(ctx, attacker.CharacterID)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to fetch character %d, an attacker on killmail %d", attacker.CharacterID, killmail.KillmailID)
+			}
+			characters[attacker.CharacterID] = character
+		}
+
+		summary.KillRights = append(summary.KillRights, character)
+	}
+
+	return summary, nil
+
+}
+
+// participant resolves the character and ship of the attacker, caching characters in characters.
+// Names are only decoration of a summary, so failures are logged and leave them unresolved
+func (s *Service) participant(ctx context.Context, killmail *esi.KillmailOk, attacker *esi.KillmailAttacker, characters map[uint64]*esi.CharacterOk) *Participant {
+
+	if attacker == nil {
+		return nil
+	}
+
+	entry := s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"killmailID": killmail.KillmailID,
+		"service":    "killright",
+	})
+
+	participant := &Participant{Attacker: attacker}
+
+	if attacker.CharacterID != 0 {
+		character, ok := characters[attacker.CharacterID]
+		if !ok {
+			var err error
+			character, err = s.esi.Character(ctx, attacker.CharacterID)
+			if err != nil {
+				entry.WithError(err).WithField("characterID", attacker.CharacterID).Warn("failed to fetch attacker character from ESI")
+			} else {
+				characters[attacker.CharacterID] = character
+			}
+		}
+		participant.Character = character
+	}
+
+	if attacker.ShipTypeID != 0 {
+		ship, err := s.universe.Entity(ctx, attacker.ShipTypeID)
+		if err != nil {
+			entry.WithError(err).WithField("shipTypeID", attacker.ShipTypeID).Warn("failed to resolve the ship of the attacker")
+		}
+		participant.Ship = ship
+	}
+
+	return participant
+
+}
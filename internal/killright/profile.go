@@ -0,0 +1,333 @@
+package killright
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/internal/esi"
+	"github.com/eveisesi/krinder/internal/zkillboard"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// warLimit is the number of active wars of each of the corporation and alliance of a profile
+const warLimit = 25
+
+// Affiliation is a corporation or alliance that was joined at StartDate. ID is zero for the periods
+// a corporation was not in an alliance
+type Affiliation struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	StartDate time.Time `json:"startDate"`
+}
+
+// Tally counts the killmails a ship or system appears on
+type Tally struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// ProfileWar is an active war of the corporation or alliance of a profile, with its sides named
+type ProfileWar struct {
+	War       *krinder.MongoWar `json:"war"`
+	Aggressor string            `json:"aggressor"`
+	Defender  string            `json:"defender"`
+}
+
+// Profile gathers what is known about a character from ESI and their killmails within the lookback
+type Profile struct {
+	Character   *esi.CharacterOk `json:"character"`
+	Corporation string           `json:"corporation"`
+	Alliance    string           `json:"alliance,omitempty"`
+	// CorporationHistory is the corporations of the character, the most recent first
+	CorporationHistory []*Affiliation `json:"corporationHistory"`
+	// AllianceHistory is the alliances of the character's current corporation, the most recent first
+	AllianceHistory []*Affiliation `json:"allianceHistory"`
+	// Kills and Losses are the killmails in high sec, the most recent first
+	Kills  []*esi.KillmailOk `json:"kills"`
+	Losses []*esi.KillmailOk `json:"losses"`
+	// Ships and Systems tally every kill and loss, the most frequent first
+	Ships   []*Tally `json:"ships"`
+	Systems []*Tally `json:"systems"`
+	// Hours counts the kills and losses by the hour of the day they happened at in UTC
+	Hours [24]int       `json:"hours"`
+	Wars  []*ProfileWar `json:"wars"`
+	// KillRights is the number of victims that may hold a kill right on the character
+	KillRights int `json:"killRights"`
+	// Warning is set when the profile was built from incomplete war data
+	Warning string `json:"warning,omitempty"`
+}
+
+// Profile builds the profile of characterID. As with Attacker, killmails that were never posted to
+// zKillboard are included when requester has linked characterID
+func (s *Service) Profile(ctx context.Context, characterID uint64, requester string, progress Progress) (*Profile, error) {
+
+	character, err := s.esi.Character(ctx, characterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch character from ESI")
+	}
+
+	profile := &Profile{
+		Character: character,
+		Kills:     make([]*esi.KillmailOk, 0),
+		Losses:    make([]*esi.KillmailOk, 0),
+		Wars:      make([]*ProfileWar, 0),
+		Warning:   s.wars.Warning(),
+	}
+
+	corporations, err := s.esi.CharacterCorporationHistory(ctx, characterID)
+	if err != nil {
+		return nil, err
+	}
+
+	alliances, err := s.esi.CorporationAllianceHistory(ctx, character.CorporationID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []uint{character.CorporationID, character.AllianceID}
+	for _, corporation := range corporations {
+		ids = append(ids, corporation.CorporationID)
+	}
+	for _, alliance := range alliances {
+		ids = append(ids, alliance.AllianceID)
+	}
+
+	wars, err := s.activeWars(ctx, character)
+	if err != nil {
+		return nil, err
+	}
+	for _, war := range wars {
+		aggressor, defender := warSides(war)
+		ids = append(ids, aggressor, defender)
+	}
+
+	names := s.names(ctx, ids)
+
+	profile.Corporation = names[character.CorporationID]
+	profile.Alliance = names[character.AllianceID]
+	for _, corporation := range corporations {
+		profile.CorporationHistory = append(profile.CorporationHistory, &Affiliation{
+			ID:        corporation.CorporationID,
+			Name:      names[corporation.CorporationID],
+			StartDate: corporation.StartDate,
+		})
+	}
+	for _, alliance := range alliances {
+		profile.AllianceHistory = append(profile.AllianceHistory, &Affiliation{
+			ID:        alliance.AllianceID,
+			Name:      names[alliance.AllianceID],
+			StartDate: alliance.StartDate,
+		})
+	}
+	for _, war := range wars {
+		aggressor, defender := warSides(war)
+		profile.Wars = append(profile.Wars, &ProfileWar{
+			War:       war,
+			Aggressor: names[aggressor],
+			Defender:  names[defender],
+		})
+	}
+
+	kills, err := s.killmails(ctx, zkillboard.CharacterEntityType, characterID, zkillboard.KillsFetchType, requester, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	losses, err := s.killmails(ctx, zkillboard.CharacterEntityType, characterID, zkillboard.LossesFetchType, requester, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	ships := make(map[uint]*Tally)
+	systems := make(map[uint]*Tally)
+	for _, killmail := range append(append([]*esi.KillmailOk{}, kills...), losses...) {
+
+		// Only the system is needed here, whether the killmail qualified for a kill right is not.
+		// The system stays attached, so victims below does not fetch it again
+		_, err := s.qualifyingSystem(ctx, killmail)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to fetch killmail solar system from ESI")
+		}
+
+		ship := killmail.Victim.ShipTypeID
+		loss := killmail.Victim.CharacterID == characterID
+		if !loss {
+			for _, attacker := range killmail.Attackers {
+				if attacker.CharacterID == characterID {
+					ship = attacker.ShipTypeID
+					break
+				}
+			}
+		}
+
+		if ship != 0 {
+			if _, ok := ships[ship]; !ok {
+				ships[ship] = &Tally{ID: ship}
+			}
+			ships[ship].Count++
+		}
+
+		system := killmail.SolarSystem
+		if _, ok := systems[system.ID]; !ok {
+			systems[system.ID] = &Tally{ID: system.ID, Name: system.Name}
+		}
+		systems[system.ID].Count++
+
+		profile.Hours[killmail.KillmailTime.UTC().Hour()]++
+
+		if system.SecurityStatus < .5 {
+			continue
+		}
+
+		if loss {
+			s.resolveShip(ctx, killmail)
+			profile.Losses = append(profile.Losses, killmail)
+			continue
+		}
+
+		profile.Kills = append(profile.Kills, killmail)
+	}
+
+	for _, ship := range ships {
+		entity, err := s.universe.Entity(ctx, ship.ID)
+		if err != nil {
+			s.logger.WithContext(ctx).WithError(err).WithFields(logrus.Fields{
+				"shipTypeID": ship.ID,
+				"service":    "killright",
+			}).Warn("failed to resolve ship")
+		} else {
+			ship.Name = entity.Name
+		}
+		profile.Ships = append(profile.Ships, ship)
+	}
+	for _, system := range systems {
+		profile.Systems = append(profile.Systems, system)
+	}
+	sortTallies(profile.Ships)
+	sortTallies(profile.Systems)
+
+	victims, err := s.victims(ctx, characterID, kills, nil)
+	if err != nil {
+		return nil, err
+	}
+	profile.KillRights = len(victims)
+
+	return profile, nil
+
+}
+
+// activeWars returns the wars that the corporation and alliance of the character are currently in
+func (s *Service) activeWars(ctx context.Context, character *esi.CharacterOk) ([]*krinder.MongoWar, error) {
+
+	wars, err := s.wars.EntityWars(ctx, character.CorporationID, false, warLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	if character.AllianceID == 0 {
+		return wars, nil
+	}
+
+	allianceWars, err := s.wars.EntityWars(ctx, character.AllianceID, false, warLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(wars, allianceWars...), nil
+
+}
+
+// names resolves the ids of corporations and alliances to their names. Names only decorate a
+// profile, so a failure is logged and leaves the ids unnamed
+func (s *Service) names(ctx context.Context, ids []uint) map[uint]string {
+
+	names := make(map[uint]string)
+
+	seen := make(map[uint]bool)
+	unique := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, int(id))
+	}
+	if len(unique) == 0 {
+		return names
+	}
+	sort.Ints(unique)
+
+	resolved, err := s.esi.Names(ctx, unique)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).WithField("service", "killright").Warn("failed to resolve names")
+		return names
+	}
+
+	for _, name := range resolved {
+		names[uint(name.ID)] = name.Name
+	}
+
+	return names
+
+}
+
+// warSides returns the ids of the alliance or corporation on each side of the war
+func warSides(war *krinder.MongoWar) (aggressor, defender uint) {
+	if war.Aggressor != nil {
+		aggressor = warSide(war.Aggressor.AllianceID, war.Aggressor.CorporationID)
+	}
+	if war.Defender != nil {
+		defender = warSide(war.Defender.AllianceID, war.Defender.CorporationID)
+	}
+	return aggressor, defender
+}
+
+func warSide(allianceID, corporationID *uint) uint {
+	if allianceID != nil {
+		return *allianceID
+	}
+	if corporationID != nil {
+		return *corporationID
+	}
+	return 0
+}
+
+func sortTallies(tallies []*Tally) {
+	sort.SliceStable(tallies, func(i, j int) bool {
+		if tallies[i].Count == tallies[j].Count {
+			return tallies[i].Name < tallies[j].Name
+		}
+		return tallies[i].Count > tallies[j].Count
+	})
+}
+
+// ActiveHours draws Hours as a bar per hour of the day, from 00 to 23 UTC
+func (p *Profile) ActiveHours() string {
+
+	bars := []rune("▁▂▃▄▅▆▇█")
+
+	peak := 0
+	for _, count := range p.Hours {
+		if count > peak {
+			peak = count
+		}
+	}
+
+	out := make([]rune, 0, len(p.Hours))
+	for _, count := range p.Hours {
+		switch {
+		case count == 0:
+			out = append(out, ' ')
+		default:
+			out = append(out, bars[(count*len(bars)-1)/peak])
+		}
+	}
+
+	return fmt.Sprintf("%s\n00    06    12    18   ", string(out))
+
+}
@@ -0,0 +1,175 @@
+package killright
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/eveisesi/krinder/internal/esi"
+	"github.com/eveisesi/krinder/internal/metrics"
+	"github.com/eveisesi/krinder/pkg/errorcode"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// killRightEarned is the type of the notification a character receives when it is granted a kill right.
+// ESI has no endpoint listing the kill rights a character holds, so they are rebuilt from these notifications
+const killRightEarned = "KillRightEarned"
+
+// killRightUsed and killRightUnavailable are the types of the notifications a character receives when a kill
+// right it holds has been used or can no longer be used
+const (
+	killRightUsed        = "KillRightUsed"
+	killRightUnavailable = "KillRightUnavailable"
+)
+
+var errTokensUnavailable = errorcode.New(errorcode.InvalidArgument, "comparing kill rights requires linking characters, which is not enabled on this bot")
+
+// Reconciliation compares the kill rights a victim search predicted with those the character holds in game
+type Reconciliation struct {
+	VictimID uint64 `json:"victimID"`
+	// Confirmed were predicted and are held
+	Confirmed []*esi.CharacterOk `json:"confirmed"`
+	// Missing were predicted but are not held
+	Missing []*esi.CharacterOk `json:"missing"`
+	// Unexpected are held but were not predicted
+	Unexpected []*esi.CharacterOk `json:"unexpected"`
+	// Warning is set when the prediction was built from incomplete war data
+	Warning string `json:"warning,omitempty"`
+}
+
+// Accuracy is the share of predicted and held kill rights that were confirmed
+func (r *Reconciliation) Accuracy() float64 {
+	total := len(r.Confirmed) + len(r.Missing) + len(r.Unexpected)
+	if total == 0 {
+		return 1
+	}
+	return float64(len(r.Confirmed)) / float64(total)
+}
+
+// Reconcile imports the kill rights characterID holds in game, which requester must have linked,
+// and compares them with the result of Victim. Only kill rights earned within the lookback are
+// compared, as older ones could never have been predicted
+func (s *Service) Reconcile(ctx context.Context, characterID uint64, requester string, progress Progress) (*Reconciliation, error) {
+
+	if s.tokens == nil {
+		return nil, errTokensUnavailable
+	}
+
+	token, err := s.tokens.Token(ctx, requester, characterID)
+	if err != nil {
+		return nil, err
+	}
+
+	notifications, err := s.esi.CharacterNotifications(esi.WithAccessToken(ctx, token), characterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to import kill rights")
+	}
+
+	held := heldKillRights(notifications, s.boundary())
+
+	entry := s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"victimID": characterID,
+		"service":  "killright",
+	})
+	entry.WithField("held", len(held)).Debug("imported kill rights")
+
+	report, err := s.Victim(ctx, characterID, requester, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	reconciliation := reconcile(report, held)
+
+	for i, character := range reconciliation.Unexpected {
+		resolved, err := s.esi.Character(ctx, character.ID)
+		if err != nil {
+			entry.WithError(err).Error("failed to fetch character from ESI")
+			continue
+		}
+		reconciliation.Unexpected[i] = resolved
+	}
+
+	metrics.ObserveReconciliation(len(reconciliation.Confirmed), len(reconciliation.Missing), len(reconciliation.Unexpected))
+
+	return reconciliation, nil
+
+}
+
+// heldKillRights returns the ids of the characters that notifications granted a kill right on since boundary,
+// less those whose kill right was later used or became unavailable
+func heldKillRights(notifications []*esi.NotificationOk, boundary time.Time) map[uint64]bool {
+
+	// ESI does not guarantee the order of notifications, and a kill right can be earned again after it was used
+	sorted := make([]*esi.NotificationOk, len(notifications))
+	copy(sorted, notifications)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	held := make(map[uint64]bool)
+	for _, notification := range sorted {
+		if notification.Timestamp.Before(boundary) {
+			continue
+		}
+
+		switch notification.Type {
+		case killRightEarned, killRightUsed, killRightUnavailable:
+		default:
+			continue
+		}
+
+		var text struct {
+			CharID uint64 `yaml:"charID"`
+		}
+		if err := yaml.Unmarshal([]byte(notification.Text), &text); err != nil || text.CharID == 0 {
+			continue
+		}
+
+		if notification.Type == killRightEarned {
+			held[text.CharID] = true
+		} else {
+			delete(held, text.CharID)
+		}
+	}
+
+	return held
+
+}
+
+// reconcile sorts the aggressors of report and the held kill rights into a Reconciliation. Unexpected
+// characters only carry their id
+func reconcile(report *VictimReport, held map[uint64]bool) *Reconciliation {
+
+	reconciliation := &Reconciliation{
+		VictimID:   report.VictimID,
+		Confirmed:  make([]*esi.CharacterOk, 0),
+		Missing:    make([]*esi.CharacterOk, 0),
+		Unexpected: make([]*esi.CharacterOk, 0),
+		Warning:    report.Warning,
+	}
+
+	predicted := make(map[uint64]bool, len(report.Aggressors))
+	for _, aggressor := range report.Aggressors {
+		predicted[aggressor.Character.ID] = true
+		if held[aggressor.Character.ID] {
+			reconciliation.Confirmed = append(reconciliation.Confirmed, aggressor.Character)
+		} else {
+			reconciliation.Missing = append(reconciliation.Missing, aggressor.Character)
+		}
+	}
+
+	for id := range held {
+		if !predicted[id] {
+			reconciliation.Unexpected = append(reconciliation.Unexpected, &esi.CharacterOk{ID: id})
+		}
+	}
+
+	sort.Slice(reconciliation.Unexpected, func(i, j int) bool {
+		return reconciliation.Unexpected[i].ID < reconciliation.Unexpected[j].ID
+	})
+
+	return reconciliation
+
+}
@@ -0,0 +1,94 @@
+package killright
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/krinder/internal/esi"
+	"github.com/eveisesi/krinder/pkg/clock"
+)
+
+func TestHeldKillRights(t *testing.T) {
+
+	now := time.Now().UTC()
+	notifications := []*esi.NotificationOk{
+		{Type: killRightEarned, Timestamp: now, Text: "charID: 90000003\ndescriptionID: 1\n"},
+		{Type: killRightEarned, Timestamp: now.Add(-time.Hour), Text: "charID: 90000004\n"},
+		// Earned before the boundary
+		{Type: killRightEarned, Timestamp: now.AddDate(0, 0, -Lookback-1), Text: "charID: 90000005\n"},
+		{Type: "WarDeclared", Timestamp: now, Text: "charID: 90000006\n"},
+		{Type: killRightEarned, Timestamp: now, Text: "not: [yaml"},
+	}
+
+	s := &Service{clock: clock.New()}
+	held := heldKillRights(notifications, s.boundary())
+
+	expected := map[uint64]bool{90000003: true, 90000004: true}
+	if !reflect.DeepEqual(held, expected) {
+		t.Errorf("expected %v, got %v", expected, held)
+	}
+
+}
+
+func TestHeldKillRightsExcludesUsedKillRights(t *testing.T) {
+
+	now := time.Now().UTC()
+	notifications := []*esi.NotificationOk{
+		{Type: killRightUsed, Timestamp: now.Add(-time.Hour), Text: "charID: 90000003\n"},
+		{Type: killRightEarned, Timestamp: now.Add(-2 * time.Hour), Text: "charID: 90000003\n"},
+		{Type: killRightEarned, Timestamp: now.Add(-2 * time.Hour), Text: "charID: 90000004\n"},
+		{Type: killRightUnavailable, Timestamp: now.Add(-time.Hour), Text: "charID: 90000004\n"},
+		// Earned again after the earlier kill right was used
+		{Type: killRightEarned, Timestamp: now.Add(-3 * time.Hour), Text: "charID: 90000005\n"},
+		{Type: killRightUsed, Timestamp: now.Add(-2 * time.Hour), Text: "charID: 90000005\n"},
+		{Type: killRightEarned, Timestamp: now, Text: "charID: 90000005\n"},
+		// Used kill right of a different character
+		{Type: killRightEarned, Timestamp: now.Add(-2 * time.Hour), Text: "charID: 90000006\n"},
+		{Type: killRightUsed, Timestamp: now, Text: "charID: 90000007\n"},
+	}
+
+	s := &Service{clock: clock.New()}
+	held := heldKillRights(notifications, s.boundary())
+
+	expected := map[uint64]bool{90000005: true, 90000006: true}
+	if !reflect.DeepEqual(held, expected) {
+		t.Errorf("expected %v, got %v", expected, held)
+	}
+
+}
+
+func TestReconcile(t *testing.T) {
+
+	report := &VictimReport{
+		VictimID: 90000001,
+		Aggressors: []*Aggressor{
+			{Character: &esi.CharacterOk{ID: 90000003, Name: "Confirmed"}},
+			{Character: &esi.CharacterOk{ID: 90000004, Name: "Missing"}},
+		},
+	}
+
+	reconciliation := reconcile(report, map[uint64]bool{90000003: true, 90000007: true, 90000006: true})
+
+	ids := func(characters []*esi.CharacterOk) []uint64 {
+		out := make([]uint64, 0, len(characters))
+		for _, character := range characters {
+			out = append(out, character.ID)
+		}
+		return out
+	}
+
+	if got := ids(reconciliation.Confirmed); !reflect.DeepEqual(got, []uint64{90000003}) {
+		t.Errorf("unexpected confirmed %v", got)
+	}
+	if got := ids(reconciliation.Missing); !reflect.DeepEqual(got, []uint64{90000004}) {
+		t.Errorf("unexpected missing %v", got)
+	}
+	if got := ids(reconciliation.Unexpected); !reflect.DeepEqual(got, []uint64{90000006, 90000007}) {
+		t.Errorf("unexpected unexpected %v", got)
+	}
+	if accuracy := reconciliation.Accuracy(); accuracy != 0.25 {
+		t.Errorf("expected accuracy of 0.25, got %v", accuracy)
+	}
+
+}
@@ -0,0 +1,785 @@
+package killright
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/internal/esi"
+	"github.com/eveisesi/krinder/internal/universe"
+	"github.com/eveisesi/krinder/internal/wars"
+	"github.com/eveisesi/krinder/internal/zkillboard"
+	"github.com/eveisesi/krinder/pkg/clock"
+	"github.com/eveisesi/krinder/pkg/errorcode"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// capsuleTypeID is the type id of a pod. Pods are the only ships that generate
+// kill rights when destroyed in low sec
+const capsuleTypeID = 670
+
+// Lookback is how many days back killmails are searched for kill rights
+const Lookback = 14
+
+// maxAsOf is how many days back a search may be evaluated as of. zKillboard is paged from the most
+// recent killmail, so every page between now and the moment of the search is fetched
+const maxAsOf = 90
+
+// maxPages caps the pages of zKillboard killmails fetched for a search
+const maxPages = 50
+
+// Progress receives human readable updates while a search runs
+type Progress func(message string)
+
+// TokenSource returns ESI access tokens for the characters chat users have linked
+type TokenSource interface {
+	Token(ctx context.Context, userID string, characterID uint64) (string, error)
+}
+
+type Service struct {
+	logger *logrus.Logger
+
+	zkb      *zkillboard.Service
+	esi      esi.API
+	wars     *wars.Service
+	universe *universe.Service
+	tokens   TokenSource
+	clock    clock.Clock
+}
+
+func New(logger *logrus.Logger, zkb *zkillboard.Service, esi esi.API, wars *wars.Service, universe *universe.Service, tokens TokenSource, clock clock.Clock) *Service {
+	return &Service{
+		logger:   logger,
+		zkb:      zkb,
+		esi:      esi,
+		wars:     wars,
+		universe: universe,
+		tokens:   tokens,
+		clock:    clock,
+	}
+}
+
+// AsOf returns a copy of the service that evaluates kill rights as they stood at t. Killmails
+// after t are ignored and the lookback ends at t rather than now
+func (s *Service) AsOf(t time.Time) *Service {
+	c := *s
+	c.clock = clock.Fixed(t)
+	return &c
+}
+
+// ParseAsOf parses the value of an --as-of option, either a date or an RFC3339 timestamp. A date
+// means the end of that day in UTC. Times after now are rejected, as their killmails are unknown,
+// and so are times more than maxAsOf days ago
+func ParseAsOf(value string, now time.Time) (time.Time, error) {
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		day, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return time.Time{}, errorcode.Newf(errorcode.InvalidArgument, "failed to parse as of %s, expected a date such as 2021-11-15 or an RFC3339 timestamp", value)
+		}
+		if day.After(now) {
+			return time.Time{}, errorcode.Newf(errorcode.InvalidArgument, "as of %s is in the future", value)
+		}
+
+		t = day.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		if t.After(now) {
+			// Today has not ended yet
+			t = now
+		}
+	}
+
+	if t.After(now) {
+		return time.Time{}, errorcode.Newf(errorcode.InvalidArgument, "as of %s is in the future", value)
+	}
+	if t.Before(now.AddDate(0, 0, -maxAsOf)) {
+		return time.Time{}, errorcode.Newf(errorcode.InvalidArgument, "as of %s is more than %d days ago", value, maxAsOf)
+	}
+
+	return t.UTC(), nil
+
+}
+
+// Aggressor is a character that a victim may hold a kill right on
+type Aggressor struct {
+	Character *esi.CharacterOk `json:"character"`
+	// Seen is the number of qualifying killmails the character is an attacker on
+	Seen int `json:"seen"`
+	// Killmails are the qualifying killmails, kept as the evidence of exports
+	Killmails []*esi.KillmailOk `json:"-"`
+}
+
+// AttackerReport lists the victims of a character that may hold a kill right on that character
+type AttackerReport struct {
+	Character *esi.CharacterOk `json:"character"`
+	// Killmails holds one qualifying killmail per victim
+	Killmails []*esi.KillmailOk `json:"killmails"`
+	// Warning is set when the report was built from incomplete war data
+	Warning string `json:"warning,omitempty"`
+}
+
+// VictimReport lists the characters a victim may hold a kill right on
+type VictimReport struct {
+	VictimID   uint64       `json:"victimID"`
+	Aggressors []*Aggressor `json:"aggressors"`
+	// Warning is set when the report was built from incomplete war data
+	Warning string `json:"warning,omitempty"`
+}
+
+type Ship struct {
+	Entity    *krinder.MongoEntity `json:"entity"`
+	Killmails []*esi.KillmailOk    `json:"killmails"`
+}
+
+// ShipVictim is a victim that lost the searched ship along with the characters they may hold kill rights on
+type ShipVictim struct {
+	Character  *esi.CharacterOk `json:"character"`
+	Aggressors []*Aggressor     `json:"aggressors"`
+}
+
+// ShipReport summarises the qualifying losses of a ship group. Victims is only populated
+// when a ship type is searched
+type ShipReport struct {
+	GroupID    uint64        `json:"groupID"`
+	ShipTypeID uint64        `json:"shipTypeID,omitempty"`
+	Killmails  int           `json:"killmails"`
+	Ships      []*Ship       `json:"ships,omitempty"`
+	Victims    []*ShipVictim `json:"victims,omitempty"`
+	// Warning is set when the report was built from incomplete war data
+	Warning string `json:"warning,omitempty"`
+}
+
+func notify(progress Progress, message string) {
+	if progress != nil {
+		progress(message)
+	}
+}
+
+// boundary returns the earliest time a killmail may have occurred to be considered
+func (s *Service) boundary() time.Time {
+	b := s.clock.Now().AddDate(0, 0, -Lookback)
+	return time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Attacker finds the victims of characterID that may hold a kill right on characterID. When requester,
+// a chat user id, has linked characterID, killmails that were never posted to zKillboard are included
+func (s *Service) Attacker(ctx context.Context, characterID uint64, requester string, progress Progress) (*AttackerReport, error) {
+
+	killmails, err := s.killmails(ctx, zkillboard.CharacterEntityType, characterID, zkillboard.KillsFetchType, requester, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	searchedCharacter, err := s.esi.Character(ctx, characterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch character from ESI")
+	}
+
+	notify(progress, "character found, checking for killmails")
+
+	report := &AttackerReport{
+		Character: searchedCharacter,
+		Warning:   s.wars.Warning(),
+	}
+
+	report.Killmails, err = s.victims(ctx, characterID, killmails, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+
+}
+
+// victims filters the kills of characterID down to one qualifying killmail per victim that may
+// hold a kill right on characterID
+func (s *Service) victims(ctx context.Context, characterID uint64, killmails []*esi.KillmailOk, progress Progress) ([]*esi.KillmailOk, error) {
+
+	victims := make([]*esi.KillmailOk, 0, len(killmails))
+
+	filteredKillmails := make([]*esi.KillmailOk, 0, len(killmails))
+	for _, killmail := range killmails {
+
+		var aggressor *esi.KillmailAttacker
+		for _, attacker := range killmail.Attackers {
+			if attacker.CharacterID == characterID {
+				aggressor = attacker
+				break
+			}
+		}
+
+		if aggressor == nil {
+			continue
+		}
+
+		// Structures don't have a character ID
+		if killmail.Victim.CharacterID == 0 {
+			continue
+		}
+
+		qualifies, err := s.qualifyingSystem(ctx, killmail)
+		if err != nil {
+			s.logger.WithContext(ctx).WithError(err).Error("failed to fetch killmail solar system from ESI")
+			continue
+		}
+		if !qualifies {
+			continue
+		}
+
+		victimCharacter, err := s.esi.Character(ctx, killmail.Victim.CharacterID)
+		if err != nil {
+			s.logger.WithContext(ctx).WithError(err).Error("failed to fetch character from ESI")
+			continue
+		}
+		killmail.Victim.Character = victimCharacter
+
+		atWar, err := s.atWar(ctx, killmail, aggressor)
+		if err != nil {
+			return nil, err
+		}
+
+		if atWar {
+			continue
+		}
+
+		filteredKillmails = append(filteredKillmails, killmail)
+
+	}
+
+	if len(filteredKillmails) == 0 {
+		return victims, nil
+	}
+
+	notify(progress, fmt.Sprintf("mails filtered down to %d killmails, filtering out duplicate victims....", len(filteredKillmails)))
+
+	seen := make(map[uint64]bool)
+	for _, killmail := range filteredKillmails {
+		if seen[killmail.Victim.CharacterID] {
+			continue
+		}
+		s.resolveShip(ctx, killmail)
+		victims = append(victims, killmail)
+		seen[killmail.Victim.CharacterID] = true
+	}
+
+	return victims, nil
+
+}
+
+// Victim finds the characters that characterID may hold a kill right on. When requester, a chat
+// user id, has linked characterID, killmails that were never posted to zKillboard are included
+func (s *Service) Victim(ctx context.Context, characterID uint64, requester string, progress Progress) (*VictimReport, error) {
+
+	killmails, err := s.killmails(ctx, zkillboard.CharacterEntityType, characterID, zkillboard.LossesFetchType, requester, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &VictimReport{
+		VictimID:   characterID,
+		Aggressors: make([]*Aggressor, 0),
+		Warning:    s.wars.Warning(),
+	}
+
+	entry := s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"victimID": characterID,
+		"service":  "killright",
+	})
+
+	mapAggressors := make(map[uint64]*Aggressor)
+	for _, killmail := range killmails {
+
+		var evidence bool
+
+		qualifies, err := s.qualifyingSystem(ctx, killmail)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to fetch killmail solar system from ESI")
+		}
+		if !qualifies {
+			entry.WithField("killmailID", killmail.KillmailID).Debug("skipping due to system security")
+			continue
+		}
+
+		for _, attacker := range uniqueAttackersByCorporation(killmail) {
+			atWar, err := s.atWar(ctx, killmail, attacker)
+			if err != nil {
+				return nil, err
+			}
+
+			if atWar {
+				entry.WithField("killmailID", killmail.KillmailID).Debug("skipping due to active war")
+				continue
+			}
+
+			// This corporation/alliance pair is not at war with the victim, so the victim
+			// has a kill right for each of the attackers that belong to the pair
+			for _, a := range killmail.Attackers {
+				if a.CorporationID != attacker.CorporationID || a.CharacterID == 0 {
+					continue
+				}
+				if _, ok := mapAggressors[a.CharacterID]; !ok {
+					mapAggressors[a.CharacterID] = &Aggressor{}
+				}
+				mapAggressors[a.CharacterID].Seen++
+				mapAggressors[a.CharacterID].Killmails = append(mapAggressors[a.CharacterID].Killmails, killmail)
+				evidence = true
+			}
+		}
+
+		if evidence {
+			s.resolveShip(ctx, killmail)
+		}
+	}
+
+	for characterID, aggressor := range mapAggressors {
+		character, err := s.esi.Character(ctx, characterID)
+		if err != nil {
+			entry.WithError(err).Error("failed to fetch character from ESI")
+			character = &esi.CharacterOk{ID: characterID}
+		}
+
+		aggressor.Character = character
+		report.Aggressors = append(report.Aggressors, aggressor)
+	}
+
+	sortAggressors(report.Aggressors)
+
+	return report, nil
+
+}
+
+// Ship summarises the qualifying losses of ships in groupID. When shipTypeID is not zero
+// the victims that lost that ship are resolved along with the characters they may hold kill rights on
+func (s *Service) Ship(ctx context.Context, groupID, shipTypeID uint64, progress Progress) (*ShipReport, error) {
+
+	killmails, err := s.killmails(ctx, zkillboard.GroupEntityType, groupID, zkillboard.LossesFetchType, "", progress)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ShipReport{
+		GroupID:    groupID,
+		ShipTypeID: shipTypeID,
+		Ships:      make([]*Ship, 0),
+		Warning:    s.wars.Warning(),
+	}
+
+	mapShips := make(map[uint]*Ship)
+	for _, killmail := range killmails {
+
+		if killmail.Victim.CharacterID == 0 {
+			continue
+		}
+
+		qualifies, err := s.qualifyingSystem(ctx, killmail)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to fetch killmail solar system from ESI")
+		}
+		if !qualifies {
+			continue
+		}
+
+		victimCharacter, err := s.esi.Character(ctx, killmail.Victim.CharacterID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to fetch killmail victim character from ESI")
+		}
+		killmail.Victim.Character = victimCharacter
+
+		if _, ok := mapShips[killmail.Victim.ShipTypeID]; !ok {
+			mapShips[killmail.Victim.ShipTypeID] = &Ship{
+				Killmails: make([]*esi.KillmailOk, 0),
+			}
+		}
+
+		mapShips[killmail.Victim.ShipTypeID].Killmails = append(mapShips[killmail.Victim.ShipTypeID].Killmails, killmail)
+		report.Killmails++
+	}
+
+	if report.Killmails == 0 {
+		return report, nil
+	}
+
+	notify(progress, fmt.Sprintf("%d killmails remain after filtering....", report.Killmails))
+
+	for entityID, ship := range mapShips {
+		ship.Entity, err = s.universe.Entity(ctx, entityID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to resolve entity id to entity")
+		}
+		for _, killmail := range ship.Killmails {
+			killmail.Victim.Ship = ship.Entity
+		}
+
+		report.Ships = append(report.Ships, ship)
+	}
+
+	sort.SliceStable(report.Ships, func(i, j int) bool {
+		if len(report.Ships[i].Killmails) == len(report.Ships[j].Killmails) {
+			return report.Ships[i].Entity.ID < report.Ships[j].Entity.ID
+		}
+		return len(report.Ships[i].Killmails) > len(report.Ships[j].Killmails)
+	})
+
+	if shipTypeID == 0 {
+		return report, nil
+	}
+
+	shipInfo := mapShips[uint(shipTypeID)]
+	if shipInfo == nil {
+		return nil, errorcode.Newf(errorcode.InvalidArgument, "unknown type id %d or type id is not member of the group %d", shipTypeID, groupID)
+	}
+
+	victims := make(map[uint64]*ShipVictim)
+	characters := make(map[uint64]*esi.CharacterOk)
+	for _, killmail := range shipInfo.Killmails {
+		victim, ok := victims[killmail.Victim.CharacterID]
+		if !ok {
+			victim = &ShipVictim{
+				Character:  killmail.Victim.Character,
+				Aggressors: make([]*Aggressor, 0),
+			}
+			victims[killmail.Victim.CharacterID] = victim
+			report.Victims = append(report.Victims, victim)
+		}
+
+		for _, attacker := range killmail.Attackers {
+			if attacker.CharacterID == 0 {
+				continue
+			}
+
+			var aggressor *Aggressor
+			for _, a := range victim.Aggressors {
+				if a.Character.ID == attacker.CharacterID {
+					aggressor = a
+					break
+				}
+			}
+
+			if aggressor == nil {
+				character, ok := characters[attacker.CharacterID]
+				if !ok {
+					character, err = s.esi.Character(ctx, attacker.CharacterID)
+					if err != nil {
+						return nil, errors.Wrapf(err, "failed to search for character %d, an attacker on killmail %d", attacker.CharacterID, killmail.KillmailID)
+					}
+					characters[attacker.CharacterID] = character
+				}
+				aggressor = &Aggressor{Character: character}
+				victim.Aggressors = append(victim.Aggressors, aggressor)
+			}
+			aggressor.Seen++
+			aggressor.Killmails = append(aggressor.Killmails, killmail)
+		}
+	}
+
+	for _, victim := range report.Victims {
+		sortAggressors(victim.Aggressors)
+	}
+
+	return report, nil
+
+}
+
+// killmails pages through zkillboard for the killmails of the entity, stopping once a page
+// reaches past the lookback boundary, and normalizes each killmail within the boundary with ESI.
+// The private killmails of characters linked by requester are merged in, see privateKillmails
+func (s *Service) killmails(ctx context.Context, entityType zkillboard.EntityType, id uint64, fetchType zkillboard.FetchType, requester string, progress Progress) ([]*esi.KillmailOk, error) {
+
+	now, boundary := s.clock.Now(), s.boundary()
+
+	entry := s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"entityType": entityType,
+		"id":         id,
+		"service":    "killright",
+	})
+
+	var zmails = make([]*zkillboard.Killmail, 0)
+	for page := uint(1); ; page++ {
+
+		if page > maxPages {
+			entry.WithField("pages", maxPages).Warn("stopped paging before reaching the time boundary")
+			break
+		}
+
+		iteration, err := s.zkb.Killmails(ctx, entityType, id, fetchType, page)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to fetch killmails")
+		}
+
+		if len(iteration) == 0 {
+			break
+		}
+
+		zmails = append(zmails, iteration...)
+
+		lastKill := iteration[len(iteration)-1]
+
+		killmail, err := s.esi.KillmailByIDHash(ctx, int64(lastKill.KillmailID), lastKill.Meta.Hash)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to fetch killmail from esi")
+		}
+
+		if killmail.KillmailTime.Before(boundary) {
+			// We have at least Lookback days worth of mails, maybe more
+			// Additional filtering will be done after we fetch each mail
+			entry.WithField("page", page).Debug("page reached the time boundary")
+			break
+		}
+
+	}
+
+	notify(progress, fmt.Sprintf("found %d killmails, normalizing with ESI....", len(zmails)))
+
+	// oldest is the id of a killmail that happened before the boundary. Killmail ids are handed out
+	// as kills happen, so any lower id is outside of the boundary as well
+	oldest := 0
+
+	killmails := make([]*esi.KillmailOk, 0, len(zmails))
+	for _, zmail := range zmails {
+		killmail, err := s.esi.KillmailByIDHash(ctx, int64(zmail.KillmailID), zmail.Meta.Hash)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to fetch killmail from ESI")
+		}
+
+		if killmail.KillmailTime.Before(boundary) {
+			entry.WithField("killmailID", killmail.KillmailID).Debug("outside time boundary, skipping")
+			oldest = killmail.KillmailID
+			break
+		}
+
+		if killmail.KillmailTime.After(now) {
+			// The search is evaluated as of a moment in the past, see AsOf
+			continue
+		}
+
+		killmails = append(killmails, killmail)
+	}
+
+	entry.WithField("killmails", len(killmails)).Debug("normalized killmails")
+
+	if s.tokens == nil || entityType != zkillboard.CharacterEntityType || requester == "" {
+		return killmails, nil
+	}
+
+	known := make(map[int]bool, len(zmails))
+	for _, zmail := range zmails {
+		known[int(zmail.KillmailID)] = true
+	}
+
+	private, err := s.privateKillmails(ctx, id, fetchType, requester, known, oldest)
+	if err != nil {
+		// The search still answers with the public killmails
+		entry.WithError(err).Warn("failed to fetch private killmails")
+		return killmails, nil
+	}
+
+	if len(private) > 0 {
+		notify(progress, fmt.Sprintf("found %d killmails that are not on zKillboard through your linked character", len(private)))
+	}
+
+	killmails = append(killmails, private...)
+	sort.Slice(killmails, func(i, j int) bool {
+		return killmails[i].KillmailTime.After(killmails[j].KillmailTime)
+	})
+
+	return killmails, nil
+
+}
+
+// privateKillmails returns the killmails of characterID within the lookback boundary that ESI knows
+// about and zKillboard did not return, when requester has linked characterID. known holds the ids
+// zKillboard returned and oldest, when not zero, the id of a killmail that happened before the
+// boundary. Only the kills or losses of the character are returned, according to fetchType
+func (s *Service) privateKillmails(ctx context.Context, characterID uint64, fetchType zkillboard.FetchType, requester string, known map[int]bool, oldest int) ([]*esi.KillmailOk, error) {
+
+	token, err := s.tokens.Token(ctx, requester, characterID)
+	if errorcode.Code(err) == errorcode.NotFound {
+		// requester has not linked characterID
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entry := s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"characterID": characterID,
+		"service":     "killright",
+	})
+
+	now, boundary := s.clock.Now(), s.boundary()
+	private := make([]*esi.KillmailOk, 0)
+	for page, pages := uint(1), uint(1); page <= pages; page++ {
+
+		recent, err := s.esi.CharacterKillmailsRecent(esi.WithAccessToken(ctx, token), characterID, page)
+		if err != nil {
+			return nil, err
+		}
+		pages = recent.Pages
+
+		for _, ref := range recent.Refs {
+			if known[ref.KillmailID] || (oldest > 0 && ref.KillmailID <= oldest) {
+				continue
+			}
+			known[ref.KillmailID] = true
+
+			killmail, err := s.esi.KillmailByIDHash(ctx, int64(ref.KillmailID), ref.KillmailHash)
+			if err != nil {
+				// A single killmail ESI cannot return should not hide the others
+				entry.WithError(err).WithField("killmailID", ref.KillmailID).Warn("failed to fetch private killmail")
+				continue
+			}
+
+			if killmail.KillmailTime.Before(boundary) {
+				if killmail.KillmailID > oldest {
+					oldest = killmail.KillmailID
+				}
+				continue
+			}
+
+			if killmail.KillmailTime.After(now) {
+				continue
+			}
+
+			if (fetchType == zkillboard.LossesFetchType) != (killmail.Victim.CharacterID == characterID) {
+				continue
+			}
+
+			private = append(private, killmail)
+		}
+
+		// The pages are ordered by id, so the following pages only hold killmails older than the boundary
+		if len(recent.Refs) > 0 && oldest > 0 && recent.Refs[len(recent.Refs)-1].KillmailID <= oldest {
+			break
+		}
+	}
+
+	return private, nil
+
+}
+
+// qualifyingSystem attaches the solar system to the killmail, unless an earlier call already has,
+// and reports whether a kill right could have been generated there. Kills in null sec never
+// generate kill rights and kills in low sec only do when the victim was in a pod
+func (s *Service) qualifyingSystem(ctx context.Context, killmail *esi.KillmailOk) (bool, error) {
+
+	system := killmail.SolarSystem
+	if system == nil {
+		var err error
+		system, err = s.esi.System(ctx, uint(killmail.SolarSystemID))
+		if err != nil {
+			return false, err
+		}
+		killmail.SolarSystem = system
+	}
+
+	if system.SecurityStatus < 0 {
+		return false, nil
+	}
+
+	if system.SecurityStatus < .5 && killmail.Victim.ShipTypeID != capsuleTypeID {
+		return false, nil
+	}
+
+	return true, nil
+
+}
+
+// atWar reports whether the attacker's corporation or alliance was at war with the
+// victim's corporation or alliance when the killmail occurred
+func (s *Service) atWar(ctx context.Context, killmail *esi.KillmailOk, attacker *esi.KillmailAttacker) (bool, error) {
+
+	victim := killmail.Victim
+
+	matrix := make([][]wars.Entity, 0, 4)
+	if victim.CorporationID > 0 && attacker.CorporationID > 0 {
+		matrix = append(matrix, []wars.Entity{
+			{T: "corporation", ID: victim.CorporationID},
+			{T: "corporation", ID: attacker.CorporationID},
+		})
+	}
+	if victim.AllianceID > 0 && attacker.AllianceID > 0 {
+		matrix = append(matrix, []wars.Entity{
+			{T: "alliance", ID: victim.AllianceID},
+			{T: "alliance", ID: attacker.AllianceID},
+		})
+	}
+	if victim.CorporationID > 0 && attacker.AllianceID > 0 {
+		matrix = append(matrix, []wars.Entity{
+			{T: "corporation", ID: victim.CorporationID},
+			{T: "alliance", ID: attacker.AllianceID},
+		})
+	}
+	if victim.AllianceID > 0 && attacker.CorporationID > 0 {
+		matrix = append(matrix, []wars.Entity{
+			{T: "alliance", ID: victim.AllianceID},
+			{T: "corporation", ID: attacker.CorporationID},
+		})
+	}
+
+	for _, pair := range matrix {
+		atWar, err := s.wars.EntitiesAtWar(ctx, pair[0], pair[1], killmail.KillmailTime)
+		if err != nil {
+			return false, errors.Wrap(err, "failed to determine if entities are at war")
+		}
+
+		if atWar {
+			return true, nil
+		}
+	}
+
+	return false, nil
+
+}
+
+// resolveShip sets the ship the victim of the killmail lost. Exports name the ship, but a report
+// is still useful without it, so failures are only logged
+func (s *Service) resolveShip(ctx context.Context, killmail *esi.KillmailOk) {
+
+	if killmail.Victim.Ship != nil {
+		return
+	}
+
+	ship, err := s.universe.Entity(ctx, killmail.Victim.ShipTypeID)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).WithFields(logrus.Fields{
+			"killmailID": killmail.KillmailID,
+			"shipTypeID": killmail.Victim.ShipTypeID,
+			"service":    "killright",
+		}).Warn("failed to resolve the ship of the victim")
+		return
+	}
+
+	killmail.Victim.Ship = ship
+
+}
+
+// uniqueAttackersByCorporation returns one attacker per corporation on the killmail. Since a
+// corporation cannot belong to multiple alliances at once, this gives one corporation/alliance
+// pair to check for wars against instead of querying for the same pair over and over
+func uniqueAttackersByCorporation(killmail *esi.KillmailOk) []*esi.KillmailAttacker {
+
+	seen := make(map[uint]bool)
+	attackers := make([]*esi.KillmailAttacker, 0, len(killmail.Attackers))
+	for _, attacker := range killmail.Attackers {
+		if seen[attacker.CorporationID] {
+			continue
+		}
+		seen[attacker.CorporationID] = true
+		attackers = append(attackers, attacker)
+	}
+
+	return attackers
+
+}
+
+func sortAggressors(aggressors []*Aggressor) {
+	sort.SliceStable(aggressors, func(i, j int) bool {
+		if aggressors[i].Seen == aggressors[j].Seen {
+			return aggressors[i].Character.Name < aggressors[j].Character.Name
+		}
+		return aggressors[i].Seen > aggressors[j].Seen
+	})
+}
@@ -0,0 +1,258 @@
+package killright
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/krinder/internal/esi"
+	"github.com/eveisesi/krinder/internal/zkillboard"
+	"github.com/eveisesi/krinder/pkg/clock"
+	"github.com/eveisesi/krinder/pkg/errorcode"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	victimID   uint64 = 90000001
+	attackerID uint64 = 90000002
+)
+
+type tokens map[string]string
+
+func (t tokens) Token(ctx context.Context, userID string, characterID uint64) (string, error) {
+	token, ok := t[fmt.Sprintf("%s:%d", userID, characterID)]
+	if !ok {
+		return "", errorcode.New(errorcode.NotFound, "not linked")
+	}
+	return token, nil
+}
+
+// fakeServices serves the killmails of victimID. Killmails 1 and 2 are public, 2 is also returned by the
+// recent killmails endpoint along with 3, a private loss, and 4, a private kill. 5 is a private loss
+// older than the lookback boundary
+func fakeServices(t *testing.T) (*zkillboard.Service, esi.API) {
+	t.Helper()
+
+	now := time.Now().UTC()
+	killmails := map[string]*esi.KillmailOk{
+		"/v1/killmails/1/a/": {KillmailID: 1, KillmailTime: now.Add(-time.Hour), Victim: &esi.KillmailVictim{CharacterID: victimID}},
+		"/v1/killmails/2/b/": {KillmailID: 2, KillmailTime: now.Add(-3 * time.Hour), Victim: &esi.KillmailVictim{CharacterID: victimID}},
+		"/v1/killmails/3/c/": {KillmailID: 3, KillmailTime: now.Add(-2 * time.Hour), Victim: &esi.KillmailVictim{CharacterID: victimID}},
+		"/v1/killmails/4/d/": {KillmailID: 4, KillmailTime: now.Add(-2 * time.Hour), Victim: &esi.KillmailVictim{CharacterID: attackerID}, Attackers: []*esi.KillmailAttacker{{CharacterID: victimID}}},
+		"/v1/killmails/5/e/": {KillmailID: 5, KillmailTime: now.AddDate(0, 0, -Lookback-2), Victim: &esi.KillmailVictim{CharacterID: victimID}},
+	}
+
+	write := func(w http.ResponseWriter, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	zkb := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case fmt.Sprintf("/characterID/%d/losses/npc/0/awox/0/page/1/", victimID):
+			write(w, []*zkillboard.Killmail{
+				{KillmailID: 1, Meta: &zkillboard.Meta{Hash: "a"}},
+				{KillmailID: 2, Meta: &zkillboard.Meta{Hash: "b"}},
+			})
+		default:
+			write(w, []*zkillboard.Killmail{})
+		}
+	}))
+	t.Cleanup(zkb.Close)
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if killmail, ok := killmails[r.URL.Path]; ok {
+			write(w, killmail)
+			return
+		}
+
+		if r.URL.Path == fmt.Sprintf("/v1/characters/%d/killmails/recent/", victimID) {
+			if r.Header.Get("Authorization") != "Bearer token" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			write(w, []*esi.KillmailRefOk{
+				{KillmailID: 2, KillmailHash: "b"},
+				{KillmailID: 3, KillmailHash: "c"},
+				{KillmailID: 4, KillmailHash: "d"},
+				{KillmailID: 5, KillmailHash: "e"},
+			})
+			return
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(api.Close)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	return zkillboard.New(logger, "krinder-test", zkillboard.WithBaseURL(zkb.URL)), esi.New(logger, "krinder-test", nil, esi.WithBaseURL(api.URL))
+}
+
+func TestKillmailsMergesPrivateKillmails(t *testing.T) {
+
+	tests := map[string]struct {
+		requester string
+		expected  []int
+	}{
+		"linked":     {requester: "user", expected: []int{1, 3, 2}},
+		"not linked": {requester: "someone", expected: []int{1, 2}},
+		"anonymous":  {requester: "", expected: []int{1, 2}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+
+			zkb, api := fakeServices(t)
+			logger := logrus.New()
+			logger.SetOutput(io.Discard)
+
+			s := New(logger, zkb, api, nil, nil, tokens{fmt.Sprintf("user:%d", victimID): "token"}, clock.New())
+
+			killmails, err := s.killmails(context.Background(), zkillboard.CharacterEntityType, victimID, zkillboard.LossesFetchType, test.requester, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			ids := make([]int, 0, len(killmails))
+			for _, killmail := range killmails {
+				ids = append(ids, killmail.KillmailID)
+			}
+
+			if fmt.Sprint(ids) != fmt.Sprint(test.expected) {
+				t.Errorf("expected killmails %v, got %v", test.expected, ids)
+			}
+
+		})
+	}
+
+}
+
+func TestPrivateKillmailsStopAtBoundary(t *testing.T) {
+
+	now := time.Now().UTC()
+	killmails := map[string]*esi.KillmailOk{
+		"/v1/killmails/12/l/": {KillmailID: 12, KillmailTime: now.Add(-time.Hour), Victim: &esi.KillmailVictim{CharacterID: victimID}},
+		"/v1/killmails/10/j/": {KillmailID: 10, KillmailTime: now.Add(-2 * time.Hour), Victim: &esi.KillmailVictim{CharacterID: victimID}},
+		"/v1/killmails/9/i/":  {KillmailID: 9, KillmailTime: now.AddDate(0, 0, -Lookback-1), Victim: &esi.KillmailVictim{CharacterID: victimID}},
+	}
+
+	write := func(w http.ResponseWriter, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	requests := make(map[string]int)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.RequestURI()]++
+		if killmail, ok := killmails[r.URL.Path]; ok {
+			write(w, killmail)
+			return
+		}
+
+		if r.URL.Path == fmt.Sprintf("/v1/characters/%d/killmails/recent/", victimID) {
+			w.Header().Set("X-Pages", "2")
+			switch r.URL.Query().Get("page") {
+			case "1":
+				write(w, []*esi.KillmailRefOk{
+					{KillmailID: 12, KillmailHash: "l"},
+					{KillmailID: 11, KillmailHash: "k"},
+					{KillmailID: 10, KillmailHash: "j"},
+					{KillmailID: 9, KillmailHash: "i"},
+				})
+			default:
+				write(w, []*esi.KillmailRefOk{{KillmailID: 8, KillmailHash: "h"}})
+			}
+			return
+		}
+
+		// 11 cannot be fetched
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(api.Close)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	s := New(logger, nil, esi.New(logger, "krinder-test", nil, esi.WithBaseURL(api.URL)), nil, nil, tokens{fmt.Sprintf("user:%d", victimID): "token"}, clock.New())
+
+	private, err := s.privateKillmails(context.Background(), victimID, zkillboard.LossesFetchType, "user", map[int]bool{12: true}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(private) != 1 || private[0].KillmailID != 10 {
+		t.Errorf("expected killmail 10, got %v", private)
+	}
+	if requests["/v1/killmails/12/l/"] != 0 {
+		t.Errorf("expected the killmail zKillboard returned not to be fetched")
+	}
+	if requests["/v1/killmails/11/k/"] == 0 {
+		t.Errorf("expected killmail 11 to be fetched")
+	}
+	if requests[fmt.Sprintf("/v1/characters/%d/killmails/recent/?page=2", victimID)] != 0 {
+		t.Errorf("expected the page past the boundary not to be fetched")
+	}
+
+}
+
+func TestQualifyingSystemReusesAttachedSystem(t *testing.T) {
+
+	// Without an ESI client, any fetch of the system would panic
+	s := &Service{}
+
+	killmail := &esi.KillmailOk{
+		SolarSystemID: 30000142,
+		SolarSystem:   &esi.SystemOk{ID: 30000142, SecurityStatus: .9},
+		Victim:        &esi.KillmailVictim{ShipTypeID: 587},
+	}
+
+	qualifies, err := s.qualifyingSystem(context.Background(), killmail)
+	if err != nil || !qualifies {
+		t.Errorf("expected the attached high sec system to qualify, got %t and %v", qualifies, err)
+	}
+
+}
+
+func TestParseAsOf(t *testing.T) {
+
+	now := time.Date(2021, 11, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		value    string
+		expected time.Time
+		invalid  bool
+	}{
+		"date is the end of the day": {value: "2021-11-10", expected: time.Date(2021, 11, 11, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond)},
+		"today is now":               {value: "2021-11-15", expected: now},
+		"timestamp":                  {value: "2021-11-10T18:00:00+02:00", expected: time.Date(2021, 11, 10, 16, 0, 0, 0, time.UTC)},
+		"future date":                {value: "2021-11-16", invalid: true},
+		"future timestamp":           {value: "2021-11-15T12:00:01Z", invalid: true},
+		"unparsable":                 {value: "last tuesday", invalid: true},
+		"oldest date":                {value: "2021-08-17", expected: time.Date(2021, 8, 18, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond)},
+		"too old date":               {value: "2021-08-16", invalid: true},
+		"too old timestamp":          {value: "2021-08-17T11:59:59Z", invalid: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			asOf, err := ParseAsOf(test.value, now)
+			if test.invalid {
+				if errorcode.Code(err) != errorcode.InvalidArgument {
+					t.Errorf("expected an invalid argument, got %v and %s", err, asOf)
+				}
+				return
+			}
+			if err != nil || !asOf.Equal(test.expected) {
+				t.Errorf("expected %s, got %s and %v", test.expected, asOf, err)
+			}
+		})
+	}
+
+}
@@ -0,0 +1,149 @@
+// Package metrics holds the Prometheus collectors shared by the krinder services.
+// Collectors are registered with the default registry, which is served by the admin server
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "krinder"
+
+var (
+	commandsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "commands_total",
+		Help:      "Number of commands handled, partitioned by command and status",
+	}, []string{"command", "status"})
+
+	commandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "command_duration_seconds",
+		Help:      "Time taken to handle a command, partitioned by command",
+		Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"command"})
+
+	apiRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_requests_total",
+		Help:      "Number of requests served by the REST API, partitioned by route and status code",
+	}, []string{"route", "code"})
+
+	apiRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Time taken to serve a request to the REST API, partitioned by route",
+		Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"route"})
+
+	upstreamRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_requests_total",
+		Help:      "Number of requests made to upstream APIs, partitioned by upstream, method and status code",
+	}, []string{"upstream", "method", "code"})
+
+	cacheRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Number of response cache lookups, partitioned by cache and result",
+	}, []string{"cache", "result"})
+
+	jobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "Time taken by scheduled jobs, partitioned by job and status",
+		Buckets:   []float64{1, 10, 30, 60, 300, 600, 1800, 3600, 7200},
+	}, []string{"job", "status"})
+
+	jobLastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "job_last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful run of a scheduled job",
+	}, []string{"job"})
+
+	killRightsReconciled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kill_rights_reconciled_total",
+		Help:      "Number of kill rights compared between the victim search and those held in game, partitioned by outcome",
+	}, []string{"outcome"})
+
+	cacheHits, cacheMisses uint64
+
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cache_hit_ratio",
+		Help:      "Ratio of response cache lookups that were hits since the process started",
+	}, func() float64 {
+		hits, misses := atomic.LoadUint64(&cacheHits), atomic.LoadUint64(&cacheMisses)
+		if hits+misses == 0 {
+			return 0
+		}
+		return float64(hits) / float64(hits+misses)
+	})
+)
+
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+func status(err error) string {
+	if err != nil {
+		return StatusError
+	}
+	return StatusOK
+}
+
+// ObserveCommand records a handled command along with how long it took
+func ObserveCommand(command string, start time.Time, err error) {
+	commandsTotal.WithLabelValues(command, status(err)).Inc()
+	commandDuration.WithLabelValues(command).Observe(time.Since(start).Seconds())
+}
+
+// ObserveRequest records a request served by the REST API along with how long it took
+func ObserveRequest(route string, code int, start time.Time) {
+	apiRequestsTotal.WithLabelValues(route, strconv.Itoa(code)).Inc()
+	apiRequestDuration.WithLabelValues(route).Observe(time.Since(start).Seconds())
+}
+
+// ObserveCache records the result of a response cache lookup
+func ObserveCache(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+		atomic.AddUint64(&cacheHits, 1)
+	} else {
+		atomic.AddUint64(&cacheMisses, 1)
+	}
+	cacheRequestsTotal.WithLabelValues(cache, result).Inc()
+}
+
+// ObserveJob records the run of a scheduled job
+func ObserveJob(job string, start time.Time, err error) {
+	jobDuration.WithLabelValues(job, status(err)).Observe(time.Since(start).Seconds())
+	if err == nil {
+		jobLastSuccess.WithLabelValues(job).SetToCurrentTime()
+	}
+}
+
+// InstrumentRoundTripper counts the responses of requests made through next under the provided upstream
+func InstrumentRoundTripper(upstream string, next http.RoundTripper) http.RoundTripper {
+	return promhttp.InstrumentRoundTripperCounter(
+		upstreamRequestsTotal.MustCurryWith(prometheus.Labels{"upstream": upstream}),
+		next,
+	)
+}
+
+// ObserveReconciliation records the outcome of comparing the kill rights a victim search
+// predicted with those the character holds in game
+func ObserveReconciliation(confirmed, missing, unexpected int) {
+	killRightsReconciled.WithLabelValues("confirmed").Add(float64(confirmed))
+	killRightsReconciled.WithLabelValues("missing").Add(float64(missing))
+	killRightsReconciled.WithLabelValues("unexpected").Add(float64(unexpected))
+}
@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/eveisesi/krinder/internal/chat"
+)
+
+// ChannelSink posts notifications as embeds to a channel of a chat transport
+type ChannelSink struct {
+	name      string
+	transport chat.Transport
+	channelID string
+}
+
+var _ Sink = new(ChannelSink)
+
+// NewChannelSink returns a sink posting to channelID. name prefixes the channel id in the
+// name of the sink, e.g. discord
+func NewChannelSink(name string, transport chat.Transport, channelID string) *ChannelSink {
+	return &ChannelSink{
+		name:      name,
+		transport: transport,
+		channelID: channelID,
+	}
+}
+
+func (s *ChannelSink) Name() string {
+	return s.name + ":" + s.channelID
+}
+
+func (s *ChannelSink) Send(ctx context.Context, n *Notification) error {
+
+	embed := &chat.Embed{
+		Title:       n.Title,
+		Description: n.Body,
+		URL:         n.URL,
+		Fields:      make([]*chat.EmbedField, 0, len(n.Fields)),
+		Footer:      n.Kind,
+	}
+	for _, field := range n.Fields {
+		embed.Fields = append(embed.Fields, &chat.EmbedField{
+			Name:   field.Name,
+			Value:  field.Value,
+			Inline: true,
+		})
+	}
+
+	_, err := s.transport.Embed(ctx, &chat.Message{ChannelID: s.channelID}, embed)
+	return err
+
+}
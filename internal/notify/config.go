@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/eveisesi/krinder/internal/chat"
+	"github.com/pkg/errors"
+)
+
+// Config names the webhooks notifications can be sent to and routes each kind of notification
+// to its destinations, for example
+//
+//	{
+//		"webhooks": {"dashboard": {"url": "https://dashboard.example/hooks/krinder", "secret": "${DASHBOARD_SECRET}"}},
+//		"routes": {"war.declared": ["discord:123456789", "webhook:dashboard"]}
+//	}
+type Config struct {
+	Webhooks map[string]*WebhookConfig `json:"webhooks"`
+	// Routes maps a kind to destinations of the form <transport>:<channel id> or webhook:<name>
+	Routes map[string][]string `json:"routes"`
+}
+
+type WebhookConfig struct {
+	URL string `json:"url"`
+	// Secret signs requests when it is not empty. Environment variables are expanded,
+	// so that the secret does not have to be stored in the file
+	Secret string `json:"secret"`
+}
+
+// LoadConfig reads the JSON config at path
+func LoadConfig(path string) (*Config, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read notify config")
+	}
+
+	var cfg = new(Config)
+	err = json.Unmarshal(data, cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse notify config")
+	}
+
+	return cfg, nil
+
+}
+
+// Apply adds the routes of the config to router. Channel destinations are posted to through the
+// transport registered under their prefix, destinations of transports that are not available to
+// the running command are skipped
+func (c *Config) Apply(router *Router, transports map[string]chat.Transport, userAgent string) error {
+
+	webhooks := make(map[string]Sink, len(c.Webhooks))
+	for name, webhook := range c.Webhooks {
+		if webhook.URL == "" {
+			return errors.Errorf("webhook %s is missing a url", name)
+		}
+		webhooks[name] = NewWebhookSink(name, webhook.URL, os.ExpandEnv(webhook.Secret), userAgent)
+	}
+
+	for kind, destinations := range c.Routes {
+		for _, destination := range destinations {
+			parts := strings.SplitN(destination, ":", 2)
+			if len(parts) != 2 || parts[1] == "" {
+				return errors.Errorf("invalid destination %q for %s, expected <transport>:<channel id> or webhook:<name>", destination, kind)
+			}
+
+			if parts[0] == "webhook" {
+				sink, ok := webhooks[parts[1]]
+				if !ok {
+					return errors.Errorf("unknown webhook %s routed for %s", parts[1], kind)
+				}
+				router.Route(kind, sink)
+				continue
+			}
+
+			transport, ok := transports[parts[0]]
+			if !ok {
+				router.logger.WithField("service", "notify").WithField("destination", destination).Debug("transport unavailable, skipping destination")
+				continue
+			}
+			router.Route(kind, NewChannelSink(parts[0], transport, parts[1]))
+		}
+	}
+
+	return nil
+
+}
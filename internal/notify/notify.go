@@ -0,0 +1,44 @@
+// Package notify delivers notifications, such as wars being declared, to the destinations
+// configured for them: Discord channels and generic JSON webhooks
+package notify
+
+import (
+	"context"
+	"time"
+)
+
+// Kinds of notification that can be routed. Routes may name any kind, these are the
+// kinds krinder sends today. Watch alerts and scheduled reports are not part of krinder yet,
+// so they have no kind; once they exist they raise kinds of their own and are routed through
+// NOTIFY_CONFIG like the war events
+const (
+	KindWarDeclared = "war.declared"
+	KindWarFinished = "war.finished"
+)
+
+type Notification struct {
+	Kind   string   `json:"kind"`
+	Title  string   `json:"title"`
+	Body   string   `json:"body,omitempty"`
+	URL    string   `json:"url,omitempty"`
+	Fields []*Field `json:"fields,omitempty"`
+	// Time is when the event being notified about happened
+	Time time.Time `json:"time"`
+}
+
+type Field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Sink delivers notifications to a single destination
+type Sink interface {
+	// Name identifies the sink in logs and dead letters, e.g. webhook:dashboard
+	Name() string
+	Send(ctx context.Context, n *Notification) error
+}
+
+// Notifier is implemented by Router and accepted by the services that raise notifications
+type Notifier interface {
+	Notify(ctx context.Context, n *Notification)
+}
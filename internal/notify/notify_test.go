@@ -0,0 +1,234 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/krinder/internal/store/memory"
+	"github.com/eveisesi/krinder/pkg/errorcode"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestRouter() (*Router, *memory.DeadLetterRepository) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	deadLetters := memory.NewDeadLetterRepository()
+	router := NewRouter(logger, deadLetters)
+	router.backoff = 0
+
+	return router, deadLetters
+}
+
+func TestWebhookSinkSignsPayload(t *testing.T) {
+
+	var received webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		expected := Sign("secret", r.Header.Get(TimestampHeader), body)
+		if r.Header.Get(SignatureHeader) != expected {
+			t.Errorf("expected signature %s, got %s", expected, r.Header.Get(SignatureHeader))
+		}
+
+		received.Notification = new(Notification)
+		_ = json.Unmarshal(body, &received)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink("dashboard", server.URL, "secret", "krinder-test")
+	err := sink.Send(context.Background(), &Notification{Kind: KindWarDeclared, Title: "War 1 declared", Body: "Aggressor declared war on Defender"})
+	if err != nil {
+		t.Fatalf("failed to send notification: %s", err)
+	}
+
+	if received.Kind != KindWarDeclared || received.Title != "War 1 declared" {
+		t.Errorf("unexpected payload %+v", received.Notification)
+	}
+	if received.Text != "War 1 declared\nAggressor declared war on Defender" {
+		t.Errorf("unexpected text %q", received.Text)
+	}
+
+}
+
+func TestWebhookSinkCodesStatus(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	err := NewWebhookSink("dashboard", server.URL, "", "krinder-test").Send(context.Background(), &Notification{Kind: KindWarDeclared})
+	if errorcode.Code(err) != errorcode.UpstreamThrottled {
+		t.Errorf("expected %s, got %s (%v)", errorcode.UpstreamThrottled, errorcode.Code(err), err)
+	}
+
+}
+
+func TestRouterRetries(t *testing.T) {
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	router, deadLetters := newTestRouter()
+	router.Route(KindWarDeclared, NewWebhookSink("dashboard", server.URL, "", "krinder-test"))
+
+	router.Notify(context.Background(), &Notification{Kind: KindWarDeclared})
+
+	if requests != 3 {
+		t.Errorf("expected 3 requests, got %d", requests)
+	}
+
+	letters, _ := deadLetters.DeadLetters(context.Background())
+	if len(letters) != 0 {
+		t.Errorf("expected no dead letters, got %d", len(letters))
+	}
+
+}
+
+func TestRouterDeadLetters(t *testing.T) {
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	router, deadLetters := newTestRouter()
+	router.Route(KindWarFinished,
+		NewWebhookSink("down", server.URL, "", "krinder-test"),
+		NewWebhookSink("gone", server.URL+"/gone", "", "krinder-test"),
+	)
+
+	// Kinds without a route are dropped
+	err := router.Deliver(context.Background(), &Notification{Kind: KindWarDeclared})
+	if errorcode.Code(err) != errorcode.NotFound {
+		t.Errorf("expected NotFound for a kind without a route, got %v", err)
+	}
+
+	err = router.Deliver(context.Background(), &Notification{Kind: KindWarFinished, Title: "War 1 finished"})
+	if err == nil || !strings.Contains(err.Error(), "webhook:down") || !strings.Contains(err.Error(), "webhook:gone") {
+		t.Errorf("expected an error naming both sinks, got %v", err)
+	}
+
+	// Every attempt against the failing webhook, but a single attempt against the missing one
+	if requests != 4 {
+		t.Errorf("expected 4 requests, got %d", requests)
+	}
+
+	letters, err := deadLetters.DeadLetters(context.Background())
+	if err != nil {
+		t.Fatalf("failed to fetch dead letters: %s", err)
+	}
+	if len(letters) != 2 {
+		t.Fatalf("expected 2 dead letters, got %d", len(letters))
+	}
+
+	attempts := map[string]int{}
+	for _, letter := range letters {
+		attempts[letter.Sink] = letter.Attempts
+
+		var n Notification
+		if err := json.Unmarshal([]byte(letter.Payload), &n); err != nil || n.Title != "War 1 finished" {
+			t.Errorf("unexpected payload %s", letter.Payload)
+		}
+	}
+	if attempts["webhook:down"] != 3 || attempts["webhook:gone"] != 1 {
+		t.Errorf("unexpected attempts %v", attempts)
+	}
+
+}
+
+func TestConfigApply(t *testing.T) {
+
+	router, _ := newTestRouter()
+
+	cfg := &Config{
+		Webhooks: map[string]*WebhookConfig{"dashboard": {URL: "http://localhost/hook"}},
+		Routes: map[string][]string{
+			KindWarDeclared: {"webhook:dashboard", "discord:123"},
+		},
+	}
+
+	// discord is not available, so only the webhook is routed
+	err := cfg.Apply(router, nil, "krinder-test")
+	if err != nil {
+		t.Fatalf("failed to apply config: %s", err)
+	}
+	if len(router.routes[KindWarDeclared]) != 1 || router.routes[KindWarDeclared][0].Name() != "webhook:dashboard" {
+		t.Errorf("unexpected routes %v", router.routes)
+	}
+
+	cfg.Routes[KindWarFinished] = []string{"webhook:slack"}
+	if err := cfg.Apply(router, nil, "krinder-test"); err == nil {
+		t.Errorf("expected an error for an unknown webhook")
+	}
+
+}
+
+func TestRouterRunDeliversInBackground(t *testing.T) {
+
+	release := make(chan struct{})
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		atomic.AddInt32(&requests, 1)
+	}))
+	defer server.Close()
+
+	router, _ := newTestRouter()
+	router.Route(KindWarDeclared, NewWebhookSink("slow", server.URL, "", "krinder-test"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		router.Run(ctx, context.Background())
+	}()
+
+	// Wait for Run to take over delivery
+	for {
+		router.queueMu.RLock()
+		running := router.running
+		router.queueMu.RUnlock()
+		if running {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	start := time.Now()
+	router.Notify(context.Background(), &Notification{Kind: KindWarDeclared, Title: "War 1 declared"})
+	router.Notify(context.Background(), &Notification{Kind: KindWarDeclared, Title: "War 2 declared"})
+	if time.Since(start) > time.Millisecond*100 {
+		t.Errorf("expected Notify not to wait for the slow sink, took %s", time.Since(start))
+	}
+
+	// Notifications still queued when Run is stopped are delivered before it returns
+	cancel()
+	close(release)
+	<-stopped
+
+	if requests != 2 {
+		t.Errorf("expected both notifications to be delivered, got %d requests", requests)
+	}
+
+}
@@ -0,0 +1,215 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/pkg/errorcode"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// queueSize bounds the notifications waiting to be delivered while the router runs
+const queueSize = 100
+
+var errQueueFull = errors.New("notification queue is full")
+
+// Router delivers notifications to the sinks routed for their kind. Deliveries that fail with
+// a transient error are retried with an exponential backoff, and notifications that still could
+// not be delivered are stored as dead letters
+type Router struct {
+	logger      *logrus.Logger
+	deadLetters krinder.DeadLetterRepository
+
+	mu     sync.RWMutex
+	routes map[string][]Sink
+
+	// running is set while Run delivers the queue in the background
+	queueMu sync.RWMutex
+	running bool
+	queue   chan *Notification
+
+	attempts int
+	backoff  time.Duration
+}
+
+var _ Notifier = new(Router)
+
+func NewRouter(logger *logrus.Logger, deadLetters krinder.DeadLetterRepository) *Router {
+	return &Router{
+		logger:      logger,
+		deadLetters: deadLetters,
+		routes:      make(map[string][]Sink),
+		queue:       make(chan *Notification, queueSize),
+		attempts:    3,
+		backoff:     time.Second * 2,
+	}
+}
+
+// Route adds sinks to the destinations of notifications of kind
+func (r *Router) Route(kind string, sinks ...Sink) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes[kind] = append(r.routes[kind], sinks...)
+}
+
+// Notify delivers n to every sink routed for its kind. While Run is running n is queued and
+// delivered in the background, so that a slow sink does not hold up the caller, and dead lettered
+// when the queue is full. Otherwise Notify returns once every delivery has either succeeded or been
+// stored as a dead letter. Notifications of kinds without a route are dropped
+func (r *Router) Notify(ctx context.Context, n *Notification) {
+
+	if n.Time.IsZero() {
+		n.Time = time.Now().UTC()
+	}
+
+	r.queueMu.RLock()
+	defer r.queueMu.RUnlock()
+
+	if !r.running {
+		// Failed deliveries have been logged and dead lettered by Deliver
+		_ = r.Deliver(ctx, n)
+		return
+	}
+
+	select {
+	case r.queue <- n:
+	default:
+		r.mu.RLock()
+		sinks := r.routes[n.Kind]
+		r.mu.RUnlock()
+
+		r.logger.WithContext(ctx).WithFields(logrus.Fields{
+			"service": "notify",
+			"kind":    n.Kind,
+		}).Error("notification queue is full, dead lettering notification")
+
+		for _, sink := range sinks {
+			r.deadLetter(sink, n, 0, errQueueFull)
+		}
+	}
+
+}
+
+// Run delivers queued notifications until ctx is cancelled. The notifications still queued then
+// are delivered with work, and later ones are delivered by Notify itself
+func (r *Router) Run(ctx, work context.Context) {
+
+	r.queueMu.Lock()
+	r.running = true
+	r.queueMu.Unlock()
+
+	for {
+		select {
+		case n := <-r.queue:
+			_ = r.Deliver(work, n)
+		case <-ctx.Done():
+			r.queueMu.Lock()
+			r.running = false
+			r.queueMu.Unlock()
+
+			for {
+				select {
+				case n := <-r.queue:
+					_ = r.Deliver(work, n)
+				default:
+					return
+				}
+			}
+		}
+	}
+
+}
+
+// Deliver is Notify for callers that need to know about the outcome. It returns NotFound when no
+// sink is routed for the kind of n, or an error naming every sink that could not be delivered to
+func (r *Router) Deliver(ctx context.Context, n *Notification) error {
+
+	r.mu.RLock()
+	sinks := r.routes[n.Kind]
+	r.mu.RUnlock()
+
+	if len(sinks) == 0 {
+		return errorcode.Newf(errorcode.NotFound, "no sink is routed for notifications of kind %s", n.Kind)
+	}
+
+	if n.Time.IsZero() {
+		n.Time = time.Now().UTC()
+	}
+
+	failed := make([]string, 0)
+	for _, sink := range sinks {
+		attempts, err := r.send(ctx, sink, n)
+		if err == nil {
+			continue
+		}
+
+		r.logger.WithContext(ctx).WithError(err).WithFields(logrus.Fields{
+			"service":  "notify",
+			"sink":     sink.Name(),
+			"kind":     n.Kind,
+			"attempts": attempts,
+		}).Error("failed to deliver notification")
+
+		r.deadLetter(sink, n, attempts, err)
+		failed = append(failed, fmt.Sprintf("%s: %s", sink.Name(), err))
+	}
+
+	if len(failed) > 0 {
+		return errors.Errorf("failed to deliver notification to %d of %d sinks (%s)", len(failed), len(sinks), strings.Join(failed, "; "))
+	}
+
+	return nil
+
+}
+
+func (r *Router) send(ctx context.Context, sink Sink, n *Notification) (int, error) {
+
+	backoff := r.backoff
+	for attempt := 1; ; attempt++ {
+		err := sink.Send(ctx, n)
+		if err == nil || attempt >= r.attempts || !errorcode.Transient(err) {
+			return attempt, err
+		}
+
+		r.logger.WithContext(ctx).WithError(err).WithFields(logrus.Fields{
+			"service": "notify",
+			"sink":    sink.Name(),
+			"attempt": attempt,
+		}).Warn("retrying notification")
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return attempt, err
+		}
+		backoff *= 2
+	}
+
+}
+
+func (r *Router) deadLetter(sink Sink, n *Notification, attempts int, err error) {
+
+	payload, _ := json.Marshal(n)
+
+	// The notification is kept even when the context it was sent with has been cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	storeErr := r.deadLetters.CreateDeadLetter(ctx, &krinder.DeadLetter{
+		Sink:     sink.Name(),
+		Kind:     n.Kind,
+		Payload:  string(payload),
+		Error:    err.Error(),
+		Attempts: attempts,
+	})
+	if storeErr != nil {
+		r.logger.WithError(storeErr).WithField("service", "notify").Error("failed to store dead letter")
+	}
+
+}
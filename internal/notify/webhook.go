@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/eveisesi/krinder/pkg/errorcode"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SignatureHeader carries the hex encoded HMAC-SHA256 of the timestamp and body, see Sign
+	SignatureHeader = "X-Krinder-Signature"
+	// TimestampHeader carries the unix time the request was signed at, so that receivers can reject replays
+	TimestampHeader = "X-Krinder-Timestamp"
+)
+
+// WebhookSink posts notifications as JSON to a URL. The payload is the notification with an
+// additional text property, which Slack compatible receivers display as the message
+type WebhookSink struct {
+	name      string
+	url       string
+	secret    string
+	userAgent string
+
+	client *http.Client
+}
+
+var _ Sink = new(WebhookSink)
+
+// NewWebhookSink returns a sink posting to url. Requests are signed when secret is not empty
+func NewWebhookSink(name, url, secret, userAgent string) *WebhookSink {
+	return &WebhookSink{
+		name:      name,
+		url:       url,
+		secret:    secret,
+		userAgent: userAgent,
+		client: &http.Client{
+			Timeout: time.Second * 10,
+		},
+	}
+}
+
+type webhookPayload struct {
+	*Notification
+	Text string `json:"text"`
+}
+
+func (s *WebhookSink) Name() string {
+	return "webhook:" + s.name
+}
+
+func (s *WebhookSink) Send(ctx context.Context, n *Notification) error {
+
+	text := n.Title
+	if n.Body != "" {
+		text = fmt.Sprintf("%s\n%s", n.Title, n.Body)
+	}
+
+	body, err := json.Marshal(webhookPayload{Notification: n, Text: text})
+	if err != nil {
+		return errors.Wrap(err, "failed to encode notification")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "failed to build request")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", s.userAgent)
+	if s.secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, Sign(s.secret, timestamp, body))
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		code := errorcode.UpstreamUnavailable
+		if errorcode.Code(err) == errorcode.Timeout {
+			code = errorcode.Timeout
+		}
+		return errorcode.Wrap(err, code, "webhook could not be reached")
+	}
+	defer res.Body.Close()
+
+	// Drain the body so that the connection can be reused
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+
+	if res.StatusCode >= http.StatusMultipleChoices {
+		return errorcode.New(errorcode.FromHTTPStatus(res.StatusCode), fmt.Sprintf("webhook responded with %d %s", res.StatusCode, http.StatusText(res.StatusCode)))
+	}
+
+	return nil
+
+}
+
+// Sign returns the signature of a webhook request, sha256= followed by the hex encoded
+// HMAC-SHA256 of the timestamp, a period and the body, keyed with secret
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(timestamp))
+	_, _ = mac.Write([]byte("."))
+	_, _ = mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,156 @@
+// Package repl serves the bot on an interactive terminal, so that commands can be
+// driven locally against real or fake backends
+package repl
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eveisesi/krinder/internal/chat"
+)
+
+const (
+	prompt = "> "
+
+	channelID = "terminal"
+	authorID  = "terminal"
+)
+
+// Transport reads commands from in, one per line, and writes replies to out
+type Transport struct {
+	in  io.Reader
+	out io.Writer
+
+	// mu serializes writes to out and guards the message ids
+	mu     sync.Mutex
+	nextID int
+
+	done chan struct{}
+}
+
+var _ chat.Transport = new(Transport)
+
+func New(in io.Reader, out io.Writer) *Transport {
+	return &Transport{
+		in:   in,
+		out:  out,
+		done: make(chan struct{}),
+	}
+}
+
+// Open starts reading commands. Done is closed once the input is exhausted
+func (t *Transport) Open(ctx context.Context, deliver func(msg *chat.Message)) error {
+
+	t.write(prompt)
+
+	go func() {
+		defer close(t.done)
+
+		scanner := bufio.NewScanner(t.in)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				t.write(prompt)
+				continue
+			}
+
+			deliver(&chat.Message{
+				ID:        t.id(),
+				ChannelID: channelID,
+				AuthorID:  authorID,
+				Content:   line,
+				Received:  time.Now(),
+			})
+		}
+	}()
+
+	return nil
+
+}
+
+// Done is closed when there are no more commands to read
+func (t *Transport) Done() <-chan struct{} {
+	return t.done
+}
+
+func (t *Transport) Close() error {
+	return nil
+}
+
+func (t *Transport) Ready(ctx context.Context) error {
+	return nil
+}
+
+func (t *Transport) Reply(ctx context.Context, msg *chat.Message, content string) (string, error) {
+	id := t.id()
+	t.write(fmt.Sprintf("%s\n%s", content, prompt))
+	return id, nil
+}
+
+func (t *Transport) Edit(ctx context.Context, msg *chat.Message, messageID, content string) error {
+	t.write(fmt.Sprintf("[edited %s] %s\n%s", messageID, content, prompt))
+	return nil
+}
+
+// Attach writes the content of every attachment inline, as a terminal cannot show files
+func (t *Transport) Attach(ctx context.Context, msg *chat.Message, content string, attachments ...*chat.Attachment) (string, error) {
+
+	var b strings.Builder
+	if content != "" {
+		b.WriteString(content + "\n")
+	}
+
+	for _, attachment := range attachments {
+		data, err := io.ReadAll(attachment.Data)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "--- %s (%s) ---\n%s\n--- end of %s ---\n", attachment.Name, attachment.ContentType, data, attachment.Name)
+	}
+
+	return t.Reply(ctx, msg, strings.TrimSuffix(b.String(), "\n"))
+
+}
+
+// Embed renders an embed as plain text
+func (t *Transport) Embed(ctx context.Context, msg *chat.Message, embed *chat.Embed) (string, error) {
+
+	lines := make([]string, 0, len(embed.Fields)+4)
+	if embed.Title != "" {
+		lines = append(lines, fmt.Sprintf("== %s ==", embed.Title))
+	}
+	if embed.URL != "" {
+		lines = append(lines, embed.URL)
+	}
+	if embed.Description != "" {
+		lines = append(lines, embed.Description)
+	}
+	for _, field := range embed.Fields {
+		lines = append(lines, fmt.Sprintf("%s: %s", field.Name, field.Value))
+	}
+	if embed.Footer != "" {
+		lines = append(lines, embed.Footer)
+	}
+
+	return t.Reply(ctx, msg, strings.Join(lines, "\n"))
+
+}
+
+func (t *Transport) id() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nextID++
+	return strconv.Itoa(t.nextID)
+}
+
+func (t *Transport) write(s string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, _ = io.WriteString(t.out, s)
+}
@@ -0,0 +1,333 @@
+package sde
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/pkg/clock"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	groupsFile = "groupIDs.yaml"
+	typesFile  = "typeIDs.yaml"
+
+	// batchSize is how many types are looked up in the universe repository at a time
+	batchSize = 1000
+)
+
+// Service loads CCP's Static Data Export into the universe repositories.
+// The SDE can be supplied as the zip archive published by CCP, or as
+// the directory that archive was extracted to
+type Service struct {
+	logger   *logrus.Logger
+	universe krinder.UniverseRepository
+	clock    clock.Clock
+}
+
+func New(logger *logrus.Logger, universe krinder.UniverseRepository, clock clock.Clock) *Service {
+	return &Service{
+		logger:   logger,
+		universe: universe,
+		clock:    clock,
+	}
+}
+
+type sdeName struct {
+	En string `yaml:"en"`
+}
+
+type sdeGroup struct {
+	CategoryID uint    `yaml:"categoryID"`
+	Name       sdeName `yaml:"name"`
+	Published  bool    `yaml:"published"`
+}
+
+type sdeType struct {
+	GroupID   uint    `yaml:"groupID"`
+	Name      sdeName `yaml:"name"`
+	Published bool    `yaml:"published"`
+}
+
+// ImportResult is a summary of the records touched by an import
+type ImportResult struct {
+	GroupsCreated   int
+	GroupsUpdated   int
+	GroupsUnchanged int
+
+	EntitiesCreated   int
+	EntitiesUpdated   int
+	EntitiesUnchanged int
+}
+
+// Import reads the groups and types from the SDE located at src and upserts them into
+// the universe repository. Records are marked as expiring after the provided duration so
+// that the universe sync does not immediately refetch them from ESI. Running Import
+// multiple times, or over an older SDE, is safe
+func (s *Service) Import(ctx context.Context, src string, expiresAfter time.Duration) (*ImportResult, error) {
+
+	var result = new(ImportResult)
+
+	var groups = make(map[uint]*sdeGroup)
+	err := s.decode(src, groupsFile, &groups)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode %s", groupsFile)
+	}
+
+	s.logger.WithField("groups", len(groups)).Info("decoded groups from sde")
+
+	expires := s.clock.Now().Add(expiresAfter).UTC()
+
+	err = s.importGroups(ctx, groups, expires, result)
+	if err != nil {
+		return nil, err
+	}
+
+	var types = make(map[uint]*sdeType)
+	err = s.decode(src, typesFile, &types)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode %s", typesFile)
+	}
+
+	s.logger.WithField("types", len(types)).Info("decoded types from sde")
+
+	err = s.importTypes(ctx, types, expires, result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+
+}
+
+func (s *Service) importGroups(ctx context.Context, groups map[uint]*sdeGroup, expires time.Time, result *ImportResult) error {
+
+	existing, err := s.universe.Groups(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch existing groups")
+	}
+
+	var mapExisting = make(map[uint]*krinder.MySQLGroup, len(existing))
+	for _, group := range existing {
+		mapExisting[group.GroupID] = group
+	}
+
+	for groupID, sdeGroup := range groups {
+
+		group, ok := mapExisting[groupID]
+		if !ok {
+			_, err = s.universe.CreateGroup(ctx, &krinder.MySQLGroup{
+				CategoryID: sdeGroup.CategoryID,
+				GroupID:    groupID,
+				Name:       sdeGroup.Name.En,
+				Published:  sdeGroup.Published,
+				Expires:    expires,
+			})
+			if err != nil {
+				return errors.Wrapf(err, "failed to create group %d", groupID)
+			}
+			result.GroupsCreated++
+			continue
+		}
+
+		if group.CategoryID == sdeGroup.CategoryID && group.Name == sdeGroup.Name.En && group.Published == sdeGroup.Published {
+			result.GroupsUnchanged++
+			continue
+		}
+
+		group.CategoryID = sdeGroup.CategoryID
+		group.Name = sdeGroup.Name.En
+		group.Published = sdeGroup.Published
+		group.Etag = ""
+		group.Expires = expires
+
+		_, err = s.universe.UpdateGroup(ctx, group)
+		if err != nil {
+			return errors.Wrapf(err, "failed to update group %d", groupID)
+		}
+		result.GroupsUpdated++
+
+	}
+
+	return nil
+
+}
+
+func (s *Service) importTypes(ctx context.Context, types map[uint]*sdeType, expires time.Time, result *ImportResult) error {
+
+	ids := make([]uint, 0, len(types))
+	for typeID := range types {
+		ids = append(ids, typeID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	// There are far more types than groups, so only the entities of a batch are held at a time
+	for start := 0; start < len(ids); start += batchSize {
+		end := start + batchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		err := s.importTypeBatch(ctx, ids[start:end], types, expires, result)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+func (s *Service) importTypeBatch(ctx context.Context, ids []uint, types map[uint]*sdeType, expires time.Time, result *ImportResult) error {
+
+	existing, err := s.universe.Entitys(ctx, krinder.NewInOperator("id", ids))
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch existing entities")
+	}
+
+	var mapExisting = make(map[uint]*krinder.MongoEntity, len(existing))
+	for _, entity := range existing {
+		mapExisting[entity.ID] = entity
+	}
+
+	for _, typeID := range ids {
+
+		sdeType := types[typeID]
+
+		entity, ok := mapExisting[typeID]
+		if !ok {
+			_, err = s.universe.CreateEntity(ctx, &krinder.MongoEntity{
+				ID:        typeID,
+				Name:      sdeType.Name.En,
+				Published: sdeType.Published,
+				Expires:   expires,
+			})
+			if err != nil {
+				return errors.Wrapf(err, "failed to create entity %d", typeID)
+			}
+			result.EntitiesCreated++
+			continue
+		}
+
+		if entity.Name == sdeType.Name.En && entity.Published == sdeType.Published {
+			result.EntitiesUnchanged++
+			continue
+		}
+
+		entity.Name = sdeType.Name.En
+		entity.Published = sdeType.Published
+		entity.Etag = ""
+		entity.Expires = expires
+
+		_, err = s.universe.UpdateEntity(ctx, entity)
+		if err != nil {
+			return errors.Wrapf(err, "failed to update entity %d", typeID)
+		}
+		result.EntitiesUpdated++
+
+	}
+
+	return nil
+
+}
+
+// decode locates the file with the provided name inside of the SDE at src and
+// decodes it onto out
+func (s *Service) decode(src, name string, out interface{}) error {
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return errors.Wrap(err, "failed to stat sde")
+	}
+
+	var r io.ReadCloser
+	switch info.IsDir() {
+	case true:
+		r, err = openFromDirectory(src, name)
+	case false:
+		r, err = openFromArchive(src, name)
+	}
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return yaml.NewDecoder(r).Decode(out)
+
+}
+
+// errFound stops the walk of openFromDirectory at the first match. filepath.SkipDir would only
+// skip the rest of the directory the match is in
+var errFound = errors.New("found")
+
+func openFromDirectory(dir, name string) (io.ReadCloser, error) {
+
+	var found string
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && info.Name() == name {
+			found = p
+			return errFound
+		}
+		return nil
+	})
+	if err != nil && err != errFound {
+		return nil, errors.Wrapf(err, "failed to walk %s", dir)
+	}
+
+	if found == "" {
+		return nil, errors.Errorf("%s not found in %s", name, dir)
+	}
+
+	return os.Open(found)
+
+}
+
+type archiveFile struct {
+	io.ReadCloser
+	archive *zip.ReadCloser
+}
+
+func (a *archiveFile) Close() error {
+	_ = a.ReadCloser.Close()
+	return a.archive.Close()
+}
+
+func openFromArchive(src, name string) (io.ReadCloser, error) {
+
+	archive, err := zip.OpenReader(src)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %s as a zip archive", src)
+	}
+
+	for _, file := range archive.File {
+		if path.Base(file.Name) != name || strings.HasSuffix(file.Name, "/") {
+			continue
+		}
+
+		r, err := file.Open()
+		if err != nil {
+			_ = archive.Close()
+			return nil, errors.Wrapf(err, "failed to open %s", file.Name)
+		}
+
+		return &archiveFile{ReadCloser: r, archive: archive}, nil
+	}
+
+	_ = archive.Close()
+
+	return nil, errors.Errorf("%s not found in %s", name, src)
+
+}
@@ -0,0 +1,89 @@
+package sde
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/internal/store/memory"
+	"github.com/eveisesi/krinder/pkg/clock"
+	"github.com/sirupsen/logrus"
+)
+
+func TestImport(t *testing.T) {
+
+	ctx := context.Background()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	// One more type than fits in a batch, so the types are looked up in two batches
+	var types strings.Builder
+	for typeID := 1; typeID <= batchSize+1; typeID++ {
+		fmt.Fprintf(&types, "%d:\n  groupID: 25\n  name:\n    en: Type %d\n  published: true\n", typeID, typeID)
+	}
+
+	src := t.TempDir()
+	writeFile(t, filepath.Join(src, groupsFile), "25:\n  categoryID: 6\n  name:\n    en: Frigate\n  published: true\n")
+	writeFile(t, filepath.Join(src, typesFile), types.String())
+
+	now := clock.NewFake(time.Date(2021, time.November, 1, 0, 0, 0, 0, time.UTC))
+	repo := memory.NewUniverseRepository(now)
+
+	_, err := repo.CreateEntity(ctx, &krinder.MongoEntity{ID: batchSize + 1, Name: "Renamed Type", Published: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	s := New(logger, repo, now)
+
+	result, err := s.Import(ctx, src, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if result.GroupsCreated != 1 {
+		t.Errorf("expected 1 group to be created, got %d", result.GroupsCreated)
+	}
+	if result.EntitiesCreated != batchSize || result.EntitiesUpdated != 1 {
+		t.Errorf("expected %d entities created and 1 updated, got %d and %d", batchSize, result.EntitiesCreated, result.EntitiesUpdated)
+	}
+
+	entity, err := repo.Entity(ctx, batchSize+1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if entity.Name != fmt.Sprintf("Type %d", batchSize+1) {
+		t.Errorf("expected entity in the second batch to be renamed, got %q", entity.Name)
+	}
+	if !entity.Expires.Equal(now.Now().Add(time.Hour)) {
+		t.Errorf("expected entity to expire an hour after the import, got %s", entity.Expires)
+	}
+
+	now.Advance(time.Minute)
+
+	result, err = s.Import(ctx, src, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if result.GroupsUnchanged != 1 || result.EntitiesUnchanged != batchSize+1 {
+		t.Errorf("expected a second import to leave everything unchanged, got %+v", result)
+	}
+
+}
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+
+	err := os.WriteFile(name, []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write %s: %s", name, err)
+	}
+}
@@ -0,0 +1,74 @@
+package sso
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+
+	"github.com/pkg/errors"
+)
+
+// Cipher seals tokens with AES-GCM before they are stored
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher returns a cipher for a base64 encoded key of 16, 24 or 32 bytes
+func NewCipher(key string) (*Cipher, error) {
+
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "encryption key must be base64 encoded")
+	}
+
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid encryption key")
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cipher{aead: aead}, nil
+
+}
+
+// Seal encrypts plaintext, returning the nonce and ciphertext base64 encoded
+func (c *Cipher) Seal(plaintext string) (string, error) {
+
+	nonce := make([]byte, c.aead.NonceSize())
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+
+	return base64.StdEncoding.EncodeToString(sealed), nil
+
+}
+
+// Open decrypts a value returned by Seal
+func (c *Cipher) Open(sealed string) (string, error) {
+
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to decode sealed value")
+	}
+
+	size := c.aead.NonceSize()
+	if len(raw) < size {
+		return "", errors.New("sealed value is too short")
+	}
+
+	plaintext, err := c.aead.Open(nil, raw[:size], raw[size:], nil)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to decrypt sealed value")
+	}
+
+	return string(plaintext), nil
+
+}
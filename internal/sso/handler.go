@@ -0,0 +1,60 @@
+package sso
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/pkg/correlation"
+	"github.com/eveisesi/krinder/pkg/errorcode"
+)
+
+// Linked is called once a character has been linked, for example to tell the user in chat
+type Linked func(ctx context.Context, credential *krinder.Credential, pending *Pending)
+
+// CallbackHandler serves the redirect from EVE SSO, completing the authorization and
+// showing the outcome to the user in their browser
+func (s *Service) CallbackHandler(linked Linked) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		id := correlation.New()
+		ctx, cancel := context.WithTimeout(correlation.WithID(r.Context(), id), time.Second*30)
+		defer cancel()
+
+		query := r.URL.Query()
+		if query.Get("error") != "" {
+			page(w, http.StatusBadRequest, "Linking cancelled", "EVE SSO did not authorize the character. Run the link command again to retry.")
+			return
+		}
+
+		credential, pending, err := s.Callback(ctx, query.Get("state"), query.Get("code"))
+		if err != nil {
+			s.logger.WithContext(ctx).WithError(err).WithField("service", "sso").Error("failed to complete authorization")
+
+			message := fmt.Sprintf("Something went wrong, run the link command again (reference %s).", id)
+			status := http.StatusInternalServerError
+			if code := errorcode.Code(err); code == errorcode.NotFound || code == errorcode.InvalidArgument {
+				message = errorcode.Message(err)
+				status = http.StatusBadRequest
+			}
+			page(w, status, "Linking failed", message)
+			return
+		}
+
+		if linked != nil {
+			linked(ctx, credential, pending)
+		}
+
+		page(w, http.StatusOK, "Character linked", fmt.Sprintf("%s is now linked, you can close this window.", credential.CharacterName))
+
+	})
+}
+
+func page(w http.ResponseWriter, status int, title, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "<!doctype html><title>%[1]s</title><h1>%[1]s</h1><p>%[2]s</p>\n", html.EscapeString(title), html.EscapeString(message))
+}
@@ -0,0 +1,463 @@
+// Package sso links chat users with their EVE characters through the EVE SSO authorization code
+// flow with PKCE, and keeps the access tokens of linked characters fresh
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/pkg/clock"
+	"github.com/eveisesi/krinder/pkg/errorcode"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// stateTTL is how long a link generated by AuthorizeURL may be used for
+	stateTTL = time.Minute * 10
+	// refreshMargin refreshes access tokens that expire within the margin, so that
+	// a token does not expire while a request is in flight
+	refreshMargin = time.Minute
+)
+
+var (
+	ErrDisabled     = errorcode.New(errorcode.InvalidArgument, "linking characters is not enabled on this bot")
+	ErrNotLinked    = errorcode.New(errorcode.NotFound, "no character is linked, use the link command to link one")
+	errUnknownState = errorcode.New(errorcode.NotFound, "the link has expired or was already used, run the link command again")
+)
+
+type Config struct {
+	ClientID string
+	// ClientSecret is optional, applications registered for PKCE authenticate with the verifier alone
+	ClientSecret string
+	CallbackURL  string
+	Scopes       []string
+	// BaseURL defaults to https://login.eveonline.com
+	BaseURL string
+}
+
+type Service struct {
+	logger *logrus.Logger
+	client *http.Client
+
+	config      Config
+	states      StateStore
+	credentials krinder.CredentialRepository
+	cipher      *Cipher
+	clock       clock.Clock
+
+	// refreshing serialises refreshes per credential, as EVE rotates refresh tokens
+	mu         sync.Mutex
+	refreshing map[string]*sync.Mutex
+}
+
+// New returns a service for config. Linking is disabled when config has no client id, in which case
+// states and cipher may be nil
+func New(logger *logrus.Logger, config Config, states StateStore, credentials krinder.CredentialRepository, cipher *Cipher, clock clock.Clock) *Service {
+	if config.BaseURL == "" {
+		config.BaseURL = "https://login.eveonline.com"
+	}
+
+	return &Service{
+		logger: logger,
+		client: &http.Client{
+			Timeout: time.Second * 10,
+		},
+		config:      config,
+		states:      states,
+		credentials: credentials,
+		cipher:      cipher,
+		clock:       clock,
+		refreshing:  make(map[string]*sync.Mutex),
+	}
+}
+
+// Enabled reports whether an application is configured to link characters with
+func (s *Service) Enabled() bool {
+	return s.config.ClientID != ""
+}
+
+// AuthorizeURL starts an authorization for the chat user, returning the url the user logs in at.
+// channelID is the channel the user is told about the outcome in
+func (s *Service) AuthorizeURL(ctx context.Context, userID, channelID string) (string, error) {
+
+	if !s.Enabled() {
+		return "", ErrDisabled
+	}
+
+	state, err := random(24)
+	if err != nil {
+		return "", err
+	}
+
+	verifier, err := random(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.states.Put(ctx, state, &Pending{UserID: userID, ChannelID: channelID, Verifier: verifier}, stateTTL)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to store pending authorization")
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("redirect_uri", s.config.CallbackURL)
+	query.Set("client_id", s.config.ClientID)
+	query.Set("scope", strings.Join(s.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+
+	return fmt.Sprintf("%s/v2/oauth/authorize?%s", s.config.BaseURL, query.Encode()), nil
+
+}
+
+// Callback completes the authorization identified by state, exchanging code for tokens and storing
+// the credential of the character. The first character a user links becomes their main
+func (s *Service) Callback(ctx context.Context, state, code string) (*krinder.Credential, *Pending, error) {
+
+	pending, err := s.states.Take(ctx, state)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("client_id", s.config.ClientID)
+	form.Set("code_verifier", pending.Verifier)
+
+	token, err := s.token(ctx, form)
+	if err != nil {
+		return nil, pending, err
+	}
+
+	claims, err := parseClaims(token.AccessToken)
+	if err != nil {
+		return nil, pending, err
+	}
+
+	existing, err := s.credentials.Credentials(ctx, krinder.NewEqualOperator("userID", pending.UserID))
+	if err != nil {
+		return nil, pending, errors.Wrap(err, "failed to fetch linked characters")
+	}
+
+	credential := &krinder.Credential{
+		UserID:        pending.UserID,
+		CharacterID:   claims.characterID,
+		CharacterName: claims.Name,
+		Main:          len(existing) == 0,
+		Scopes:        claims.scopes,
+	}
+	for _, c := range existing {
+		if c.CharacterID == credential.CharacterID {
+			// Linking a character again refreshes its tokens and scopes
+			credential.Main = c.Main
+			credential.CreatedAt = c.CreatedAt
+		}
+	}
+
+	err = s.store(ctx, credential, token)
+	if err != nil {
+		return nil, pending, err
+	}
+
+	return credential, pending, nil
+
+}
+
+// Characters returns the characters linked by the chat user
+func (s *Service) Characters(ctx context.Context, userID string) ([]*krinder.Credential, error) {
+	return s.credentials.Credentials(ctx, krinder.NewEqualOperator("userID", userID), krinder.NewOrderOperator("characterName", krinder.SortAsc))
+}
+
+// Main returns the main character of the chat user, ErrNotLinked is returned if the user has not linked one
+func (s *Service) Main(ctx context.Context, userID string) (*krinder.Credential, error) {
+
+	credentials, err := s.credentials.Credentials(ctx, krinder.NewEqualOperator("userID", userID), krinder.NewEqualOperator("main", true))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch main character")
+	}
+
+	if len(credentials) == 0 {
+		return nil, ErrNotLinked
+	}
+
+	return credentials[0], nil
+
+}
+
+// SetMain makes characterID the main character of the chat user
+func (s *Service) SetMain(ctx context.Context, userID string, characterID uint64) error {
+
+	credentials, err := s.Characters(ctx, userID)
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch linked characters")
+	}
+
+	var found bool
+	for _, c := range credentials {
+		found = found || c.CharacterID == characterID
+	}
+	if !found {
+		return errorcode.Newf(errorcode.NotFound, "character %d is not linked", characterID)
+	}
+
+	for _, c := range credentials {
+		main := c.CharacterID == characterID
+		if c.Main == main {
+			continue
+		}
+
+		c.Main = main
+		err = s.credentials.SaveCredential(ctx, c)
+		if err != nil {
+			return errors.Wrap(err, "failed to save credential")
+		}
+	}
+
+	return nil
+
+}
+
+// Unlink removes the credential of characterID. If it was the main character, the next linked character becomes the main
+func (s *Service) Unlink(ctx context.Context, userID string, characterID uint64) error {
+
+	credential, err := s.credentials.Credential(ctx, userID, characterID)
+	if errors.Is(err, krinder.ErrNotFound) {
+		return errorcode.Newf(errorcode.NotFound, "character %d is not linked", characterID)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch credential")
+	}
+
+	err = s.credentials.DeleteCredential(ctx, userID, characterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete credential")
+	}
+
+	if !credential.Main {
+		return nil
+	}
+
+	remaining, err := s.Characters(ctx, userID)
+	if err != nil || len(remaining) == 0 {
+		return err
+	}
+
+	return s.SetMain(ctx, userID, remaining[0].CharacterID)
+
+}
+
+// Token returns an access token for the character linked by the chat user, refreshing it when it has expired
+func (s *Service) Token(ctx context.Context, userID string, characterID uint64) (string, error) {
+
+	if !s.Enabled() {
+		return "", ErrDisabled
+	}
+
+	lock := s.lock(userID, characterID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	credential, err := s.credentials.Credential(ctx, userID, characterID)
+	if errors.Is(err, krinder.ErrNotFound) {
+		return "", errorcode.Newf(errorcode.NotFound, "character %d is not linked", characterID)
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "failed to fetch credential")
+	}
+
+	if credential.AccessExpiresAt.Sub(s.clock.Now()) > refreshMargin {
+		return s.cipher.Open(credential.AccessToken)
+	}
+
+	refreshToken, err := s.cipher.Open(credential.RefreshToken)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", refreshToken)
+	form.Set("client_id", s.config.ClientID)
+
+	token, err := s.token(ctx, form)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to refresh token")
+	}
+
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"service":     "sso",
+		"characterID": characterID,
+	}).Debug("refreshed access token")
+
+	err = s.store(ctx, credential, token)
+	if err != nil {
+		return "", err
+	}
+
+	return token.AccessToken, nil
+
+}
+
+func (s *Service) lock(userID string, characterID uint64) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := fmt.Sprintf("%s:%d", userID, characterID)
+	lock, ok := s.refreshing[key]
+	if !ok {
+		lock = new(sync.Mutex)
+		s.refreshing[key] = lock
+	}
+	return lock
+}
+
+// store seals the tokens onto credential and saves it
+func (s *Service) store(ctx context.Context, credential *krinder.Credential, token *tokenResponse) error {
+
+	var err error
+	credential.AccessToken, err = s.cipher.Seal(token.AccessToken)
+	if err != nil {
+		return errors.Wrap(err, "failed to seal access token")
+	}
+
+	// EVE may rotate the refresh token, in which case the new one must be kept
+	if token.RefreshToken != "" {
+		credential.RefreshToken, err = s.cipher.Seal(token.RefreshToken)
+		if err != nil {
+			return errors.Wrap(err, "failed to seal refresh token")
+		}
+	}
+
+	credential.AccessExpiresAt = s.clock.Now().UTC().Add(time.Duration(token.ExpiresIn) * time.Second)
+
+	return errors.Wrap(s.credentials.SaveCredential(ctx, credential), "failed to save credential")
+
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (s *Service) token(ctx context.Context, form url.Values) (*tokenResponse, error) {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.BaseURL+"/v2/oauth/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build token request")
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if s.config.ClientSecret != "" {
+		req.SetBasicAuth(s.config.ClientID, s.config.ClientSecret)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		code := errorcode.UpstreamUnavailable
+		if errorcode.Code(err) == errorcode.Timeout {
+			code = errorcode.Timeout
+		}
+		return nil, errorcode.Wrap(err, code, "eve sso could not be reached")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(res.Body)
+
+		// EVE answers 400 when a code or refresh token is invalid or has been revoked
+		code := errorcode.FromHTTPStatus(res.StatusCode)
+		message := fmt.Sprintf("eve sso responded with %d %s", res.StatusCode, http.StatusText(res.StatusCode))
+		if res.StatusCode == http.StatusBadRequest {
+			message = "eve sso rejected the authorization, link the character again"
+		}
+
+		return nil, errorcode.Wrap(errors.Errorf("unexpected status %d: %s", res.StatusCode, data), code, message)
+	}
+
+	var token = new(tokenResponse)
+	err = json.NewDecoder(res.Body).Decode(token)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode token response")
+	}
+
+	return token, nil
+
+}
+
+type claims struct {
+	Subject string          `json:"sub"`
+	Name    string          `json:"name"`
+	Scope   json.RawMessage `json:"scp"`
+
+	characterID uint64
+	scopes      []string
+}
+
+// parseClaims reads the character and scopes from an access token. The signature is not verified,
+// as the token was received directly from the token endpoint over TLS
+func parseClaims(token string) (*claims, error) {
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("access token is not a jwt")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode access token")
+	}
+
+	var c = new(claims)
+	err = json.Unmarshal(payload, c)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode access token claims")
+	}
+
+	// The subject of a character token is CHARACTER:EVE:<character id>
+	id, err := strconv.ParseUint(strings.TrimPrefix(c.Subject, "CHARACTER:EVE:"), 10, 64)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unexpected subject %s", c.Subject)
+	}
+	c.characterID = id
+
+	// scp is a string when a single scope was granted and an array otherwise
+	if len(c.Scope) > 0 {
+		var scope string
+		if json.Unmarshal(c.Scope, &scope) == nil {
+			c.scopes = []string{scope}
+		} else if err := json.Unmarshal(c.Scope, &c.scopes); err != nil {
+			return nil, errors.Wrap(err, "failed to decode scopes")
+		}
+	}
+
+	return c, nil
+
+}
+
+func random(n int) (string, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
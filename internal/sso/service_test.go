@@ -0,0 +1,175 @@
+package sso
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/krinder/internal/store/memory"
+	"github.com/eveisesi/krinder/pkg/clock"
+	"github.com/sirupsen/logrus"
+)
+
+type memoryStates map[string]*Pending
+
+func (m memoryStates) Put(ctx context.Context, state string, pending *Pending, ttl time.Duration) error {
+	m[state] = pending
+	return nil
+}
+
+func (m memoryStates) Take(ctx context.Context, state string) (*Pending, error) {
+	pending, ok := m[state]
+	if !ok {
+		return nil, errUnknownState
+	}
+	delete(m, state)
+	return pending, nil
+}
+
+func jwt(characterID uint64, name string, scopes interface{}) string {
+	claims, _ := json.Marshal(map[string]interface{}{
+		"sub":  fmt.Sprintf("CHARACTER:EVE:%d", characterID),
+		"name": name,
+		"scp":  scopes,
+	})
+	return "e30." + base64.RawURLEncoding.EncodeToString(claims) + ".sig"
+}
+
+// fakeSSO issues tokens for character 90000001. It verifies the PKCE verifier against the challenge
+// of the last authorization and rotates the refresh token on every refresh
+type fakeSSO struct {
+	t         *testing.T
+	challenge string
+	refreshes int
+}
+
+func (f *fakeSSO) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		f.t.Fatalf("failed to parse form: %s", err)
+	}
+
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != f.challenge || r.PostForm.Get("code") != "code" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	case "refresh_token":
+		if r.PostForm.Get("refresh_token") != fmt.Sprintf("refresh-%d", f.refreshes) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.refreshes++
+	}
+
+	_ = json.NewEncoder(w).Encode(tokenResponse{
+		AccessToken:  jwt(90000001, "Hunter", []string{"publicData", "esi-killmails.read_killmails.v1"}),
+		ExpiresIn:    1200,
+		RefreshToken: fmt.Sprintf("refresh-%d", f.refreshes),
+	})
+}
+
+func TestLink(t *testing.T) {
+
+	fake := &fakeSSO{t: t}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	cipher, err := NewCipher(base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	if err != nil {
+		t.Fatalf("failed to build cipher: %s", err)
+	}
+
+	now := clock.NewFake(time.Date(2021, 11, 15, 12, 0, 0, 0, time.UTC))
+	credentials := memory.NewCredentialRepository()
+	s := New(logger, Config{ClientID: "client", CallbackURL: "http://localhost/callback", BaseURL: server.URL}, memoryStates{}, credentials, cipher, now)
+
+	ctx := context.Background()
+
+	uri, err := s.AuthorizeURL(ctx, "user", "channel")
+	if err != nil {
+		t.Fatalf("failed to build authorize url: %s", err)
+	}
+
+	parsed, _ := url.Parse(uri)
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("client_id") != "client" {
+		t.Errorf("unexpected authorize url %s", uri)
+	}
+	fake.challenge = query.Get("code_challenge")
+
+	credential, pending, err := s.Callback(ctx, query.Get("state"), "code")
+	if err != nil {
+		t.Fatalf("failed to complete authorization: %s", err)
+	}
+	if pending.ChannelID != "channel" || credential.CharacterID != 90000001 || credential.CharacterName != "Hunter" || !credential.Main || len(credential.Scopes) != 2 {
+		t.Errorf("unexpected credential %+v", credential)
+	}
+
+	// A state can only be used once
+	if _, _, err := s.Callback(ctx, query.Get("state"), "code"); err == nil {
+		t.Errorf("expected a reused state to fail")
+	}
+
+	stored, _ := credentials.Credential(ctx, "user", 90000001)
+	if stored.RefreshToken == "refresh-0" || stored.AccessToken == "" {
+		t.Errorf("expected tokens to be stored sealed")
+	}
+
+	token, err := s.Token(ctx, "user", 90000001)
+	if err != nil || token != jwt(90000001, "Hunter", []string{"publicData", "esi-killmails.read_killmails.v1"}) {
+		t.Fatalf("expected the stored access token, got %q (%v)", token, err)
+	}
+	if fake.refreshes != 0 {
+		t.Errorf("expected a fresh token not to be refreshed")
+	}
+
+	// Expired tokens are refreshed, and the rotated refresh token is used next time
+	for i := 1; i <= 2; i++ {
+		now.Advance(time.Second * 1200)
+
+		if _, err := s.Token(ctx, "user", 90000001); err != nil {
+			t.Fatalf("failed to refresh token: %s", err)
+		}
+		if fake.refreshes != i {
+			t.Errorf("expected %d refreshes, got %d", i, fake.refreshes)
+		}
+	}
+
+	main, err := s.Main(ctx, "user")
+	if err != nil || main.CharacterID != 90000001 {
+		t.Errorf("expected the linked character to be the main, got %+v (%v)", main, err)
+	}
+
+	if err := s.Unlink(ctx, "user", 90000001); err != nil {
+		t.Fatalf("failed to unlink: %s", err)
+	}
+	if _, err := s.Main(ctx, "user"); err != ErrNotLinked {
+		t.Errorf("expected ErrNotLinked after unlinking, got %v", err)
+	}
+
+}
+
+func TestParseClaimsSingleScope(t *testing.T) {
+
+	c, err := parseClaims(jwt(90000002, "Alt", "publicData"))
+	if err != nil {
+		t.Fatalf("failed to parse claims: %s", err)
+	}
+	if c.characterID != 90000002 || len(c.scopes) != 1 || c.scopes[0] != "publicData" {
+		t.Errorf("unexpected claims %+v", c)
+	}
+
+}
@@ -0,0 +1,77 @@
+package sso
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+)
+
+// Pending is an authorization that was started by the link command and is waiting for the callback
+type Pending struct {
+	UserID    string `json:"userID"`
+	ChannelID string `json:"channelID"`
+	Verifier  string `json:"verifier"`
+}
+
+// StateStore holds pending authorizations under their state parameter
+type StateStore interface {
+	Put(ctx context.Context, state string, pending *Pending, ttl time.Duration) error
+	// Take returns and removes the pending authorization, so that a state can only be used once.
+	// An error coded NotFound is returned for unknown or expired states
+	Take(ctx context.Context, state string) (*Pending, error)
+}
+
+const statePrefix = "sso:state:"
+
+// RedisStateStore keeps pending authorizations in redis, so that the callback
+// may be served by any replica
+type RedisStateStore struct {
+	client *redis.Client
+}
+
+var _ StateStore = new(RedisStateStore)
+
+func NewRedisStateStore(client *redis.Client) *RedisStateStore {
+	return &RedisStateStore{
+		client: client,
+	}
+}
+
+func (s *RedisStateStore) Put(ctx context.Context, state string, pending *Pending, ttl time.Duration) error {
+
+	payload, err := json.Marshal(pending)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode pending authorization")
+	}
+
+	return s.client.Set(ctx, statePrefix+state, payload, ttl).Err()
+
+}
+
+func (s *RedisStateStore) Take(ctx context.Context, state string) (*Pending, error) {
+
+	var get *redis.StringCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, statePrefix+state)
+		pipe.Del(ctx, statePrefix+state)
+		return nil
+	})
+	if errors.Is(err, redis.Nil) {
+		return nil, errUnknownState
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var pending = new(Pending)
+	err = json.Unmarshal([]byte(get.Val()), pending)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode pending authorization")
+	}
+
+	return pending, nil
+
+}
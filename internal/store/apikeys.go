@@ -0,0 +1,118 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/pkg/errors"
+	"github.com/volatiletech/null"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type APIKeyRepository struct {
+	keys *mongo.Collection
+}
+
+var _ krinder.APIKeyRepository = new(APIKeyRepository)
+
+func NewAPIKeyRepository(database *mongo.Database) (*APIKeyRepository, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+	keys := database.Collection("api_keys")
+
+	_, err := keys.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				primitive.E{Key: "name", Value: 1},
+			},
+			Options: &options.IndexOptions{
+				Unique: null.BoolFrom(true).Ptr(),
+			},
+		},
+		{
+			Keys: bson.D{
+				primitive.E{Key: "hash", Value: 1},
+			},
+			Options: &options.IndexOptions{
+				Unique: null.BoolFrom(true).Ptr(),
+			},
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create indexes")
+	}
+
+	return &APIKeyRepository{
+		keys: keys,
+	}, nil
+
+}
+
+func (r *APIKeyRepository) APIKey(ctx context.Context, hash string) (*krinder.APIKey, error) {
+
+	var key = new(krinder.APIKey)
+
+	err := r.keys.FindOne(ctx, bson.D{primitive.E{Key: "hash", Value: hash}}).Decode(key)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = krinder.ErrNotFound
+	}
+
+	return key, err
+
+}
+
+func (r *APIKeyRepository) APIKeys(ctx context.Context, operators ...*krinder.Operator) ([]*krinder.APIKey, error) {
+
+	var keys = make([]*krinder.APIKey, 0)
+
+	filters, err := BuildMongoFilters(operators...)
+	if err != nil {
+		return keys, err
+	}
+
+	options, err := BuildMongoFindOptions(operators...)
+	if err != nil {
+		return keys, err
+	}
+
+	result, err := r.keys.Find(ctx, filters, options)
+	if err != nil {
+		return keys, err
+	}
+
+	return keys, result.All(ctx, &keys)
+
+}
+
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, key *krinder.APIKey) error {
+
+	key.CreatedAt = time.Now().UTC()
+
+	_, err := r.keys.InsertOne(ctx, key)
+	if mongo.IsDuplicateKeyError(err) {
+		return krinder.ErrDuplicate
+	}
+
+	return err
+
+}
+
+func (r *APIKeyRepository) DeleteAPIKey(ctx context.Context, name string) error {
+
+	result, err := r.keys.DeleteOne(ctx, bson.D{primitive.E{Key: "name", Value: name}})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return krinder.ErrNotFound
+	}
+
+	return nil
+
+}
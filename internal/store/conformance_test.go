@@ -0,0 +1,170 @@
+package store_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/krinder/internal/store"
+	"github.com/eveisesi/krinder/internal/store/storetest"
+	"github.com/eveisesi/krinder/pkg/clock"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The conformance suites only run against real databases when they are provided, e.g.
+//   KRINDER_TEST_MONGO_URI=mongodb://localhost:27017
+//   KRINDER_TEST_MYSQL_DSN=root:pass@tcp(localhost:3306)/krinder_test?parseTime=true
+// The MySQL database is migrated up before and down after the suite runs, so it should not be shared
+
+// mongoDatabase connects to KRINDER_TEST_MONGO_URI and returns a database of its own that is dropped
+// once the test completes. The test is skipped when no URI is provided
+func mongoDatabase(t *testing.T) *mongo.Database {
+
+	t.Helper()
+
+	uri := os.Getenv("KRINDER_TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("KRINDER_TEST_MONGO_URI is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("failed to connect to mongo: %s", err)
+	}
+
+	database := client.Database(fmt.Sprintf("krinder_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		_ = database.Drop(context.Background())
+		_ = client.Disconnect(context.Background())
+	})
+
+	return database
+
+}
+
+func TestMongoUniverseRepository(t *testing.T) {
+
+	repo, err := store.NewUniverseRepository(mongoDatabase(t), clock.New())
+	if err != nil {
+		t.Fatalf("failed to initialize repository: %s", err)
+	}
+
+	storetest.TestUniverseRepository(t, repo)
+
+}
+
+func TestMongoWarRepository(t *testing.T) {
+
+	repo, err := store.NewWarRepository(mongoDatabase(t), clock.New())
+	if err != nil {
+		t.Fatalf("failed to initialize repository: %s", err)
+	}
+
+	storetest.TestWarRepository(t, repo)
+
+}
+
+func TestMongoJobRepository(t *testing.T) {
+
+	repo, err := store.NewJobRepository(mongoDatabase(t))
+	if err != nil {
+		t.Fatalf("failed to initialize repository: %s", err)
+	}
+
+	storetest.TestJobRepository(t, repo)
+
+}
+
+func TestMongoCredentialRepository(t *testing.T) {
+
+	repo, err := store.NewCredentialRepository(mongoDatabase(t))
+	if err != nil {
+		t.Fatalf("failed to initialize repository: %s", err)
+	}
+
+	storetest.TestCredentialRepository(t, repo)
+
+}
+
+func TestMongoAPIKeyRepository(t *testing.T) {
+
+	repo, err := store.NewAPIKeyRepository(mongoDatabase(t))
+	if err != nil {
+		t.Fatalf("failed to initialize repository: %s", err)
+	}
+
+	storetest.TestAPIKeyRepository(t, repo)
+
+}
+
+func TestMongoDeadLetterRepository(t *testing.T) {
+
+	repo, err := store.NewDeadLetterRepository(mongoDatabase(t))
+	if err != nil {
+		t.Fatalf("failed to initialize repository: %s", err)
+	}
+
+	storetest.TestDeadLetterRepository(t, repo)
+
+}
+
+func TestMongoPaginationRepository(t *testing.T) {
+
+	repo, err := store.NewPaginationRepository(mongoDatabase(t), clock.New())
+	if err != nil {
+		t.Fatalf("failed to initialize repository: %s", err)
+	}
+
+	storetest.TestPaginationRepository(t, repo)
+
+}
+
+func TestMySQLUniverseRepository(t *testing.T) {
+
+	dsn := os.Getenv("KRINDER_TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("KRINDER_TEST_MYSQL_DSN is not set")
+	}
+
+	db, err := sqlx.Connect("mysql", dsn)
+	if err != nil {
+		t.Fatalf("failed to connect to mysql: %s", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	migrator, err := store.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("failed to initialize migrator: %s", err)
+	}
+
+	_, err = migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("failed to migrate: %s", err)
+	}
+	defer func() {
+		for {
+			migration, err := migrator.Down(ctx)
+			if err != nil || migration == nil {
+				return
+			}
+		}
+	}()
+
+	repo, err := store.NewMySQLUniverseRepository(db, clock.New())
+	if err != nil {
+		t.Fatalf("failed to initialize repository: %s", err)
+	}
+
+	storetest.TestUniverseRepository(t, repo)
+
+}
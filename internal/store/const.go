@@ -13,5 +13,9 @@ const (
 )
 
 const (
-	EntityID = "id"
+	EntityID        = "id"
+	EntityName      = "name"
+	EntityPublished = "published"
+	EntityEtag      = "etag"
+	EntityExpires   = "expires"
 )
@@ -0,0 +1,117 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/pkg/errors"
+	"github.com/volatiletech/null"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CredentialRepository struct {
+	credentials *mongo.Collection
+}
+
+var _ krinder.CredentialRepository = new(CredentialRepository)
+
+func NewCredentialRepository(database *mongo.Database) (*CredentialRepository, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+	credentials := database.Collection("credentials")
+
+	_, err := credentials.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			primitive.E{Key: "userID", Value: 1},
+			primitive.E{Key: "characterID", Value: 1},
+		},
+		Options: &options.IndexOptions{
+			Unique: null.BoolFrom(true).Ptr(),
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create index")
+	}
+
+	return &CredentialRepository{
+		credentials: credentials,
+	}, nil
+
+}
+
+func credentialFilter(userID string, characterID uint64) primitive.D {
+	return primitive.D{
+		primitive.E{Key: "userID", Value: userID},
+		primitive.E{Key: "characterID", Value: characterID},
+	}
+}
+
+func (r *CredentialRepository) Credential(ctx context.Context, userID string, characterID uint64) (*krinder.Credential, error) {
+
+	var credential = new(krinder.Credential)
+
+	err := r.credentials.FindOne(ctx, credentialFilter(userID, characterID)).Decode(credential)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = krinder.ErrNotFound
+	}
+
+	return credential, err
+
+}
+
+func (r *CredentialRepository) Credentials(ctx context.Context, operators ...*krinder.Operator) ([]*krinder.Credential, error) {
+
+	var credentials = make([]*krinder.Credential, 0)
+
+	filters, err := BuildMongoFilters(operators...)
+	if err != nil {
+		return credentials, err
+	}
+
+	options, err := BuildMongoFindOptions(operators...)
+	if err != nil {
+		return credentials, err
+	}
+
+	result, err := r.credentials.Find(ctx, filters, options)
+	if err != nil {
+		return credentials, err
+	}
+
+	return credentials, result.All(ctx, &credentials)
+
+}
+
+func (r *CredentialRepository) SaveCredential(ctx context.Context, credential *krinder.Credential) error {
+
+	now := time.Now().UTC()
+	if credential.CreatedAt.IsZero() {
+		credential.CreatedAt = now
+	}
+	credential.UpdatedAt = now
+
+	_, err := r.credentials.ReplaceOne(ctx, credentialFilter(credential.UserID, credential.CharacterID), credential, options.Replace().SetUpsert(true))
+
+	return err
+
+}
+
+func (r *CredentialRepository) DeleteCredential(ctx context.Context, userID string, characterID uint64) error {
+
+	result, err := r.credentials.DeleteOne(ctx, credentialFilter(userID, characterID))
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return krinder.ErrNotFound
+	}
+
+	return nil
+
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type DeadLetterRepository struct {
+	letters *mongo.Collection
+}
+
+var _ krinder.DeadLetterRepository = new(DeadLetterRepository)
+
+func NewDeadLetterRepository(database *mongo.Database) (*DeadLetterRepository, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+	letters := database.Collection("dead_letters")
+
+	_, err := letters.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			primitive.E{Key: "createdAt", Value: -1},
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create index")
+	}
+
+	return &DeadLetterRepository{
+		letters: letters,
+	}, nil
+
+}
+
+func (r *DeadLetterRepository) CreateDeadLetter(ctx context.Context, letter *krinder.DeadLetter) error {
+
+	letter.CreatedAt = time.Now().UTC()
+
+	_, err := r.letters.InsertOne(ctx, letter)
+	return err
+
+}
+
+func (r *DeadLetterRepository) DeadLetters(ctx context.Context, operators ...*krinder.Operator) ([]*krinder.DeadLetter, error) {
+
+	var letters = make([]*krinder.DeadLetter, 0)
+
+	filters, err := BuildMongoFilters(operators...)
+	if err != nil {
+		return letters, err
+	}
+
+	options, err := BuildMongoFindOptions(operators...)
+	if err != nil {
+		return letters, err
+	}
+
+	result, err := r.letters.Find(ctx, filters, options)
+	if err != nil {
+		return letters, err
+	}
+
+	return letters, result.All(ctx, &letters)
+
+}
@@ -0,0 +1,68 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/pkg/errors"
+	"github.com/volatiletech/null"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type JobRepository struct {
+	jobs *mongo.Collection
+}
+
+var _ krinder.JobRepository = new(JobRepository)
+
+func NewJobRepository(database *mongo.Database) (*JobRepository, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+	jobs := database.Collection("jobs")
+
+	_, err := jobs.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			primitive.E{Key: "name", Value: 1},
+		},
+		Options: &options.IndexOptions{
+			Unique: null.BoolFrom(true).Ptr(),
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create index")
+	}
+
+	return &JobRepository{
+		jobs: jobs,
+	}, nil
+
+}
+
+func (r *JobRepository) Job(ctx context.Context, name string) (*krinder.Job, error) {
+
+	var job = new(krinder.Job)
+
+	err := r.jobs.FindOne(ctx, bson.D{primitive.E{Key: "name", Value: name}}).Decode(job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = krinder.ErrNotFound
+	}
+
+	return job, err
+
+}
+
+func (r *JobRepository) SaveJob(ctx context.Context, job *krinder.Job) error {
+
+	job.UpdatedAt = time.Now().UTC()
+
+	filter := primitive.D{primitive.E{Key: "name", Value: job.Name}}
+	_, err := r.jobs.ReplaceOne(ctx, filter, job, options.Replace().SetUpsert(true))
+
+	return err
+
+}
@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/eveisesi/krinder"
+)
+
+// APIKeyRepository is an in process implementation of krinder.APIKeyRepository.
+// Nothing is persisted, so keys have to be created again after a restart
+type APIKeyRepository struct {
+	mu   sync.RWMutex
+	keys map[string]*krinder.APIKey
+}
+
+var _ krinder.APIKeyRepository = new(APIKeyRepository)
+
+func NewAPIKeyRepository() *APIKeyRepository {
+	return &APIKeyRepository{
+		keys: make(map[string]*krinder.APIKey),
+	}
+}
+
+func (r *APIKeyRepository) APIKey(ctx context.Context, hash string) (*krinder.APIKey, error) {
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.Hash == hash {
+			c := *key
+			return &c, nil
+		}
+	}
+
+	return new(krinder.APIKey), krinder.ErrNotFound
+
+}
+
+func (r *APIKeyRepository) APIKeys(ctx context.Context, operators ...*krinder.Operator) ([]*krinder.APIKey, error) {
+
+	r.mu.RLock()
+	var keys = make([]*krinder.APIKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		c := *key
+		keys = append(keys, &c)
+	}
+	r.mu.RUnlock()
+
+	result, err := apply(keys, operators...)
+	if err != nil {
+		return make([]*krinder.APIKey, 0), err
+	}
+
+	return result.([]*krinder.APIKey), nil
+
+}
+
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, key *krinder.APIKey) error {
+
+	key.CreatedAt = time.Now().UTC()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.keys {
+		if existing.Name == key.Name || existing.Hash == key.Hash {
+			return krinder.ErrDuplicate
+		}
+	}
+
+	c := *key
+	r.keys[key.Name] = &c
+
+	return nil
+
+}
+
+func (r *APIKeyRepository) DeleteAPIKey(ctx context.Context, name string) error {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.keys[name]; !ok {
+		return krinder.ErrNotFound
+	}
+
+	delete(r.keys, name)
+
+	return nil
+
+}
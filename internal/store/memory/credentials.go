@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/eveisesi/krinder"
+)
+
+// CredentialRepository is an in process implementation of krinder.CredentialRepository.
+// Nothing is persisted, so characters have to be linked again after a restart
+type CredentialRepository struct {
+	mu          sync.RWMutex
+	credentials map[string]*krinder.Credential
+}
+
+var _ krinder.CredentialRepository = new(CredentialRepository)
+
+func NewCredentialRepository() *CredentialRepository {
+	return &CredentialRepository{
+		credentials: make(map[string]*krinder.Credential),
+	}
+}
+
+func credentialKey(userID string, characterID uint64) string {
+	return fmt.Sprintf("%s:%d", userID, characterID)
+}
+
+func (r *CredentialRepository) Credential(ctx context.Context, userID string, characterID uint64) (*krinder.Credential, error) {
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	credential, ok := r.credentials[credentialKey(userID, characterID)]
+	if !ok {
+		return new(krinder.Credential), krinder.ErrNotFound
+	}
+
+	c := *credential
+	return &c, nil
+
+}
+
+func (r *CredentialRepository) Credentials(ctx context.Context, operators ...*krinder.Operator) ([]*krinder.Credential, error) {
+
+	r.mu.RLock()
+	var credentials = make([]*krinder.Credential, 0, len(r.credentials))
+	for _, credential := range r.credentials {
+		c := *credential
+		credentials = append(credentials, &c)
+	}
+	r.mu.RUnlock()
+
+	result, err := apply(credentials, operators...)
+	if err != nil {
+		return make([]*krinder.Credential, 0), err
+	}
+
+	return result.([]*krinder.Credential), nil
+
+}
+
+func (r *CredentialRepository) SaveCredential(ctx context.Context, credential *krinder.Credential) error {
+
+	now := time.Now().UTC()
+	if credential.CreatedAt.IsZero() {
+		credential.CreatedAt = now
+	}
+	credential.UpdatedAt = now
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := *credential
+	r.credentials[credentialKey(credential.UserID, credential.CharacterID)] = &c
+
+	return nil
+
+}
+
+func (r *CredentialRepository) DeleteCredential(ctx context.Context, userID string, characterID uint64) error {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := credentialKey(userID, characterID)
+	if _, ok := r.credentials[key]; !ok {
+		return krinder.ErrNotFound
+	}
+
+	delete(r.credentials, key)
+
+	return nil
+
+}
@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/eveisesi/krinder"
+)
+
+// DeadLetterRepository is an in process implementation of krinder.DeadLetterRepository.
+// Nothing is persisted, so it is only suitable for tests and local development
+type DeadLetterRepository struct {
+	mu      sync.RWMutex
+	letters []*krinder.DeadLetter
+}
+
+var _ krinder.DeadLetterRepository = new(DeadLetterRepository)
+
+func NewDeadLetterRepository() *DeadLetterRepository {
+	return &DeadLetterRepository{
+		letters: make([]*krinder.DeadLetter, 0),
+	}
+}
+
+func (r *DeadLetterRepository) CreateDeadLetter(ctx context.Context, letter *krinder.DeadLetter) error {
+
+	letter.CreatedAt = time.Now().UTC()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := *letter
+	r.letters = append(r.letters, &c)
+
+	return nil
+
+}
+
+func (r *DeadLetterRepository) DeadLetters(ctx context.Context, operators ...*krinder.Operator) ([]*krinder.DeadLetter, error) {
+
+	r.mu.RLock()
+	var letters = make([]*krinder.DeadLetter, 0, len(r.letters))
+	for _, letter := range r.letters {
+		c := *letter
+		letters = append(letters, &c)
+	}
+	r.mu.RUnlock()
+
+	result, err := apply(letters, operators...)
+	if err != nil {
+		return make([]*krinder.DeadLetter, 0), err
+	}
+
+	return result.([]*krinder.DeadLetter), nil
+
+}
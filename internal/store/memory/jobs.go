@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/eveisesi/krinder"
+)
+
+// JobRepository is an in process implementation of krinder.JobRepository.
+// Nothing is persisted, so jobs run again after a restart
+type JobRepository struct {
+	mu   sync.RWMutex
+	jobs map[string]*krinder.Job
+}
+
+var _ krinder.JobRepository = new(JobRepository)
+
+func NewJobRepository() *JobRepository {
+	return &JobRepository{
+		jobs: make(map[string]*krinder.Job),
+	}
+}
+
+func (r *JobRepository) Job(ctx context.Context, name string) (*krinder.Job, error) {
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	job, ok := r.jobs[name]
+	if !ok {
+		return new(krinder.Job), krinder.ErrNotFound
+	}
+
+	c := *job
+	return &c, nil
+
+}
+
+func (r *JobRepository) SaveJob(ctx context.Context, job *krinder.Job) error {
+
+	job.UpdatedAt = time.Now().UTC()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := *job
+	r.jobs[job.Name] = &c
+
+	return nil
+
+}
@@ -0,0 +1,37 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/eveisesi/krinder/internal/store/memory"
+	"github.com/eveisesi/krinder/internal/store/storetest"
+	"github.com/eveisesi/krinder/pkg/clock"
+)
+
+func TestUniverseRepository(t *testing.T) {
+	storetest.TestUniverseRepository(t, memory.NewUniverseRepository(clock.New()))
+}
+
+func TestWarRepository(t *testing.T) {
+	storetest.TestWarRepository(t, memory.NewWarRepository(clock.New()))
+}
+
+func TestJobRepository(t *testing.T) {
+	storetest.TestJobRepository(t, memory.NewJobRepository())
+}
+
+func TestDeadLetterRepository(t *testing.T) {
+	storetest.TestDeadLetterRepository(t, memory.NewDeadLetterRepository())
+}
+
+func TestAPIKeyRepository(t *testing.T) {
+	storetest.TestAPIKeyRepository(t, memory.NewAPIKeyRepository())
+}
+
+func TestCredentialRepository(t *testing.T) {
+	storetest.TestCredentialRepository(t, memory.NewCredentialRepository())
+}
+
+func TestPaginationRepository(t *testing.T) {
+	storetest.TestPaginationRepository(t, memory.NewPaginationRepository(clock.New()))
+}
@@ -0,0 +1,330 @@
+package memory
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eveisesi/krinder"
+)
+
+// apply evaluates the operators against records, which must be a slice of struct pointers.
+// Columns are resolved against the bson names of the struct fields, the same names used when the
+// records are stored in mongo, and dotted columns traverse nested structs. A slice of the same type
+// containing the matching records is returned
+func apply(records interface{}, operators ...*krinder.Operator) (interface{}, error) {
+
+	err := krinder.ValidateOperators(operators...)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := reflect.ValueOf(records)
+	out := reflect.MakeSlice(rv.Type(), 0, rv.Len())
+
+	for i := 0; i < rv.Len(); i++ {
+		record := rv.Index(i)
+		matched := true
+		for _, o := range operators {
+			if !o.Operation.IsFilter() {
+				continue
+			}
+			if !match(record, o) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			out = reflect.Append(out, record)
+		}
+	}
+
+	var orders = make([]*krinder.Operator, 0)
+	var limit, skip int64 = -1, 0
+	var projection []string
+	for _, o := range operators {
+		switch o.Operation {
+		case krinder.OrderOp:
+			orders = append(orders, o)
+		case krinder.LimitOp:
+			limit = o.Value.(int64)
+		case krinder.SkipOp:
+			skip = o.Value.(int64)
+		case krinder.ProjectOp:
+			projection = o.Value.([]string)
+		}
+	}
+
+	if len(orders) > 0 {
+		sort.SliceStable(out.Interface(), func(i, j int) bool {
+			for _, o := range orders {
+				a, _ := lookup(out.Index(i), o.Column)
+				b, _ := lookup(out.Index(j), o.Column)
+				c := compare(a, b)
+				if c == 0 {
+					continue
+				}
+				if o.SortValue() == krinder.SortDesc {
+					return c > 0
+				}
+				return c < 0
+			}
+			return false
+		})
+	}
+
+	if skip > int64(out.Len()) {
+		skip = int64(out.Len())
+	}
+	out = out.Slice(int(skip), out.Len())
+
+	if limit > 0 && limit < int64(out.Len()) {
+		out = out.Slice(0, int(limit))
+	}
+
+	if projection != nil {
+		projected := reflect.MakeSlice(out.Type(), 0, out.Len())
+		for i := 0; i < out.Len(); i++ {
+			projected = reflect.Append(projected, project(out.Index(i), projection))
+		}
+		out = projected
+	}
+
+	return out.Interface(), nil
+
+}
+
+func match(record reflect.Value, o *krinder.Operator) bool {
+
+	switch o.Operation {
+	case krinder.OrOp:
+		for _, child := range o.Value.([]*krinder.Operator) {
+			if match(record, child) {
+				return true
+			}
+		}
+		return false
+	case krinder.AndOp:
+		for _, child := range o.Value.([]*krinder.Operator) {
+			if !match(record, child) {
+				return false
+			}
+		}
+		return true
+	}
+
+	value, ok := lookup(record, o.Column)
+
+	switch o.Operation {
+	case krinder.EqualOp:
+		if o.Value == nil {
+			return !ok
+		}
+		return ok && compare(value, o.Value) == 0
+	case krinder.NotEqualOp:
+		if o.Value == nil {
+			return ok
+		}
+		return !ok || compare(value, o.Value) != 0
+	case krinder.GreaterThanOp:
+		return ok && sameKind(value, o.Value) && compare(value, o.Value) > 0
+	case krinder.GreaterThanEqualToOp:
+		return ok && sameKind(value, o.Value) && compare(value, o.Value) >= 0
+	case krinder.LessThanOp:
+		return ok && sameKind(value, o.Value) && compare(value, o.Value) < 0
+	case krinder.LessThanEqualToOp:
+		return ok && sameKind(value, o.Value) && compare(value, o.Value) <= 0
+	case krinder.InOp, krinder.NotInOp:
+		values, _ := o.ValueSlice()
+		found := false
+		for _, v := range values {
+			if ok && compare(value, v) == 0 {
+				found = true
+				break
+			}
+		}
+		if o.Operation == krinder.InOp {
+			return found
+		}
+		return !found
+	case krinder.LikeOp:
+		s, isString := value.(string)
+		return ok && isString && strings.Contains(strings.ToLower(s), strings.ToLower(o.Value.(string)))
+	case krinder.EqualFoldOp:
+		s, isString := value.(string)
+		return ok && isString && strings.EqualFold(s, o.Value.(string))
+	case krinder.ExistsOp:
+		return ok == o.Value.(bool)
+	}
+
+	return false
+
+}
+
+// lookup resolves a possibly dotted column against a record. The second return value is false
+// when the column does not exist on the record or is a nil pointer, mirroring a field that
+// was omitted from a mongo document
+func lookup(record reflect.Value, column string) (interface{}, bool) {
+
+	v := record
+	for _, part := range strings.Split(column, ".") {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return nil, false
+			}
+			v = v.Elem()
+		}
+
+		if v.Kind() != reflect.Struct {
+			return nil, false
+		}
+
+		field, ok := fieldByColumn(v, part)
+		if !ok {
+			return nil, false
+		}
+		v = field
+	}
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+
+	return v.Interface(), true
+
+}
+
+func fieldByColumn(v reflect.Value, column string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if columnName(t.Field(i)) == column {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// columnName returns the name the mongo driver would use for the field
+func columnName(field reflect.StructField) string {
+	if tag, ok := field.Tag.Lookup("bson"); ok {
+		name := strings.Split(tag, ",")[0]
+		if name != "" {
+			return name
+		}
+	}
+	return strings.ToLower(field.Name)
+}
+
+// project returns a copy of record with only the top level fields named by columns populated
+func project(record reflect.Value, columns []string) reflect.Value {
+
+	src := record.Elem()
+	dst := reflect.New(src.Type())
+
+	for _, column := range columns {
+		field, ok := fieldByColumn(src, strings.Split(column, ".")[0])
+		if !ok {
+			continue
+		}
+		target, _ := fieldByColumn(dst.Elem(), strings.Split(column, ".")[0])
+		target.Set(field)
+	}
+
+	return dst
+
+}
+
+// sameKind reports whether a and b are of the same kind of value, i.e. both numbers,
+// both strings, both times or both bools. Range operators never match values of differing kinds
+func sameKind(a, b interface{}) bool {
+	return kind(a) == kind(b) && kind(a) != ""
+}
+
+func kind(v interface{}) string {
+	if _, ok := v.(time.Time); ok {
+		return "time"
+	}
+
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	}
+	return ""
+}
+
+// compare returns -1, 0 or 1 if a is less than, equal to or greater than b.
+// nil sorts before all other values and values of differing kinds are ordered by kind
+func compare(a, b interface{}) int {
+
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	if !sameKind(a, b) {
+		return strings.Compare(kind(a)+fmt.Sprint(a), kind(b)+fmt.Sprint(b))
+	}
+
+	switch kind(a) {
+	case "string":
+		return strings.Compare(reflect.ValueOf(a).String(), reflect.ValueOf(b).String())
+	case "time":
+		at, bt := a.(time.Time), b.(time.Time)
+		switch {
+		case at.Before(bt):
+			return -1
+		case at.After(bt):
+			return 1
+		}
+		return 0
+	case "bool":
+		ab, bb := reflect.ValueOf(a).Bool(), reflect.ValueOf(b).Bool()
+		switch {
+		case ab == bb:
+			return 0
+		case !ab:
+			return -1
+		}
+		return 1
+	}
+
+	af, bf := number(a), number(b)
+	switch {
+	case af < bf:
+		return -1
+	case af > bf:
+		return 1
+	}
+	return 0
+
+}
+
+func number(v interface{}) float64 {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	}
+	return 0
+}
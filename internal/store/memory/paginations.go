@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/pkg/clock"
+)
+
+// PaginationRepository is an in process implementation of krinder.PaginationRepository.
+// Nothing is persisted, so the buttons of paginated replies stop working after a restart
+type PaginationRepository struct {
+	mu          sync.RWMutex
+	paginations map[string]*krinder.Pagination
+	clock       clock.Clock
+}
+
+var _ krinder.PaginationRepository = new(PaginationRepository)
+
+func NewPaginationRepository(clock clock.Clock) *PaginationRepository {
+	return &PaginationRepository{
+		paginations: make(map[string]*krinder.Pagination),
+		clock:       clock,
+	}
+}
+
+func (r *PaginationRepository) Pagination(ctx context.Context, messageID string) (*krinder.Pagination, error) {
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pagination, ok := r.paginations[messageID]
+	if !ok {
+		return new(krinder.Pagination), krinder.ErrNotFound
+	}
+
+	c := *pagination
+	c.Pages = append([]string(nil), pagination.Pages...)
+	return &c, nil
+
+}
+
+func (r *PaginationRepository) CreatePagination(ctx context.Context, pagination *krinder.Pagination) error {
+
+	pagination.CreatedAt = r.clock.Now().UTC()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.paginations[pagination.MessageID]; ok {
+		return krinder.ErrDuplicate
+	}
+
+	c := *pagination
+	c.Pages = append([]string(nil), pagination.Pages...)
+	r.paginations[pagination.MessageID] = &c
+
+	return nil
+
+}
+
+func (r *PaginationRepository) UpdatePaginationPage(ctx context.Context, messageID string, page int) error {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pagination, ok := r.paginations[messageID]
+	if !ok {
+		return krinder.ErrNotFound
+	}
+
+	pagination.Page = page
+
+	return nil
+
+}
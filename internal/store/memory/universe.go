@@ -0,0 +1,169 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/pkg/clock"
+)
+
+// UniverseRepository is an in process implementation of krinder.UniverseRepository.
+// Nothing is persisted, so it is only suitable for tests and local development
+type UniverseRepository struct {
+	mu       sync.RWMutex
+	groups   map[uint]*krinder.MySQLGroup
+	entities map[uint]*krinder.MongoEntity
+	clock    clock.Clock
+}
+
+var _ krinder.UniverseRepository = new(UniverseRepository)
+
+func NewUniverseRepository(clock clock.Clock) *UniverseRepository {
+	return &UniverseRepository{
+		groups:   make(map[uint]*krinder.MySQLGroup),
+		entities: make(map[uint]*krinder.MongoEntity),
+		clock:    clock,
+	}
+}
+
+func (r *UniverseRepository) Group(ctx context.Context, groupID uint) (*krinder.MySQLGroup, error) {
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	group, ok := r.groups[groupID]
+	if !ok {
+		return new(krinder.MySQLGroup), krinder.ErrNotFound
+	}
+
+	c := *group
+	return &c, nil
+
+}
+
+func (r *UniverseRepository) Groups(ctx context.Context, operators ...*krinder.Operator) ([]*krinder.MySQLGroup, error) {
+
+	r.mu.RLock()
+	var groups = make([]*krinder.MySQLGroup, 0, len(r.groups))
+	for _, group := range r.groups {
+		c := *group
+		groups = append(groups, &c)
+	}
+	r.mu.RUnlock()
+
+	result, err := apply(groups, operators...)
+	if err != nil {
+		return make([]*krinder.MySQLGroup, 0), err
+	}
+
+	return result.([]*krinder.MySQLGroup), nil
+
+}
+
+func (r *UniverseRepository) CreateGroup(ctx context.Context, group *krinder.MySQLGroup) (*krinder.MySQLGroup, error) {
+
+	group.CreatedAt = r.clock.Now().UTC()
+	group.UpdatedAt = r.clock.Now().UTC()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Duplicates are ignored, matching the mongo implementation
+	if _, ok := r.groups[group.GroupID]; !ok {
+		c := *group
+		r.groups[group.GroupID] = &c
+	}
+
+	return group, nil
+
+}
+
+func (r *UniverseRepository) UpdateGroup(ctx context.Context, group *krinder.MySQLGroup) (*krinder.MySQLGroup, error) {
+
+	group.UpdatedAt = r.clock.Now().UTC()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.groups[group.GroupID]
+	if !ok {
+		return group, nil
+	}
+
+	c := *group
+	c.CreatedAt = existing.CreatedAt
+	r.groups[group.GroupID] = &c
+
+	return group, nil
+
+}
+
+func (r *UniverseRepository) Entity(ctx context.Context, entityID uint) (*krinder.MongoEntity, error) {
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entity, ok := r.entities[entityID]
+	if !ok {
+		return new(krinder.MongoEntity), krinder.ErrNotFound
+	}
+
+	c := *entity
+	return &c, nil
+
+}
+
+func (r *UniverseRepository) Entitys(ctx context.Context, operators ...*krinder.Operator) ([]*krinder.MongoEntity, error) {
+
+	r.mu.RLock()
+	var entities = make([]*krinder.MongoEntity, 0, len(r.entities))
+	for _, entity := range r.entities {
+		c := *entity
+		entities = append(entities, &c)
+	}
+	r.mu.RUnlock()
+
+	result, err := apply(entities, operators...)
+	if err != nil {
+		return make([]*krinder.MongoEntity, 0), err
+	}
+
+	return result.([]*krinder.MongoEntity), nil
+
+}
+
+func (r *UniverseRepository) CreateEntity(ctx context.Context, entity *krinder.MongoEntity) (*krinder.MongoEntity, error) {
+
+	entity.CreatedAt = r.clock.Now().UTC()
+	entity.UpdatedAt = r.clock.Now().UTC()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Duplicates are ignored, matching the mongo implementation
+	if _, ok := r.entities[entity.ID]; !ok {
+		c := *entity
+		r.entities[entity.ID] = &c
+	}
+
+	return entity, nil
+
+}
+
+func (r *UniverseRepository) UpdateEntity(ctx context.Context, entity *krinder.MongoEntity) (*krinder.MongoEntity, error) {
+
+	entity.UpdatedAt = r.clock.Now().UTC()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.entities[entity.ID]; ok {
+		c := *entity
+		c.CreatedAt = existing.CreatedAt
+		r.entities[entity.ID] = &c
+	}
+
+	return entity, nil
+
+}
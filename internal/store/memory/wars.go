@@ -0,0 +1,165 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/pkg/clock"
+)
+
+// WarRepository is an in process implementation of krinder.WarRepository.
+// Nothing is persisted, so it is only suitable for tests and local development
+type WarRepository struct {
+	mu    sync.RWMutex
+	wars  map[uint]*krinder.MongoWar
+	clock clock.Clock
+}
+
+var _ krinder.WarRepository = new(WarRepository)
+
+func NewWarRepository(clock clock.Clock) *WarRepository {
+	return &WarRepository{
+		wars:  make(map[uint]*krinder.MongoWar),
+		clock: clock,
+	}
+}
+
+func (r *WarRepository) War(ctx context.Context, warID uint) (*krinder.MongoWar, error) {
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	war, ok := r.wars[warID]
+	if !ok {
+		return new(krinder.MongoWar), krinder.ErrNotFound
+	}
+
+	return copyWar(war), nil
+
+}
+
+func (r *WarRepository) Wars(ctx context.Context, operators ...*krinder.Operator) ([]*krinder.MongoWar, error) {
+
+	r.mu.RLock()
+	var wars = make([]*krinder.MongoWar, 0, len(r.wars))
+	for _, war := range r.wars {
+		wars = append(wars, copyWar(war))
+	}
+	r.mu.RUnlock()
+
+	result, err := apply(wars, operators...)
+	if err != nil {
+		return make([]*krinder.MongoWar, 0), err
+	}
+
+	return result.([]*krinder.MongoWar), nil
+
+}
+
+func (r *WarRepository) CreateWar(ctx context.Context, war *krinder.MongoWar) (*krinder.MongoWar, error) {
+
+	war.CreatedAt = r.clock.Now().UTC()
+	war.UpdatedAt = r.clock.Now().UTC()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Duplicates are ignored, matching the mongo implementation
+	if _, ok := r.wars[war.ID]; !ok {
+		r.wars[war.ID] = copyWar(war)
+	}
+
+	return war, nil
+
+}
+
+func (r *WarRepository) CreateWarBulk(ctx context.Context, wars []*krinder.MongoWar) error {
+
+	for _, war := range wars {
+		_, err := r.CreateWar(ctx, war)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+func (r *WarRepository) UpdateWar(ctx context.Context, war *krinder.MongoWar) error {
+
+	war.UpdatedAt = r.clock.Now().UTC()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.wars[war.ID]
+	if !ok {
+		return nil
+	}
+
+	c := copyWar(war)
+	c.CreatedAt = existing.CreatedAt
+	r.wars[war.ID] = c
+
+	return nil
+
+}
+
+// copyWar returns a deep copy of war, so that callers mutating a war they passed in or
+// got back cannot change the war held by the repository
+func copyWar(war *krinder.MongoWar) *krinder.MongoWar {
+
+	c := *war
+	c.Finished = copyTime(war.Finished)
+	c.Retracted = copyTime(war.Retracted)
+	c.ExpiresAt = copyTime(war.ExpiresAt)
+
+	if war.Aggressor != nil {
+		aggressor := *war.Aggressor
+		aggressor.AllianceID = copyUint(war.Aggressor.AllianceID)
+		aggressor.CorporationID = copyUint(war.Aggressor.CorporationID)
+		c.Aggressor = &aggressor
+	}
+
+	if war.Defender != nil {
+		defender := *war.Defender
+		defender.AllianceID = copyUint(war.Defender.AllianceID)
+		defender.CorporationID = copyUint(war.Defender.CorporationID)
+		c.Defender = &defender
+	}
+
+	if war.Allies != nil {
+		c.Allies = make([]*krinder.MongoWarAlly, len(war.Allies))
+		for i, ally := range war.Allies {
+			if ally == nil {
+				continue
+			}
+			c.Allies[i] = &krinder.MongoWarAlly{
+				AllianceID:    copyUint(ally.AllianceID),
+				CorporationID: copyUint(ally.CorporationID),
+			}
+		}
+	}
+
+	return &c
+
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+func copyUint(u *uint) *uint {
+	if u == nil {
+		return nil
+	}
+	c := *u
+	return &c
+}
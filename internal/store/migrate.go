@@ -0,0 +1,327 @@
+package store
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+const migrationsTable = "schema_migrations"
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a single versioned change to the MySQL schema. Migrations are embedded into the binary
+// from the migrations directory and are named <version>_<name>.<up|down>.sql
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a Migration has been applied to the database
+type MigrationStatus struct {
+	*Migration
+	AppliedAt *time.Time
+}
+
+// Migrator applies the embedded migrations to a MySQL database, tracking which
+// versions have been applied in the schema_migrations table
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []*Migration
+}
+
+func NewMigrator(db *sqlx.DB) (*Migrator, error) {
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+
+}
+
+func loadMigrations() ([]*Migration, error) {
+
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read embedded migrations")
+	}
+
+	var mapMigrations = make(map[uint]*Migration)
+	for _, entry := range entries {
+		matches := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, errors.Errorf("invalid migration file name %s, expected <version>_<name>.<up|down>.sql", entry.Name())
+		}
+
+		version, err := strconv.ParseUint(matches[1], 10, 32)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid version on migration %s", entry.Name())
+		}
+
+		data, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read migration %s", entry.Name())
+		}
+
+		migration, ok := mapMigrations[uint(version)]
+		if !ok {
+			migration = &Migration{Version: uint(version), Name: matches[2]}
+			mapMigrations[uint(version)] = migration
+		}
+
+		switch matches[3] {
+		case "up":
+			migration.Up = string(data)
+		case "down":
+			migration.Down = string(data)
+		}
+	}
+
+	var migrations = make([]*Migration, 0, len(mapMigrations))
+	for _, migration := range mapMigrations {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, errors.Errorf("migration %d_%s must have both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+
+}
+
+// Latest returns the highest migration version known to this binary
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) ensureMigrationsTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		version INT UNSIGNED NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		applied_at DATETIME NOT NULL,
+		PRIMARY KEY (version)
+	) ENGINE = InnoDB`, migrationsTable))
+	return errors.Wrap(err, "failed to create migrations table")
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[uint]time.Time, error) {
+
+	if err := m.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+
+	query, args, err := sq.Select("version", "applied_at").From(migrationsTable).ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate query")
+	}
+
+	var rows = make([]struct {
+		Version   uint      `db:"version"`
+		AppliedAt time.Time `db:"applied_at"`
+	}, 0)
+	err = m.db.SelectContext(ctx, &rows, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch applied migrations")
+	}
+
+	var applied = make(map[uint]time.Time, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row.AppliedAt
+	}
+
+	return applied, nil
+
+}
+
+// Version returns the highest migration version that has been applied to the database
+func (m *Migrator) Version(ctx context.Context) (uint, error) {
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var version uint
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+
+	return version, nil
+
+}
+
+// Check returns an error if the database has been migrated past the latest version this binary knows about.
+// This happens when an older binary is started against a database that a newer binary has already migrated
+func (m *Migrator) Check(ctx context.Context) error {
+
+	version, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	if version > m.Latest() {
+		return errors.Errorf("database schema is at version %d, but this binary only supports up to version %d. Please upgrade krinder", version, m.Latest())
+	}
+
+	return nil
+
+}
+
+// Up applies all pending migrations in order, returning the migrations that were applied
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+
+	if err := m.Check(ctx); err != nil {
+		return nil, err
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran = make([]*Migration, 0)
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		err = m.exec(ctx, migration.Up)
+		if err != nil {
+			return ran, errors.Wrapf(err, "failed to apply migration %d_%s", migration.Version, migration.Name)
+		}
+
+		query, args, err := sq.Insert(migrationsTable).SetMap(map[string]interface{}{
+			"version":    migration.Version,
+			"name":       migration.Name,
+			"applied_at": time.Now().UTC(),
+		}).ToSql()
+		if err != nil {
+			return ran, errors.Wrap(err, "failed to generate query")
+		}
+
+		_, err = m.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return ran, errors.Wrapf(err, "failed to record migration %d_%s", migration.Version, migration.Name)
+		}
+
+		ran = append(ran, migration)
+	}
+
+	return ran, nil
+
+}
+
+// Down reverts the most recently applied migration. If no migrations have been applied, nil is returned
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+
+	if err := m.Check(ctx); err != nil {
+		return nil, err
+	}
+
+	version, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if version == 0 {
+		return nil, nil
+	}
+
+	var migration *Migration
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			migration = mig
+			break
+		}
+	}
+
+	if migration == nil {
+		return nil, errors.Errorf("migration %d has been applied but is unknown to this binary", version)
+	}
+
+	err = m.exec(ctx, migration.Down)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to revert migration %d_%s", migration.Version, migration.Name)
+	}
+
+	query, args, err := sq.Delete(migrationsTable).Where(sq.Eq{"version": migration.Version}).ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate query")
+	}
+
+	_, err = m.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to remove record of migration %d_%s", migration.Version, migration.Name)
+	}
+
+	return migration, nil
+
+}
+
+// Status returns every known migration along with when it was applied, if it has been
+func (m *Migrator) Status(ctx context.Context) ([]*MigrationStatus, error) {
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses = make([]*MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := &MigrationStatus{Migration: migration}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+
+}
+
+// exec runs each statement in a migration individually since the driver
+// is not configured to allow multiple statements per query
+func (m *Migrator) exec(ctx context.Context, migration string) error {
+	for _, statement := range strings.Split(migration, ";") {
+		statement = strings.TrimSpace(statement)
+		if statement == "" {
+			continue
+		}
+
+		_, err := m.db.ExecContext(ctx, statement)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,4 +1,4 @@
-CREATE TABLE `groups` (
+CREATE TABLE IF NOT EXISTS `groups` (
     `category_id` INT(11) NOT NULL,
     `group_id` INT(11) NOT NULL,
     `name` VARCHAR(255) NOT NULL COLLATE 'utf8mb4_general_ci',
@@ -0,0 +1,2 @@
+ALTER TABLE `groups` MODIFY `name` VARCHAR(255) NOT NULL COLLATE 'utf8mb4_bin';
+ALTER TABLE `entities` MODIFY `name` VARCHAR(255) NOT NULL COLLATE 'utf8mb4_bin';
\ No newline at end of file
@@ -2,6 +2,7 @@ package store
 
 import (
 	"fmt"
+	"regexp"
 
 	"github.com/eveisesi/krinder"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -20,84 +21,115 @@ const (
 	notin            string = "$nin"
 	and              string = "$and"
 	or               string = "$or"
-	exists           string = "$exists"
 )
 
-func BuildMongoFilters(operators ...*krinder.Operator) primitive.D {
+// BuildMongoFilters converts the filter operators into a mongo filter document. Operators that shape
+// the result set rather than filter it are ignored here and handled by BuildMongoFindOptions.
+// When more than one filter is provided they are combined with $and, so multiple filters on the same
+// column do not overwrite each other
+func BuildMongoFilters(operators ...*krinder.Operator) (primitive.D, error) {
 
-	var ops = make(primitive.D, 0)
+	err := krinder.ValidateOperators(operators...)
+	if err != nil {
+		return nil, err
+	}
+
+	var conditions = make(primitive.A, 0, len(operators))
 	for _, a := range operators {
-		switch a.Operation {
-		case krinder.EqualOp:
-			ops = append(ops, primitive.E{Key: a.Column, Value: primitive.D{primitive.E{Key: equal, Value: a.Value}}})
-		case krinder.NotEqualOp:
-			ops = append(ops, primitive.E{Key: a.Column, Value: primitive.D{primitive.E{Key: notequal, Value: a.Value}}})
-		case krinder.GreaterThanOp:
-			ops = append(ops, primitive.E{Key: a.Column, Value: primitive.D{primitive.E{Key: greaterthan, Value: a.Value}}})
-		case krinder.GreaterThanEqualToOp:
-			ops = append(ops, primitive.E{Key: a.Column, Value: primitive.D{primitive.E{Key: greaterthanequal, Value: a.Value}}})
-		case krinder.LessThanOp:
-			ops = append(ops, primitive.E{Key: a.Column, Value: primitive.D{primitive.E{Key: lessthan, Value: a.Value}}})
-		case krinder.LessThanEqualToOp:
-			ops = append(ops, primitive.E{Key: a.Column, Value: primitive.D{primitive.E{Key: lessthanequal, Value: a.Value}}})
-		case krinder.ExistsOp:
-			ops = append(ops, primitive.E{Key: a.Column, Value: primitive.D{primitive.E{Key: exists, Value: a.Value.(bool)}}})
-		case krinder.OrOp:
-			switch o := a.Value.(type) {
-			case []*krinder.Operator:
-				arr := make(primitive.A, 0)
-
-				for _, op := range o {
-					arr = append(arr, BuildMongoFilters(op))
-				}
-
-				ops = append(ops, primitive.E{Key: or, Value: arr})
-			}
+		if !a.Operation.IsFilter() {
+			continue
+		}
 
-		case krinder.AndOp:
-			switch o := a.Value.(type) {
-			case []*krinder.Operator:
-				arr := make(primitive.A, 0)
-				for _, op := range o {
-					arr = append(arr, BuildMongoFilters(op))
-				}
+		condition, err := buildMongoCondition(a)
+		if err != nil {
+			return nil, err
+		}
 
-				ops = append(ops, primitive.E{Key: and, Value: arr})
-			}
+		conditions = append(conditions, condition)
+	}
 
-		case krinder.InOp:
-			switch o := a.Value.(type) {
-			case []krinder.OpValue:
-				arr := make(primitive.A, 0)
-				for _, value := range o {
-					arr = append(arr, value)
-				}
-
-				ops = append(ops, primitive.E{Key: a.Column, Value: primitive.D{primitive.E{Key: in, Value: arr}}})
-			default:
-				panic(fmt.Sprintf("valid type %#T supplied, expected one of [[]krinder.OpValue]", o))
-			}
-		case krinder.NotInOp:
-			switch o := a.Value.(type) {
-			case []krinder.OpValue:
-				arr := make(primitive.A, 0)
-				for _, value := range o {
-					arr = append(arr, value)
-				}
-
-				ops = append(ops, primitive.E{Key: a.Column, Value: primitive.D{primitive.E{Key: notin, Value: arr}}})
-			default:
-				panic(fmt.Sprintf("valid type %#T supplied, expected one of [[]krinder.OpValue]", o))
+	switch len(conditions) {
+	case 0:
+		return primitive.D{}, nil
+	case 1:
+		return conditions[0].(primitive.D), nil
+	}
+
+	return primitive.D{primitive.E{Key: and, Value: conditions}}, nil
+
+}
+
+func buildMongoCondition(a *krinder.Operator) (primitive.D, error) {
+
+	switch a.Operation {
+	case krinder.EqualOp:
+		return primitive.D{primitive.E{Key: a.Column, Value: primitive.D{primitive.E{Key: equal, Value: a.Value}}}}, nil
+	case krinder.NotEqualOp:
+		return primitive.D{primitive.E{Key: a.Column, Value: primitive.D{primitive.E{Key: notequal, Value: a.Value}}}}, nil
+	case krinder.GreaterThanOp:
+		return primitive.D{primitive.E{Key: a.Column, Value: primitive.D{primitive.E{Key: greaterthan, Value: a.Value}}}}, nil
+	case krinder.GreaterThanEqualToOp:
+		return primitive.D{primitive.E{Key: a.Column, Value: primitive.D{primitive.E{Key: greaterthanequal, Value: a.Value}}}}, nil
+	case krinder.LessThanOp:
+		return primitive.D{primitive.E{Key: a.Column, Value: primitive.D{primitive.E{Key: lessthan, Value: a.Value}}}}, nil
+	case krinder.LessThanEqualToOp:
+		return primitive.D{primitive.E{Key: a.Column, Value: primitive.D{primitive.E{Key: lessthanequal, Value: a.Value}}}}, nil
+	case krinder.LikeOp:
+		// Mirrors the behaviour of LOWER(column) LIKE LOWER('%value%') in MySQL
+		return primitive.D{primitive.E{Key: a.Column, Value: primitive.Regex{Pattern: regexp.QuoteMeta(a.Value.(string)), Options: "i"}}}, nil
+	case krinder.EqualFoldOp:
+		return primitive.D{primitive.E{Key: a.Column, Value: primitive.Regex{Pattern: fmt.Sprintf("^%s$", regexp.QuoteMeta(a.Value.(string))), Options: "i"}}}, nil
+	case krinder.ExistsOp:
+		// A field that is present but null is treated as not existing, matching IS NULL / IS NOT NULL in SQL
+		if a.Value.(bool) {
+			return primitive.D{primitive.E{Key: a.Column, Value: primitive.D{primitive.E{Key: notequal, Value: nil}}}}, nil
+		}
+		return primitive.D{primitive.E{Key: a.Column, Value: primitive.D{primitive.E{Key: equal, Value: nil}}}}, nil
+	case krinder.OrOp, krinder.AndOp:
+		key := and
+		if a.Operation == krinder.OrOp {
+			key = or
+		}
+
+		arr := make(primitive.A, 0)
+		for _, op := range a.Value.([]*krinder.Operator) {
+			condition, err := buildMongoCondition(op)
+			if err != nil {
+				return nil, err
 			}
+			arr = append(arr, condition)
+		}
+
+		return primitive.D{primitive.E{Key: key, Value: arr}}, nil
+	case krinder.InOp, krinder.NotInOp:
+		key := in
+		if a.Operation == krinder.NotInOp {
+			key = notin
+		}
+
+		values, err := a.ValueSlice()
+		if err != nil {
+			return nil, err
 		}
+
+		return primitive.D{primitive.E{Key: a.Column, Value: primitive.D{primitive.E{Key: key, Value: primitive.A(values)}}}}, nil
 	}
 
-	return ops
+	return nil, fmt.Errorf("unsupported filter operation %s", a.Operation)
 
 }
 
-func BuildMongoFindOptions(ops ...*krinder.Operator) *options.FindOptions {
+// BuildMongoFindOptions converts the limit, skip, order and projection operators into find options.
+// Multiple order operators result in a multi column sort applied in the order they were provided
+func BuildMongoFindOptions(ops ...*krinder.Operator) (*options.FindOptions, error) {
+
+	err := krinder.ValidateOperators(ops...)
+	if err != nil {
+		return nil, err
+	}
+
 	var opts = options.Find()
+	var sort = make(primitive.D, 0)
 	for _, a := range ops {
 		switch a.Operation {
 		case krinder.LimitOp:
@@ -105,9 +137,19 @@ func BuildMongoFindOptions(ops ...*krinder.Operator) *options.FindOptions {
 		case krinder.SkipOp:
 			opts.SetSkip(a.Value.(int64))
 		case krinder.OrderOp:
-			opts.SetSort(primitive.D{primitive.E{Key: a.Column, Value: a.Value}})
+			sort = append(sort, primitive.E{Key: a.Column, Value: a.SortValue().Value()})
+		case krinder.ProjectOp:
+			projection := make(primitive.D, 0)
+			for _, column := range a.Value.([]string) {
+				projection = append(projection, primitive.E{Key: column, Value: 1})
+			}
+			opts.SetProjection(projection)
 		}
 	}
 
-	return opts
+	if len(sort) > 0 {
+		opts.SetSort(sort)
+	}
+
+	return opts, nil
 }
@@ -2,45 +2,128 @@ package store
 
 import (
 	"fmt"
+	"math"
+	"strings"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/eveisesi/krinder"
 )
 
-func BuildSQLFilters(s sq.SelectBuilder, operators ...*krinder.Operator) sq.SelectBuilder {
+// likeEscaper escapes the wildcard characters so that the value of a LikeOp is matched literally,
+// the same way the mongo implementation quotes the value before building a regex
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// BuildSQLFilters applies the operators to the select builder. Projections are not applied here
+// since the columns of a SelectBuilder cannot be replaced, use BuildSQLColumns when creating the builder
+func BuildSQLFilters(s sq.SelectBuilder, operators ...*krinder.Operator) (sq.SelectBuilder, error) {
+
+	err := krinder.ValidateOperators(operators...)
+	if err != nil {
+		return s, err
+	}
+
+	var limit, skip bool
 	for _, a := range operators {
-		if !a.Operation.IsValid() {
-			continue
-		}
 
 		switch a.Operation {
-		case krinder.EqualOp:
-			s = s.Where(sq.Eq{a.Column: a.Value})
-		case krinder.NotEqualOp:
-			s = s.Where(sq.NotEq{a.Column: a.Value})
-		case krinder.GreaterThanEqualToOp:
-			s = s.Where(sq.GtOrEq{a.Column: a.Value})
-		case krinder.GreaterThanOp:
-			s = s.Where(sq.Gt{a.Column: a.Value})
-		case krinder.LessThanEqualToOp:
-			s = s.Where(sq.LtOrEq{a.Column: a.Value})
-		case krinder.LessThanOp:
-			s = s.Where(sq.Lt{a.Column: a.Value})
-		case krinder.InOp:
-			s = s.Where(sq.Eq{a.Column: a.Value.(interface{})})
-		case krinder.NotInOp:
-			s = s.Where(sq.NotEq{a.Column: a.Value.([]interface{})})
-		case krinder.LikeOp:
-			s = s.Where(sq.Like{a.Column: fmt.Sprintf("%%%v%%", a.Value)})
 		case krinder.OrderOp:
-			s = s.OrderBy(fmt.Sprintf("%s %s", a.Column, a.Value))
+			direction := "ASC"
+			if a.SortValue() == krinder.SortDesc {
+				direction = "DESC"
+			}
+			s = s.OrderBy(fmt.Sprintf("%s %s", a.Column, direction))
 		case krinder.LimitOp:
+			limit = true
 			s = s.Limit(uint64(a.Value.(int64)))
 		case krinder.SkipOp:
+			skip = true
 			s = s.Offset(uint64(a.Value.(int64)))
+		case krinder.ProjectOp:
+			continue
+		default:
+			condition, err := buildSQLCondition(a)
+			if err != nil {
+				return s, err
+			}
+			s = s.Where(condition)
+		}
+	}
+
+	// MySQL does not support an OFFSET without a LIMIT
+	if skip && !limit {
+		s = s.Limit(math.MaxUint64)
+	}
+
+	return s, nil
+
+}
+
+func buildSQLCondition(a *krinder.Operator) (sq.Sqlizer, error) {
+
+	switch a.Operation {
+	case krinder.EqualOp:
+		return sq.Eq{a.Column: a.Value}, nil
+	case krinder.NotEqualOp:
+		return sq.NotEq{a.Column: a.Value}, nil
+	case krinder.GreaterThanEqualToOp:
+		return sq.GtOrEq{a.Column: a.Value}, nil
+	case krinder.GreaterThanOp:
+		return sq.Gt{a.Column: a.Value}, nil
+	case krinder.LessThanEqualToOp:
+		return sq.LtOrEq{a.Column: a.Value}, nil
+	case krinder.LessThanOp:
+		return sq.Lt{a.Column: a.Value}, nil
+	case krinder.InOp:
+		values, err := a.ValueSlice()
+		if err != nil {
+			return nil, err
+		}
+		return sq.Eq{a.Column: values}, nil
+	case krinder.NotInOp:
+		values, err := a.ValueSlice()
+		if err != nil {
+			return nil, err
 		}
+		return sq.NotEq{a.Column: values}, nil
+	case krinder.LikeOp:
+		// name columns use a binary collation so that equality and ordering match Mongo, which makes LIKE
+		// case sensitive unless both sides are lowered
+		return sq.Expr(fmt.Sprintf("LOWER(%s) LIKE LOWER(?)", a.Column), fmt.Sprintf("%%%s%%", likeEscaper.Replace(a.Value.(string)))), nil
+	case krinder.EqualFoldOp:
+		return sq.Expr(fmt.Sprintf("LOWER(%s) = LOWER(?)", a.Column), a.Value), nil
+	case krinder.ExistsOp:
+		if a.Value.(bool) {
+			return sq.NotEq{a.Column: nil}, nil
+		}
+		return sq.Eq{a.Column: nil}, nil
+	case krinder.OrOp, krinder.AndOp:
+		conditions := make([]sq.Sqlizer, 0)
+		for _, op := range a.Value.([]*krinder.Operator) {
+			condition, err := buildSQLCondition(op)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, condition)
+		}
+
+		if a.Operation == krinder.OrOp {
+			return sq.Or(conditions), nil
+		}
+		return sq.And(conditions), nil
 	}
 
-	return s
+	return nil, fmt.Errorf("unsupported filter operation %s", a.Operation)
+
+}
 
+// BuildSQLColumns returns the columns of the last ProjectOp in operators, or columns if there isn't one
+func BuildSQLColumns(columns []string, operators ...*krinder.Operator) []string {
+	for _, a := range operators {
+		if a != nil && a.Operation == krinder.ProjectOp {
+			if projection, ok := a.Value.([]string); ok {
+				columns = projection
+			}
+		}
+	}
+	return columns
 }
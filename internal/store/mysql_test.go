@@ -0,0 +1,114 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/eveisesi/krinder"
+)
+
+func TestBuildSQLFilters(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		operators []*krinder.Operator
+		query     string
+		args      []interface{}
+	}{
+		{
+			name:      "order uses the direction rather than the sort value",
+			operators: []*krinder.Operator{krinder.NewOrderOperator("name", krinder.SortAsc), krinder.NewOrderOperator("group_id", krinder.SortDesc)},
+			query:     "SELECT id FROM t ORDER BY name ASC, group_id DESC",
+		},
+		{
+			name:      "or",
+			operators: []*krinder.Operator{krinder.NewOrOperator(krinder.NewEqualOperator("a", 1), krinder.NewEqualOperator("b", 2))},
+			query:     "SELECT id FROM t WHERE (a = ? OR b = ?)",
+			args:      []interface{}{1, 2},
+		},
+		{
+			name:      "and",
+			operators: []*krinder.Operator{krinder.NewAndOperator(krinder.NewEqualOperator("a", 1), krinder.NewExistsOperator("b", false))},
+			query:     "SELECT id FROM t WHERE (a = ? AND b IS NULL)",
+			args:      []interface{}{1},
+		},
+		{
+			name:      "exists",
+			operators: []*krinder.Operator{krinder.NewExistsOperator("b", true)},
+			query:     "SELECT id FROM t WHERE b IS NOT NULL",
+		},
+		{
+			name:      "in accepts typed slices",
+			operators: []*krinder.Operator{krinder.NewInOperator("a", []uint{1, 2})},
+			query:     "SELECT id FROM t WHERE a IN (?,?)",
+			args:      []interface{}{uint(1), uint(2)},
+		},
+		{
+			name:      "like escapes wildcards",
+			operators: []*krinder.Operator{krinder.NewLikeOperator("name", "10%_")},
+			query:     "SELECT id FROM t WHERE LOWER(name) LIKE LOWER(?)",
+			args:      []interface{}{`%10\%\_%`},
+		},
+		{
+			name:      "equal fold",
+			operators: []*krinder.Operator{krinder.NewEqualFoldOperator("name", "Rifter")},
+			query:     "SELECT id FROM t WHERE LOWER(name) = LOWER(?)",
+			args:      []interface{}{"Rifter"},
+		},
+		{
+			name:      "skip without limit",
+			operators: []*krinder.Operator{krinder.NewSkipOperator(5)},
+			query:     "SELECT id FROM t LIMIT 18446744073709551615 OFFSET 5",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			builder, err := BuildSQLFilters(sq.Select("id").From("t"), tt.operators...)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			query, args, err := builder.ToSql()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if query != tt.query {
+				t.Errorf("expected query %q, got %q", tt.query, query)
+			}
+
+			if len(tt.args) != 0 || len(args) != 0 {
+				if !reflect.DeepEqual(tt.args, args) {
+					t.Errorf("expected args %#v, got %#v", tt.args, args)
+				}
+			}
+		})
+	}
+
+}
+
+func TestBuildSQLFiltersInvalidOperator(t *testing.T) {
+
+	_, err := BuildSQLFilters(sq.Select("id").From("t"), krinder.NewInOperator("a", "not a slice"))
+	if err == nil {
+		t.Fatal("expected an error for an In operator with a scalar value")
+	}
+
+}
+
+func TestBuildSQLColumns(t *testing.T) {
+
+	columns := BuildSQLColumns([]string{"a", "b", "c"}, krinder.NewEqualOperator("a", 1), krinder.NewProjectOperator("a", "b"))
+	if !reflect.DeepEqual(columns, []string{"a", "b"}) {
+		t.Errorf("expected projected columns, got %v", columns)
+	}
+
+	columns = BuildSQLColumns([]string{"a", "b", "c"})
+	if !reflect.DeepEqual(columns, []string{"a", "b", "c"}) {
+		t.Errorf("expected default columns, got %v", columns)
+	}
+
+}
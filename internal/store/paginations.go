@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/pkg/clock"
+	"github.com/pkg/errors"
+	"github.com/volatiletech/null"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type PaginationRepository struct {
+	paginations *mongo.Collection
+	clock       clock.Clock
+}
+
+var _ krinder.PaginationRepository = new(PaginationRepository)
+
+func NewPaginationRepository(database *mongo.Database, clock clock.Clock) (*PaginationRepository, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+	paginations := database.Collection("paginations")
+
+	_, err := paginations.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				primitive.E{Key: "messageID", Value: 1},
+			},
+			Options: &options.IndexOptions{
+				Unique: null.BoolFrom(true).Ptr(),
+			},
+		},
+		{
+			// Mongo removes paginations once they expire
+			Keys: bson.D{
+				primitive.E{Key: "expiresAt", Value: 1},
+			},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create indexes")
+	}
+
+	return &PaginationRepository{
+		paginations: paginations,
+		clock:       clock,
+	}, nil
+
+}
+
+func (r *PaginationRepository) Pagination(ctx context.Context, messageID string) (*krinder.Pagination, error) {
+
+	var pagination = new(krinder.Pagination)
+
+	err := r.paginations.FindOne(ctx, primitive.D{primitive.E{Key: "messageID", Value: messageID}}).Decode(pagination)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = krinder.ErrNotFound
+	}
+
+	return pagination, err
+
+}
+
+func (r *PaginationRepository) CreatePagination(ctx context.Context, pagination *krinder.Pagination) error {
+
+	pagination.CreatedAt = r.clock.Now().UTC()
+
+	_, err := r.paginations.InsertOne(ctx, pagination)
+	if mongo.IsDuplicateKeyError(err) {
+		return krinder.ErrDuplicate
+	}
+
+	return err
+
+}
+
+func (r *PaginationRepository) UpdatePaginationPage(ctx context.Context, messageID string, page int) error {
+
+	result, err := r.paginations.UpdateOne(
+		ctx,
+		primitive.D{primitive.E{Key: "messageID", Value: messageID}},
+		primitive.D{primitive.E{Key: "$set", Value: primitive.D{primitive.E{Key: "page", Value: page}}}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return krinder.ErrNotFound
+	}
+
+	return nil
+
+}
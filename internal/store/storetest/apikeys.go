@@ -0,0 +1,70 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/eveisesi/krinder"
+)
+
+// TestAPIKeyRepository runs the conformance suite against repo. The repository must be empty when the suite starts
+func TestAPIKeyRepository(t *testing.T, repo krinder.APIKeyRepository) {
+
+	ctx := context.Background()
+
+	key, err := repo.APIKey(ctx, "unknown")
+	if !errors.Is(err, krinder.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for an unknown key, got %v", err)
+	}
+	if key == nil {
+		t.Fatalf("expected an empty key alongside ErrNotFound")
+	}
+
+	err = repo.CreateAPIKey(ctx, &krinder.APIKey{Name: "corp-tools", Hash: "abc", RateLimit: 30})
+	if err != nil {
+		t.Fatalf("failed to create key: %s", err)
+	}
+
+	err = repo.CreateAPIKey(ctx, &krinder.APIKey{Name: "corp-tools", Hash: "def"})
+	if !errors.Is(err, krinder.ErrDuplicate) {
+		t.Errorf("expected ErrDuplicate for a duplicate name, got %v", err)
+	}
+
+	err = repo.CreateAPIKey(ctx, &krinder.APIKey{Name: "dashboard", Hash: "def"})
+	if err != nil {
+		t.Fatalf("failed to create key: %s", err)
+	}
+
+	key, err = repo.APIKey(ctx, "abc")
+	if err != nil {
+		t.Fatalf("failed to fetch key: %s", err)
+	}
+	if key.Name != "corp-tools" || key.RateLimit != 30 || key.CreatedAt.IsZero() {
+		t.Errorf("unexpected key %+v", key)
+	}
+
+	keys, err := repo.APIKeys(ctx, krinder.NewOrderOperator("name", krinder.SortAsc))
+	if err != nil {
+		t.Fatalf("failed to list keys: %s", err)
+	}
+	if len(keys) != 2 || keys[0].Name != "corp-tools" || keys[1].Name != "dashboard" {
+		t.Errorf("unexpected keys %+v", keys)
+	}
+
+	err = repo.DeleteAPIKey(ctx, "corp-tools")
+	if err != nil {
+		t.Fatalf("failed to delete key: %s", err)
+	}
+
+	_, err = repo.APIKey(ctx, "abc")
+	if !errors.Is(err, krinder.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a deleted key, got %v", err)
+	}
+
+	err = repo.DeleteAPIKey(ctx, "corp-tools")
+	if !errors.Is(err, krinder.ErrNotFound) {
+		t.Errorf("expected ErrNotFound when deleting a missing key, got %v", err)
+	}
+
+}
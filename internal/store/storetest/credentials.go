@@ -0,0 +1,75 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/eveisesi/krinder"
+)
+
+// TestCredentialRepository runs the conformance suite against repo. The repository must be empty when the suite starts
+func TestCredentialRepository(t *testing.T, repo krinder.CredentialRepository) {
+
+	ctx := context.Background()
+
+	credential, err := repo.Credential(ctx, "user", 90000001)
+	if !errors.Is(err, krinder.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for an unknown credential, got %v", err)
+	}
+	if credential == nil {
+		t.Fatalf("expected an empty credential alongside ErrNotFound")
+	}
+
+	for _, c := range []*krinder.Credential{
+		{UserID: "user", CharacterID: 90000001, CharacterName: "Main", Main: true, Scopes: []string{"publicData"}, RefreshToken: "sealed"},
+		{UserID: "user", CharacterID: 90000002, CharacterName: "Alt"},
+		{UserID: "other", CharacterID: 90000001, CharacterName: "Main"},
+	} {
+		if err := repo.SaveCredential(ctx, c); err != nil {
+			t.Fatalf("failed to save credential: %s", err)
+		}
+	}
+
+	credential, err = repo.Credential(ctx, "user", 90000001)
+	if err != nil {
+		t.Fatalf("failed to fetch credential: %s", err)
+	}
+	if credential.CharacterName != "Main" || !credential.Main || credential.RefreshToken != "sealed" || len(credential.Scopes) != 1 {
+		t.Errorf("unexpected credential %+v", credential)
+	}
+	if credential.CreatedAt.IsZero() || credential.UpdatedAt.IsZero() {
+		t.Errorf("expected CreatedAt and UpdatedAt to be set")
+	}
+
+	// Saving again replaces the credential
+	credential.Main = false
+	credential.RefreshToken = "rotated"
+	if err := repo.SaveCredential(ctx, credential); err != nil {
+		t.Fatalf("failed to save credential: %s", err)
+	}
+
+	credentials, err := repo.Credentials(ctx, krinder.NewEqualOperator("userID", "user"))
+	if err != nil {
+		t.Fatalf("failed to fetch credentials: %s", err)
+	}
+	if len(credentials) != 2 {
+		t.Fatalf("expected 2 credentials for the user, got %d", len(credentials))
+	}
+	for _, c := range credentials {
+		if c.CharacterID == 90000001 && (c.Main || c.RefreshToken != "rotated") {
+			t.Errorf("expected the credential to be replaced, got %+v", c)
+		}
+	}
+
+	err = repo.DeleteCredential(ctx, "user", 90000002)
+	if err != nil {
+		t.Fatalf("failed to delete credential: %s", err)
+	}
+
+	err = repo.DeleteCredential(ctx, "user", 90000002)
+	if !errors.Is(err, krinder.ErrNotFound) {
+		t.Errorf("expected ErrNotFound when deleting a missing credential, got %v", err)
+	}
+
+}
@@ -0,0 +1,60 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eveisesi/krinder"
+)
+
+// TestDeadLetterRepository runs the conformance suite against repo. The repository must be empty when the suite starts
+func TestDeadLetterRepository(t *testing.T, repo krinder.DeadLetterRepository) {
+
+	ctx := context.Background()
+
+	letters, err := repo.DeadLetters(ctx)
+	if err != nil {
+		t.Fatalf("failed to fetch dead letters: %s", err)
+	}
+	if len(letters) != 0 {
+		t.Fatalf("expected no dead letters, got %d", len(letters))
+	}
+
+	for _, sink := range []string{"webhook:dashboard", "discord:123"} {
+		err = repo.CreateDeadLetter(ctx, &krinder.DeadLetter{
+			Sink:     sink,
+			Kind:     "war.declared",
+			Payload:  `{"kind":"war.declared"}`,
+			Error:    "boom",
+			Attempts: 3,
+		})
+		if err != nil {
+			t.Fatalf("failed to create dead letter: %s", err)
+		}
+	}
+
+	letters, err = repo.DeadLetters(ctx, krinder.NewEqualOperator("sink", "webhook:dashboard"))
+	if err != nil {
+		t.Fatalf("failed to fetch dead letters: %s", err)
+	}
+	if len(letters) != 1 {
+		t.Fatalf("expected 1 dead letter for the webhook, got %d", len(letters))
+	}
+
+	letter := letters[0]
+	if letter.Kind != "war.declared" || letter.Payload != `{"kind":"war.declared"}` || letter.Error != "boom" || letter.Attempts != 3 {
+		t.Errorf("unexpected dead letter %+v", letter)
+	}
+	if letter.CreatedAt.IsZero() {
+		t.Errorf("expected CreatedAt to be set")
+	}
+
+	letters, err = repo.DeadLetters(ctx, krinder.NewOrderOperator("createdAt", krinder.SortDesc), krinder.NewLimitOperator(1))
+	if err != nil {
+		t.Fatalf("failed to fetch dead letters: %s", err)
+	}
+	if len(letters) != 1 {
+		t.Errorf("expected the limit to be applied, got %d dead letters", len(letters))
+	}
+
+}
@@ -0,0 +1,71 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/krinder"
+)
+
+// TestJobRepository runs the conformance suite against repo. The repository must be empty when the suite starts
+func TestJobRepository(t *testing.T, repo krinder.JobRepository) {
+
+	ctx := context.Background()
+
+	job, err := repo.Job(ctx, "wars")
+	if !errors.Is(err, krinder.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for an unknown job, got %v", err)
+	}
+	if job == nil || !job.NextRun.IsZero() {
+		t.Fatalf("expected an empty job alongside ErrNotFound, got %+v", job)
+	}
+
+	// Mongo stores times with millisecond precision
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	err = repo.SaveJob(ctx, &krinder.Job{
+		Name:      "wars",
+		LastRun:   now,
+		LastError: "boom",
+		NextRun:   now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("failed to save job: %s", err)
+	}
+
+	job, err = repo.Job(ctx, "wars")
+	if err != nil {
+		t.Fatalf("failed to fetch job: %s", err)
+	}
+	if !job.LastRun.Equal(now) || !job.NextRun.Equal(now.Add(time.Hour)) || job.LastError != "boom" {
+		t.Errorf("unexpected job %+v", job)
+	}
+	if job.UpdatedAt.IsZero() {
+		t.Errorf("expected UpdatedAt to be set")
+	}
+
+	// Saving again replaces the job
+	err = repo.SaveJob(ctx, &krinder.Job{
+		Name:        "wars",
+		LastRun:     now,
+		LastSuccess: now,
+		NextRun:     now.Add(time.Hour * 2),
+	})
+	if err != nil {
+		t.Fatalf("failed to save job: %s", err)
+	}
+
+	job, err = repo.Job(ctx, "wars")
+	if err != nil {
+		t.Fatalf("failed to fetch job: %s", err)
+	}
+	if job.LastError != "" || !job.LastSuccess.Equal(now) || !job.NextRun.Equal(now.Add(time.Hour*2)) {
+		t.Errorf("expected the job to be replaced, got %+v", job)
+	}
+
+	if _, err = repo.Job(ctx, "universe"); !errors.Is(err, krinder.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for another job, got %v", err)
+	}
+
+}
@@ -0,0 +1,62 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/krinder"
+)
+
+// TestPaginationRepository runs the conformance suite against repo. The repository must be empty when the suite starts
+func TestPaginationRepository(t *testing.T, repo krinder.PaginationRepository) {
+
+	ctx := context.Background()
+
+	pagination, err := repo.Pagination(ctx, "1001")
+	if !errors.Is(err, krinder.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for an unknown pagination, got %v", err)
+	}
+	if pagination == nil {
+		t.Fatalf("expected an empty pagination alongside ErrNotFound")
+	}
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	err = repo.CreatePagination(ctx, &krinder.Pagination{
+		MessageID: "1001",
+		ChannelID: "2001",
+		AuthorID:  "3001",
+		Pages:     []string{`{"title":"first"}`, `{"title":"second"}`},
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("failed to create pagination: %s", err)
+	}
+
+	err = repo.CreatePagination(ctx, &krinder.Pagination{MessageID: "1001", ExpiresAt: expiresAt})
+	if !errors.Is(err, krinder.ErrDuplicate) {
+		t.Errorf("expected ErrDuplicate for a second pagination of the message, got %v", err)
+	}
+
+	if err := repo.UpdatePaginationPage(ctx, "1001", 1); err != nil {
+		t.Fatalf("failed to update page: %s", err)
+	}
+
+	pagination, err = repo.Pagination(ctx, "1001")
+	if err != nil {
+		t.Fatalf("failed to fetch pagination: %s", err)
+	}
+	if pagination.ChannelID != "2001" || pagination.AuthorID != "3001" || len(pagination.Pages) != 2 || pagination.Pages[1] != `{"title":"second"}` {
+		t.Errorf("unexpected pagination %+v", pagination)
+	}
+	if pagination.Page != 1 || !pagination.ExpiresAt.Equal(expiresAt) || pagination.CreatedAt.IsZero() {
+		t.Errorf("expected page 1 expiring at %s with CreatedAt set, got %+v", expiresAt, pagination)
+	}
+
+	err = repo.UpdatePaginationPage(ctx, "1002", 1)
+	if !errors.Is(err, krinder.ErrNotFound) {
+		t.Errorf("expected ErrNotFound when updating an unknown pagination, got %v", err)
+	}
+
+}
@@ -0,0 +1,334 @@
+// Package storetest contains conformance suites that every implementation of the
+// krinder repository interfaces must pass, so that each backend returns the same
+// results for the same operators
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/krinder"
+)
+
+// Column names shared by every universe backend
+const (
+	groupCategoryID = "category_id"
+	groupGroupID    = "group_id"
+	groupName       = "name"
+	groupPublished  = "published"
+	entityID        = "id"
+	entityName      = "name"
+)
+
+var groupFixtures = []*krinder.MySQLGroup{
+	{GroupID: 1, CategoryID: 6, Name: "Frigate", Published: true},
+	{GroupID: 2, CategoryID: 6, Name: "Cruiser", Published: true},
+	{GroupID: 3, CategoryID: 6, Name: "Battleship", Published: true},
+	{GroupID: 4, CategoryID: 9, Name: "Frigate Blueprint", Published: true},
+	{GroupID: 5, CategoryID: 6, Name: "Shuttle", Published: false},
+	{GroupID: 6, CategoryID: 2, Name: "100% Pure_Ore", Published: true},
+	// Lower case, so that every backend must compare names by their bytes rather than a case insensitive collation
+	{GroupID: 7, CategoryID: 25, Name: "arkonor", Published: false},
+}
+
+var entityFixtures = []*krinder.MongoEntity{
+	{ID: 587, Name: "Rifter", Published: true},
+	{ID: 670, Name: "Capsule", Published: true},
+	{ID: 11377, Name: "Nemesis", Published: true},
+}
+
+type operatorCase struct {
+	name      string
+	operators []*krinder.Operator
+	// expected IDs, compared in order when ordered is true
+	expected []uint
+	ordered  bool
+}
+
+var groupCases = []operatorCase{
+	{name: "no operators", expected: []uint{1, 2, 3, 4, 5, 6, 7}},
+	{name: "equal", operators: ops(krinder.NewEqualOperator(groupCategoryID, 6)), expected: []uint{1, 2, 3, 5}},
+	{name: "equal bool", operators: ops(krinder.NewEqualOperator(groupPublished, false)), expected: []uint{5, 7}},
+	{name: "equal is case sensitive", operators: ops(krinder.NewEqualOperator(groupName, "Arkonor")), expected: []uint{}},
+	{name: "not equal", operators: ops(krinder.NewNotEqualOperator(groupCategoryID, 6)), expected: []uint{4, 6, 7}},
+	{name: "greater than", operators: ops(krinder.NewGreaterThanOperator(groupGroupID, 4)), expected: []uint{5, 6, 7}},
+	{name: "greater than equal to", operators: ops(krinder.NewGreaterThanEqualToOperator(groupGroupID, 4)), expected: []uint{4, 5, 6, 7}},
+	{name: "less than", operators: ops(krinder.NewLessThanOperator(groupGroupID, 2)), expected: []uint{1}},
+	{name: "less than equal to", operators: ops(krinder.NewLessThanEqualToOperator(groupGroupID, 2)), expected: []uint{1, 2}},
+	{name: "range on one column", operators: ops(krinder.NewGreaterThanOperator(groupGroupID, 1), krinder.NewLessThanOperator(groupGroupID, 4)), expected: []uint{2, 3}},
+	{name: "in typed slice", operators: ops(krinder.NewInOperator(groupCategoryID, []uint{2, 9})), expected: []uint{4, 6}},
+	{name: "in op values", operators: ops(krinder.NewInOperator(groupCategoryID, []krinder.OpValue{9})), expected: []uint{4}},
+	{name: "in empty", operators: ops(krinder.NewInOperator(groupCategoryID, []uint{})), expected: []uint{}},
+	{name: "not in", operators: ops(krinder.NewNotInOperator(groupCategoryID, []interface{}{6})), expected: []uint{4, 6, 7}},
+	{name: "like is case insensitive", operators: ops(krinder.NewLikeOperator(groupName, "RIG")), expected: []uint{1, 4}},
+	{name: "like matches wildcards literally", operators: ops(krinder.NewLikeOperator(groupName, "%")), expected: []uint{6}},
+	{name: "like matches underscore literally", operators: ops(krinder.NewLikeOperator(groupName, "e_O")), expected: []uint{6}},
+	{name: "like lower case value", operators: ops(krinder.NewLikeOperator(groupName, "ARK")), expected: []uint{7}},
+	{name: "equal fold", operators: ops(krinder.NewEqualFoldOperator(groupName, "FRIGATE")), expected: []uint{1}},
+	{name: "equal fold lower case value", operators: ops(krinder.NewEqualFoldOperator(groupName, "Arkonor")), expected: []uint{7}},
+	{name: "exists", operators: ops(krinder.NewExistsOperator(groupName, true)), expected: []uint{1, 2, 3, 4, 5, 6, 7}},
+	{name: "not exists", operators: ops(krinder.NewExistsOperator(groupName, false)), expected: []uint{}},
+	{
+		name:      "or",
+		operators: ops(krinder.NewOrOperator(krinder.NewEqualOperator(groupCategoryID, 9), krinder.NewEqualOperator(groupGroupID, 1))),
+		expected:  []uint{1, 4},
+	},
+	{
+		name:      "and",
+		operators: ops(krinder.NewAndOperator(krinder.NewEqualOperator(groupCategoryID, 6), krinder.NewEqualOperator(groupPublished, false))),
+		expected:  []uint{5},
+	},
+	{
+		name: "nested or inside and",
+		operators: ops(krinder.NewAndOperator(
+			krinder.NewEqualOperator(groupPublished, true),
+			krinder.NewOrOperator(krinder.NewLikeOperator(groupName, "frigate"), krinder.NewEqualOperator(groupCategoryID, 2)),
+		)),
+		expected: []uint{1, 4, 6},
+	},
+	{
+		name:      "multiple ors",
+		operators: ops(krinder.NewOrOperator(krinder.NewEqualOperator(groupGroupID, 1), krinder.NewEqualOperator(groupGroupID, 2)), krinder.NewOrOperator(krinder.NewEqualOperator(groupGroupID, 2), krinder.NewEqualOperator(groupGroupID, 3))),
+		expected:  []uint{2},
+	},
+	{
+		name:      "order",
+		operators: ops(krinder.NewOrderOperator(groupGroupID, krinder.SortDesc)),
+		expected:  []uint{7, 6, 5, 4, 3, 2, 1},
+		ordered:   true,
+	},
+	{
+		name:      "multi column order",
+		operators: ops(krinder.NewOrderOperator(groupCategoryID, krinder.SortAsc), krinder.NewOrderOperator(groupName, krinder.SortDesc)),
+		expected:  []uint{6, 5, 1, 2, 3, 4, 7},
+		ordered:   true,
+	},
+	{
+		// Upper case sorts before lower case, as it does when comparing bytes
+		name:      "order is case sensitive",
+		operators: ops(krinder.NewOrderOperator(groupName, krinder.SortAsc)),
+		expected:  []uint{6, 3, 2, 1, 4, 5, 7},
+		ordered:   true,
+	},
+	{
+		name:      "limit and skip",
+		operators: ops(krinder.NewOrderOperator(groupGroupID, krinder.SortAsc), krinder.NewSkipOperator(1), krinder.NewLimitOperator(2)),
+		expected:  []uint{2, 3},
+		ordered:   true,
+	},
+	{
+		name:      "skip without limit",
+		operators: ops(krinder.NewOrderOperator(groupGroupID, krinder.SortAsc), krinder.NewSkipOperator(4)),
+		expected:  []uint{5, 6, 7},
+		ordered:   true,
+	},
+}
+
+var invalidCases = []struct {
+	name      string
+	operators []*krinder.Operator
+}{
+	{name: "nil operator", operators: ops(nil)},
+	{name: "unknown operation", operators: ops(&krinder.Operator{Column: groupName, Operation: krinder.Operation(999)})},
+	{name: "in with scalar", operators: ops(krinder.NewInOperator(groupCategoryID, 6))},
+	{name: "not in with nil", operators: ops(krinder.NewNotInOperator(groupCategoryID, nil))},
+	{name: "like with int", operators: ops(krinder.NewLikeOperator(groupName, 6))},
+	{name: "exists with string", operators: ops(&krinder.Operator{Column: groupName, Operation: krinder.ExistsOp, Value: "yes"})},
+	{name: "limit with int", operators: ops(&krinder.Operator{Operation: krinder.LimitOp, Value: 5})},
+	{name: "negative skip", operators: ops(krinder.NewSkipOperator(-1))},
+	{name: "order with invalid column", operators: ops(krinder.NewOrderOperator("name; DROP TABLE groups", krinder.SortAsc))},
+	{name: "order with invalid sort", operators: ops(&krinder.Operator{Column: groupName, Operation: krinder.OrderOp, Value: 2})},
+	{name: "or containing limit", operators: ops(krinder.NewOrOperator(krinder.NewLimitOperator(1)))},
+	{name: "empty and", operators: ops(krinder.NewAndOperator())},
+	{name: "empty projection", operators: ops(krinder.NewProjectOperator())},
+	{name: "missing column", operators: ops(krinder.NewEqualOperator("", 1))},
+}
+
+func ops(operators ...*krinder.Operator) []*krinder.Operator {
+	return operators
+}
+
+// TestUniverseRepository runs the conformance suite against repo. The repository must be empty when the suite starts
+func TestUniverseRepository(t *testing.T, repo krinder.UniverseRepository) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	for _, fixture := range groupFixtures {
+		group := *fixture
+		group.Expires = expires
+		_, err := repo.CreateGroup(ctx, &group)
+		if err != nil {
+			t.Fatalf("failed to create group %d: %s", group.GroupID, err)
+		}
+	}
+
+	for _, fixture := range entityFixtures {
+		entity := *fixture
+		entity.Expires = expires
+		_, err := repo.CreateEntity(ctx, &entity)
+		if err != nil {
+			t.Fatalf("failed to create entity %d: %s", entity.ID, err)
+		}
+	}
+
+	t.Run("Group", func(t *testing.T) {
+		group, err := repo.Group(ctx, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if group.Name != "Cruiser" || group.CategoryID != 6 || !group.Published {
+			t.Errorf("unexpected group %+v", group)
+		}
+		if !group.Expires.Equal(expires) {
+			t.Errorf("expected expires %s, got %s", expires, group.Expires)
+		}
+
+		group, err = repo.Group(ctx, 1000)
+		if !errors.Is(err, krinder.ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+		if group == nil {
+			t.Errorf("expected an empty group alongside ErrNotFound")
+		}
+
+		// Creating a duplicate group is not an error, and does not overwrite the existing group
+		_, err = repo.CreateGroup(ctx, &krinder.MySQLGroup{GroupID: 2, CategoryID: 6, Name: "Duplicate", Published: true, Expires: expires})
+		if err != nil {
+			t.Errorf("unexpected error creating duplicate group: %s", err)
+		}
+		group, err = repo.Group(ctx, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if group.Name != "Cruiser" {
+			t.Errorf("expected duplicate group to be ignored, got %+v", group)
+		}
+	})
+
+	t.Run("UpdateGroup", func(t *testing.T) {
+		group, err := repo.Group(ctx, 5)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		group.Etag = "updated"
+		_, err = repo.UpdateGroup(ctx, group)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		group, err = repo.Group(ctx, 5)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if group.Etag != "updated" || group.Name != "Shuttle" {
+			t.Errorf("update was not persisted, got %+v", group)
+		}
+	})
+
+	t.Run("Groups", func(t *testing.T) {
+		for _, c := range groupCases {
+			c := c
+			t.Run(c.name, func(t *testing.T) {
+				groups, err := repo.Groups(ctx, c.operators...)
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				ids := make([]uint, 0, len(groups))
+				for _, group := range groups {
+					ids = append(ids, group.GroupID)
+				}
+				assertIDs(t, c.expected, ids, c.ordered)
+			})
+		}
+	})
+
+	t.Run("GroupsProjection", func(t *testing.T) {
+		groups, err := repo.Groups(ctx, krinder.NewEqualOperator(groupGroupID, 1), krinder.NewProjectOperator(groupGroupID, groupName))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(groups) != 1 {
+			t.Fatalf("expected 1 group, got %d", len(groups))
+		}
+		if groups[0].Name != "Frigate" || groups[0].CategoryID != 0 {
+			t.Errorf("expected only the projected columns to be populated, got %+v", groups[0])
+		}
+	})
+
+	t.Run("GroupsInvalidOperators", func(t *testing.T) {
+		for _, c := range invalidCases {
+			c := c
+			t.Run(c.name, func(t *testing.T) {
+				_, err := repo.Groups(ctx, c.operators...)
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+			})
+		}
+	})
+
+	t.Run("Entity", func(t *testing.T) {
+		entity, err := repo.Entity(ctx, 670)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if entity.Name != "Capsule" {
+			t.Errorf("unexpected entity %+v", entity)
+		}
+
+		_, err = repo.Entity(ctx, 1)
+		if !errors.Is(err, krinder.ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+
+		// Creating a duplicate entity is not an error
+		_, err = repo.CreateEntity(ctx, &krinder.MongoEntity{ID: 670, Name: "Capsule", Published: true, Expires: expires})
+		if err != nil {
+			t.Errorf("unexpected error creating duplicate entity: %s", err)
+		}
+	})
+
+	t.Run("Entitys", func(t *testing.T) {
+		entities, err := repo.Entitys(ctx, krinder.NewLikeOperator(entityName, "E"), krinder.NewOrderOperator(entityID, krinder.SortAsc))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		ids := make([]uint, 0, len(entities))
+		for _, entity := range entities {
+			ids = append(ids, entity.ID)
+		}
+		assertIDs(t, []uint{587, 670, 11377}, ids, true)
+	})
+
+}
+
+func assertIDs(t *testing.T, expected, actual []uint, ordered bool) {
+	t.Helper()
+
+	if len(expected) != len(actual) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+
+	if ordered {
+		for i := range expected {
+			if expected[i] != actual[i] {
+				t.Fatalf("expected %v, got %v", expected, actual)
+			}
+		}
+		return
+	}
+
+	seen := make(map[uint]int, len(actual))
+	for _, id := range actual {
+		seen[id]++
+	}
+	for _, id := range expected {
+		if seen[id] == 0 {
+			t.Fatalf("expected %v, got %v", expected, actual)
+		}
+		seen[id]--
+	}
+}
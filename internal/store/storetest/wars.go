@@ -0,0 +1,189 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/krinder"
+)
+
+// Column names used by the wars service
+const (
+	warAggressorAllianceID    = "aggressor.allianceID"
+	warAggressorCorporationID = "aggressor.corporationID"
+	warDefenderAllianceID     = "defender.allianceID"
+	warDefenderCorporationID  = "defender.corporationID"
+	warStarted                = "started"
+	warFinished               = "finished"
+	warExpiresAt              = "expiresAt"
+)
+
+func warFixtures(now time.Time) []*krinder.MongoWar {
+
+	id := func(i uint) *uint { return &i }
+	at := func(d time.Duration) *time.Time { t := now.Add(d); return &t }
+
+	return []*krinder.MongoWar{
+		{
+			// Ongoing alliance war, due for an update
+			ID:        1,
+			Declared:  now.Add(-time.Hour * 72),
+			Started:   now.Add(-time.Hour * 48),
+			Aggressor: &krinder.MongoWarAggressor{AllianceID: id(100)},
+			Defender:  &krinder.MongoWarDefender{AllianceID: id(200)},
+			ExpiresAt: at(-time.Hour),
+		},
+		{
+			// Finished corporation war
+			ID:        2,
+			Declared:  now.Add(-time.Hour * 240),
+			Started:   now.Add(-time.Hour * 216),
+			Finished:  at(-time.Hour * 120),
+			Aggressor: &krinder.MongoWarAggressor{CorporationID: id(300)},
+			Defender:  &krinder.MongoWarDefender{CorporationID: id(400)},
+		},
+		{
+			// Ongoing corporation against alliance war that is not due for an update
+			ID:        3,
+			Declared:  now.Add(-time.Hour * 30),
+			Started:   now.Add(-time.Hour * 6),
+			Aggressor: &krinder.MongoWarAggressor{CorporationID: id(300)},
+			Defender:  &krinder.MongoWarDefender{AllianceID: id(200)},
+			ExpiresAt: at(time.Hour),
+		},
+	}
+
+}
+
+// TestWarRepository runs the conformance suite against repo. The repository must be empty when the suite starts
+func TestWarRepository(t *testing.T, repo krinder.WarRepository) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	err := repo.CreateWarBulk(ctx, warFixtures(now))
+	if err != nil {
+		t.Fatalf("failed to create wars: %s", err)
+	}
+
+	t.Run("War", func(t *testing.T) {
+		war, err := repo.War(ctx, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if war.Finished == nil || war.Aggressor == nil || war.Aggressor.CorporationID == nil || *war.Aggressor.CorporationID != 300 {
+			t.Errorf("unexpected war %+v", war)
+		}
+
+		_, err = repo.War(ctx, 1000)
+		if !errors.Is(err, krinder.ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("MutatingReturnedWar", func(t *testing.T) {
+		war, err := repo.War(ctx, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		finished := *war.Finished
+
+		// Changes to a returned war must not reach the stored war until it is updated
+		*war.Finished = finished.Add(time.Hour)
+		*war.Aggressor.CorporationID = 301
+		war.Defender.CorporationID = nil
+
+		wars, err := repo.Wars(ctx, krinder.NewEqualOperator("id", 2))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(wars) != 1 {
+			t.Fatalf("expected 1 war, got %d", len(wars))
+		}
+		wars[0].Aggressor.AllianceID = &wars[0].ID
+
+		war, err = repo.War(ctx, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !war.Finished.Equal(finished) || war.Aggressor.AllianceID != nil || *war.Aggressor.CorporationID != 300 ||
+			war.Defender.CorporationID == nil || *war.Defender.CorporationID != 400 {
+			t.Errorf("stored war was changed through a returned war, got %+v", war)
+		}
+	})
+
+	t.Run("UpdateWar", func(t *testing.T) {
+		war, err := repo.War(ctx, 3)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		war.IntegrityHash = "updated"
+		err = repo.UpdateWar(ctx, war)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		war, err = repo.War(ctx, 3)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if war.IntegrityHash != "updated" {
+			t.Errorf("update was not persisted, got %+v", war)
+		}
+	})
+
+	t.Run("Wars", func(t *testing.T) {
+
+		atWar := func(a, b *krinder.Operator, killTime time.Time) []*krinder.Operator {
+			return ops(krinder.NewAndOperator(
+				a, b,
+				krinder.NewLessThanOperator(warStarted, killTime),
+				krinder.NewOrOperator(krinder.NewExistsOperator(warFinished, false), krinder.NewGreaterThanOperator(warFinished, killTime)),
+			))
+		}
+		alliance := func(id uint) *krinder.Operator {
+			return krinder.NewOrOperator(krinder.NewEqualOperator(warAggressorAllianceID, id), krinder.NewEqualOperator(warDefenderAllianceID, id))
+		}
+		corporation := func(id uint) *krinder.Operator {
+			return krinder.NewOrOperator(krinder.NewEqualOperator(warAggressorCorporationID, id), krinder.NewEqualOperator(warDefenderCorporationID, id))
+		}
+
+		cases := []operatorCase{
+			{name: "alliances at war", operators: atWar(alliance(100), alliance(200), now), expected: []uint{1}},
+			{name: "before the war started", operators: atWar(alliance(100), alliance(200), now.Add(-time.Hour*60)), expected: []uint{}},
+			{name: "corporation and alliance at war", operators: atWar(corporation(300), alliance(200), now), expected: []uint{3}},
+			{name: "finished war", operators: atWar(corporation(300), corporation(400), now), expected: []uint{}},
+			{name: "before the war finished", operators: atWar(corporation(300), corporation(400), now.Add(-time.Hour*150)), expected: []uint{2}},
+			{
+				name:      "due for an update",
+				operators: ops(krinder.NewExistsOperator(warFinished, false), krinder.NewLessThanOperator(warExpiresAt, now)),
+				expected:  []uint{1},
+			},
+			{
+				name:      "order by nested column",
+				operators: ops(krinder.NewExistsOperator(warAggressorCorporationID, true), krinder.NewOrderOperator(warStarted, krinder.SortDesc)),
+				expected:  []uint{3, 2},
+				ordered:   true,
+			},
+		}
+
+		for _, c := range cases {
+			c := c
+			t.Run(c.name, func(t *testing.T) {
+				wars, err := repo.Wars(ctx, c.operators...)
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				ids := make([]uint, 0, len(wars))
+				for _, war := range wars {
+					ids = append(ids, war.ID)
+				}
+				assertIDs(t, c.expected, ids, c.ordered)
+			})
+		}
+	})
+
+}
@@ -4,10 +4,8 @@ import (
 	"context"
 	"time"
 
-	sq "github.com/Masterminds/squirrel"
-	"github.com/davecgh/go-spew/spew"
 	"github.com/eveisesi/krinder"
-	"github.com/jmoiron/sqlx"
+	"github.com/eveisesi/krinder/pkg/clock"
 	"github.com/pkg/errors"
 	"github.com/volatiletech/null"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -15,39 +13,49 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// UniverseRepository stores both groups and entities in Mongo
 type UniverseRepository struct {
-	mysql struct {
-		db *sqlx.DB
-	}
-	mongo struct {
-		entities *mongo.Collection
-	}
+	groups   *mongo.Collection
+	entities *mongo.Collection
+	clock    clock.Clock
 }
 
 var _ krinder.UniverseRepository = new(UniverseRepository)
 
 var (
-	groupsTableColumns = []string{GroupCategoryID,
-		GroupGroupID,
-		GroupName,
-		GroupEtag,
-		GroupPublished,
-		GroupExpires,
-		GroupCreatedAt,
-		GroupUpdatedAt,
-	}
-	groupsTable      = "groups"
+	groupCollection  = "groups"
 	entityCollection = "entities"
 )
 
-func NewUniverseRepository(sqldb *sqlx.DB, mongodb *mongo.Database) (*UniverseRepository, error) {
+func NewUniverseRepository(mongodb *mongo.Database, clock clock.Clock) (*UniverseRepository, error) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
 	defer cancel()
 
+	groups := mongodb.Collection(groupCollection)
+
+	_, err := groups.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: primitive.D{
+				primitive.E{Key: GroupGroupID, Value: 1},
+			},
+			Options: &options.IndexOptions{
+				Unique: null.BoolFrom(true).Ptr(),
+			},
+		},
+		{
+			Keys: primitive.D{
+				primitive.E{Key: GroupName, Value: 1},
+			},
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create index")
+	}
+
 	entities := mongodb.Collection(entityCollection)
 
-	_, err := entities.Indexes().CreateMany(ctx, []mongo.IndexModel{
+	_, err = entities.Indexes().CreateMany(ctx, []mongo.IndexModel{
 		{
 			Keys: primitive.D{
 				primitive.E{Key: EntityID, Value: 1},
@@ -62,26 +70,22 @@ func NewUniverseRepository(sqldb *sqlx.DB, mongodb *mongo.Database) (*UniverseRe
 		return nil, errors.Wrap(err, "failed to create index")
 	}
 
-	repo := new(UniverseRepository)
-	repo.mongo.entities = entities
-	repo.mysql.db = sqldb
-
-	return repo, nil
+	return &UniverseRepository{
+		groups:   groups,
+		entities: entities,
+		clock:    clock,
+	}, nil
 
 }
 
 func (r *UniverseRepository) Group(ctx context.Context, groupID uint) (*krinder.MySQLGroup, error) {
 
-	query, args, err := sq.Select(groupsTableColumns...).From(groupsTable).
-		Where(sq.Eq{GroupGroupID: groupID}).
-		ToSql()
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to generate query")
-	}
-
 	var group = new(krinder.MySQLGroup)
 
-	err = r.mysql.db.GetContext(ctx, group, query, args...)
+	err := r.groups.FindOne(ctx, primitive.D{primitive.E{Key: GroupGroupID, Value: groupID}}).Decode(group)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = krinder.ErrNotFound
+	}
 
 	return group, err
 
@@ -89,67 +93,62 @@ func (r *UniverseRepository) Group(ctx context.Context, groupID uint) (*krinder.
 
 func (r *UniverseRepository) Groups(ctx context.Context, operators ...*krinder.Operator) ([]*krinder.MySQLGroup, error) {
 
-	query, args, err := BuildSQLFilters(
-		sq.Select(groupsTableColumns...).From(groupsTable),
-		operators...).ToSql()
+	var groups = make([]*krinder.MySQLGroup, 0)
+
+	filters, err := BuildMongoFilters(operators...)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to generate query")
+		return groups, err
 	}
 
-	var groups = make([]*krinder.MySQLGroup, 0)
+	options, err := BuildMongoFindOptions(operators...)
+	if err != nil {
+		return groups, err
+	}
 
-	err = r.mysql.db.SelectContext(ctx, &groups, query, args...)
+	result, err := r.groups.Find(ctx, filters, options)
+	if err != nil {
+		return groups, err
+	}
 
-	return groups, err
+	return groups, result.All(ctx, &groups)
 
 }
 
 func (r *UniverseRepository) CreateGroup(ctx context.Context, group *krinder.MySQLGroup) (*krinder.MySQLGroup, error) {
 
-	group.CreatedAt = time.Now().UTC()
-	group.UpdatedAt = time.Now().UTC()
-
-	query, args, err := sq.Insert(groupsTable).SetMap(map[string]interface {
-	}{
-		GroupCategoryID: group.CategoryID,
-		GroupGroupID:    group.GroupID,
-		GroupName:       group.Name,
-		GroupPublished:  group.Published,
-		GroupEtag:       group.Etag,
-		GroupExpires:    group.Expires,
-		GroupCreatedAt:  group.CreatedAt,
-		GroupUpdatedAt:  group.UpdatedAt,
-	}).ToSql()
+	group.CreatedAt = r.clock.Now().UTC()
+	group.UpdatedAt = r.clock.Now().UTC()
+
+	_, err := r.groups.InsertOne(ctx, group)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to generate query")
-	}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, err
+		}
 
-	_, err = r.mysql.db.ExecContext(ctx, query, args...)
+	}
 
-	return group, err
+	return group, nil
 
 }
 
 func (r *UniverseRepository) UpdateGroup(ctx context.Context, group *krinder.MySQLGroup) (*krinder.MySQLGroup, error) {
 
-	group.UpdatedAt = time.Now()
-
-	query, args, err := sq.Update(groupsTable).SetMap(map[string]interface {
-	}{
-		GroupCategoryID: group.CategoryID,
-		GroupName:       group.Name,
-		GroupPublished:  group.Published,
-		GroupEtag:       group.Etag,
-		GroupExpires:    group.Expires,
-		GroupUpdatedAt:  group.UpdatedAt,
-	}).Where(sq.Eq{GroupGroupID: group.GroupID}).ToSql()
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to generate query")
+	group.UpdatedAt = r.clock.Now().UTC()
+
+	// created_at is intentionally omitted so that the original insert time is preserved
+	// when the group being updated was rebuilt from an ESI response
+	update := primitive.D{
+		primitive.E{Key: GroupCategoryID, Value: group.CategoryID},
+		primitive.E{Key: GroupName, Value: group.Name},
+		primitive.E{Key: GroupPublished, Value: group.Published},
+		primitive.E{Key: GroupEtag, Value: group.Etag},
+		primitive.E{Key: GroupExpires, Value: group.Expires},
+		primitive.E{Key: GroupUpdatedAt, Value: group.UpdatedAt},
 	}
 
-	spew.Dump(query)
+	filter := primitive.D{primitive.E{Key: GroupGroupID, Value: group.GroupID}}
+	_, err := r.groups.UpdateOne(ctx, filter, primitive.D{primitive.E{Key: "$set", Value: update}})
 
-	_, err = r.mysql.db.ExecContext(ctx, query, args...)
 	return group, err
 
 }
@@ -158,18 +157,30 @@ func (r *UniverseRepository) Entity(ctx context.Context, entityID uint) (*krinde
 
 	var entity = new(krinder.MongoEntity)
 
-	err := r.mongo.entities.FindOne(ctx, primitive.D{primitive.E{Key: EntityID, Value: entityID}}).Decode(entity)
+	err := r.entities.FindOne(ctx, primitive.D{primitive.E{Key: EntityID, Value: entityID}}).Decode(entity)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = krinder.ErrNotFound
+	}
 
 	return entity, err
 
 }
-func (r *UniverseRepository) Entitys(ctx context.Context, operators ...*krinder.Operator) ([]*krinder.MongoEntity, error) {
 
-	filters := BuildMongoFilters(operators...)
-	options := BuildMongoFindOptions(operators...)
+func (r *UniverseRepository) Entitys(ctx context.Context, operators ...*krinder.Operator) ([]*krinder.MongoEntity, error) {
 
 	var entities = make([]*krinder.MongoEntity, 0)
-	result, err := r.mongo.entities.Find(ctx, filters, options)
+
+	filters, err := BuildMongoFilters(operators...)
+	if err != nil {
+		return entities, err
+	}
+
+	options, err := BuildMongoFindOptions(operators...)
+	if err != nil {
+		return entities, err
+	}
+
+	result, err := r.entities.Find(ctx, filters, options)
 	if err != nil {
 		return entities, err
 	}
@@ -177,12 +188,13 @@ func (r *UniverseRepository) Entitys(ctx context.Context, operators ...*krinder.
 	return entities, result.All(ctx, &entities)
 
 }
+
 func (r *UniverseRepository) CreateEntity(ctx context.Context, entity *krinder.MongoEntity) (*krinder.MongoEntity, error) {
 
-	entity.CreatedAt = time.Now()
-	entity.UpdatedAt = time.Now()
+	entity.CreatedAt = r.clock.Now().UTC()
+	entity.UpdatedAt = r.clock.Now().UTC()
 
-	_, err := r.mongo.entities.InsertOne(ctx, entity)
+	_, err := r.entities.InsertOne(ctx, entity)
 	if err != nil {
 		if !mongo.IsDuplicateKeyError(err) {
 			return nil, err
@@ -196,10 +208,10 @@ func (r *UniverseRepository) CreateEntity(ctx context.Context, entity *krinder.M
 
 func (r *UniverseRepository) UpdateEntity(ctx context.Context, entity *krinder.MongoEntity) (*krinder.MongoEntity, error) {
 
-	entity.UpdatedAt = time.Now()
+	entity.UpdatedAt = r.clock.Now().UTC()
 
-	filter := BuildMongoFilters(krinder.NewEqualOperator(EntityID, entity.ID))
-	_, err := r.mongo.entities.UpdateOne(ctx, filter, primitive.D{primitive.E{Key: "$set", Value: entity}})
+	filter := primitive.D{primitive.E{Key: EntityID, Value: entity.ID}}
+	_, err := r.entities.UpdateOne(ctx, filter, primitive.D{primitive.E{Key: "$set", Value: entity}})
 
 	return entity, err
 
@@ -0,0 +1,253 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/pkg/clock"
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// MySQLUniverseRepository is an alternative to UniverseRepository that stores
+// both groups and entities in MySQL
+type MySQLUniverseRepository struct {
+	db    *sqlx.DB
+	clock clock.Clock
+}
+
+var _ krinder.UniverseRepository = new(MySQLUniverseRepository)
+
+var (
+	groupsTableColumns = []string{GroupCategoryID,
+		GroupGroupID,
+		GroupName,
+		GroupEtag,
+		GroupPublished,
+		GroupExpires,
+		GroupCreatedAt,
+		GroupUpdatedAt,
+	}
+	// groups is a reserved word as of MySQL 8, so the table name is quoted
+	groupsTable = "`groups`"
+
+	entitiesTableColumns = []string{
+		EntityID,
+		EntityName,
+		EntityPublished,
+		EntityEtag,
+		EntityExpires,
+		"created_at",
+		"updated_at",
+	}
+	entitiesTable = "entities"
+)
+
+// mysqlErrDuplicateEntry is the error number returned by MySQL when an insert violates a unique key
+const mysqlErrDuplicateEntry = 1062
+
+func NewMySQLUniverseRepository(sqldb *sqlx.DB, clock clock.Clock) (*MySQLUniverseRepository, error) {
+	return &MySQLUniverseRepository{
+		db:    sqldb,
+		clock: clock,
+	}, nil
+}
+
+// Ping verifies the connection to the database is still alive
+func (r *MySQLUniverseRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+func (r *MySQLUniverseRepository) Group(ctx context.Context, groupID uint) (*krinder.MySQLGroup, error) {
+
+	query, args, err := sq.Select(groupsTableColumns...).From(groupsTable).
+		Where(sq.Eq{GroupGroupID: groupID}).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate query")
+	}
+
+	var group = new(krinder.MySQLGroup)
+
+	err = r.db.GetContext(ctx, group, query, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = krinder.ErrNotFound
+	}
+
+	return group, err
+
+}
+
+func (r *MySQLUniverseRepository) Groups(ctx context.Context, operators ...*krinder.Operator) ([]*krinder.MySQLGroup, error) {
+
+	builder, err := BuildSQLFilters(
+		sq.Select(BuildSQLColumns(groupsTableColumns, operators...)...).From(groupsTable),
+		operators...)
+	if err != nil {
+		return nil, err
+	}
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate query")
+	}
+
+	var groups = make([]*krinder.MySQLGroup, 0)
+
+	err = r.db.SelectContext(ctx, &groups, query, args...)
+
+	return groups, err
+
+}
+
+func (r *MySQLUniverseRepository) CreateGroup(ctx context.Context, group *krinder.MySQLGroup) (*krinder.MySQLGroup, error) {
+
+	group.CreatedAt = r.clock.Now().UTC()
+	group.UpdatedAt = r.clock.Now().UTC()
+
+	query, args, err := sq.Insert(groupsTable).SetMap(map[string]interface {
+	}{
+		GroupCategoryID: group.CategoryID,
+		GroupGroupID:    group.GroupID,
+		GroupName:       group.Name,
+		GroupPublished:  group.Published,
+		GroupEtag:       group.Etag,
+		GroupExpires:    group.Expires,
+		GroupCreatedAt:  group.CreatedAt,
+		GroupUpdatedAt:  group.UpdatedAt,
+	}).ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate query")
+	}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		var mysqlErr *mysqlDriver.MySQLError
+		if !errors.As(err, &mysqlErr) || mysqlErr.Number != mysqlErrDuplicateEntry {
+			return nil, err
+		}
+	}
+
+	return group, nil
+
+}
+
+func (r *MySQLUniverseRepository) UpdateGroup(ctx context.Context, group *krinder.MySQLGroup) (*krinder.MySQLGroup, error) {
+
+	group.UpdatedAt = r.clock.Now().UTC()
+
+	query, args, err := sq.Update(groupsTable).SetMap(map[string]interface {
+	}{
+		GroupCategoryID: group.CategoryID,
+		GroupName:       group.Name,
+		GroupPublished:  group.Published,
+		GroupEtag:       group.Etag,
+		GroupExpires:    group.Expires,
+		GroupUpdatedAt:  group.UpdatedAt,
+	}).Where(sq.Eq{GroupGroupID: group.GroupID}).ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate query")
+	}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+	return group, err
+
+}
+
+func (r *MySQLUniverseRepository) Entity(ctx context.Context, entityID uint) (*krinder.MongoEntity, error) {
+
+	query, args, err := sq.Select(entitiesTableColumns...).From(entitiesTable).
+		Where(sq.Eq{EntityID: entityID}).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate query")
+	}
+
+	var entity = new(krinder.MongoEntity)
+
+	err = r.db.GetContext(ctx, entity, query, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = krinder.ErrNotFound
+	}
+
+	return entity, err
+
+}
+
+func (r *MySQLUniverseRepository) Entitys(ctx context.Context, operators ...*krinder.Operator) ([]*krinder.MongoEntity, error) {
+
+	builder, err := BuildSQLFilters(
+		sq.Select(BuildSQLColumns(entitiesTableColumns, operators...)...).From(entitiesTable),
+		operators...)
+	if err != nil {
+		return nil, err
+	}
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate query")
+	}
+
+	var entities = make([]*krinder.MongoEntity, 0)
+
+	err = r.db.SelectContext(ctx, &entities, query, args...)
+
+	return entities, err
+
+}
+
+func (r *MySQLUniverseRepository) CreateEntity(ctx context.Context, entity *krinder.MongoEntity) (*krinder.MongoEntity, error) {
+
+	entity.CreatedAt = r.clock.Now().UTC()
+	entity.UpdatedAt = r.clock.Now().UTC()
+
+	query, args, err := sq.Insert(entitiesTable).SetMap(map[string]interface {
+	}{
+		EntityID:        entity.ID,
+		EntityName:      entity.Name,
+		EntityPublished: entity.Published,
+		EntityEtag:      entity.Etag,
+		EntityExpires:   entity.Expires,
+		"created_at":    entity.CreatedAt,
+		"updated_at":    entity.UpdatedAt,
+	}).ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate query")
+	}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		var mysqlErr *mysqlDriver.MySQLError
+		if !errors.As(err, &mysqlErr) || mysqlErr.Number != mysqlErrDuplicateEntry {
+			return nil, err
+		}
+	}
+
+	return entity, nil
+
+}
+
+func (r *MySQLUniverseRepository) UpdateEntity(ctx context.Context, entity *krinder.MongoEntity) (*krinder.MongoEntity, error) {
+
+	entity.UpdatedAt = r.clock.Now().UTC()
+
+	query, args, err := sq.Update(entitiesTable).SetMap(map[string]interface {
+	}{
+		EntityName:      entity.Name,
+		EntityPublished: entity.Published,
+		EntityEtag:      entity.Etag,
+		EntityExpires:   entity.Expires,
+		"updated_at":    entity.UpdatedAt,
+	}).Where(sq.Eq{EntityID: entity.ID}).ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate query")
+	}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+
+	return entity, err
+
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/pkg/clock"
 	"github.com/pkg/errors"
 	"github.com/volatiletech/null"
 	"go.mongodb.org/mongo-driver/bson"
@@ -14,12 +15,13 @@ import (
 )
 
 type WarRepository struct {
-	wars *mongo.Collection
+	wars  *mongo.Collection
+	clock clock.Clock
 }
 
 var _ krinder.WarRepository = new(WarRepository)
 
-func NewWarRepository(database *mongo.Database) (*WarRepository, error) {
+func NewWarRepository(database *mongo.Database, clock clock.Clock) (*WarRepository, error) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
 	defer cancel()
@@ -72,7 +74,8 @@ func NewWarRepository(database *mongo.Database) (*WarRepository, error) {
 	}
 
 	return &WarRepository{
-		wars: wars,
+		wars:  wars,
+		clock: clock,
 	}, nil
 
 }
@@ -82,6 +85,9 @@ func (r *WarRepository) War(ctx context.Context, warID uint) (*krinder.MongoWar,
 	var war = new(krinder.MongoWar)
 
 	err := r.wars.FindOne(ctx, bson.D{primitive.E{Key: "id", Value: warID}}).Decode(war)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = krinder.ErrNotFound
+	}
 
 	return war, err
 
@@ -89,10 +95,18 @@ func (r *WarRepository) War(ctx context.Context, warID uint) (*krinder.MongoWar,
 
 func (r *WarRepository) Wars(ctx context.Context, operators ...*krinder.Operator) ([]*krinder.MongoWar, error) {
 
-	filters := BuildMongoFilters(operators...)
-	options := BuildMongoFindOptions(operators...)
-
 	var wars = make([]*krinder.MongoWar, 0)
+
+	filters, err := BuildMongoFilters(operators...)
+	if err != nil {
+		return wars, err
+	}
+
+	options, err := BuildMongoFindOptions(operators...)
+	if err != nil {
+		return wars, err
+	}
+
 	result, err := r.wars.Find(ctx, filters, options)
 	if err != nil {
 		return wars, err
@@ -103,8 +117,8 @@ func (r *WarRepository) Wars(ctx context.Context, operators ...*krinder.Operator
 }
 
 func (r *WarRepository) CreateWar(ctx context.Context, war *krinder.MongoWar) (*krinder.MongoWar, error) {
-	war.CreatedAt = time.Now().UTC()
-	war.UpdatedAt = time.Now().UTC()
+	war.CreatedAt = r.clock.Now().UTC()
+	war.UpdatedAt = r.clock.Now().UTC()
 
 	_, err := r.wars.InsertOne(ctx, war)
 	if err != nil {
@@ -120,7 +134,7 @@ func (r *WarRepository) CreateWar(ctx context.Context, war *krinder.MongoWar) (*
 
 func (r *WarRepository) CreateWarBulk(ctx context.Context, wars []*krinder.MongoWar) error {
 
-	now := time.Now().UTC()
+	now := r.clock.Now().UTC()
 	documents := make([]interface{}, len(wars))
 	for i, war := range wars {
 		war.CreatedAt = now
@@ -146,10 +160,10 @@ func (r *WarRepository) CreateWarBulk(ctx context.Context, wars []*krinder.Mongo
 
 func (r *WarRepository) UpdateWar(ctx context.Context, war *krinder.MongoWar) error {
 
-	now := time.Now()
+	now := r.clock.Now().UTC()
 	war.UpdatedAt = now
 
-	filter := BuildMongoFilters(krinder.NewEqualOperator("id", war.ID))
+	filter := primitive.D{primitive.E{Key: "id", Value: war.ID}}
 	_, err := r.wars.UpdateOne(ctx, filter, primitive.D{primitive.E{Key: "$set", Value: war}})
 	if err != nil {
 		return err
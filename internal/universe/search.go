@@ -0,0 +1,77 @@
+package universe
+
+import (
+	"context"
+	"strings"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/internal/store"
+	"github.com/eveisesi/krinder/pkg/errorcode"
+	"github.com/pkg/errors"
+)
+
+// SearchCategories are the categories Search accepts
+var SearchCategories = []string{"character", "invgroup"}
+
+type SearchResult struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// Search finds the characters or inventory groups matching term. Characters are searched
+// through ESI and groups in the universe store, which only holds published groups
+func (s *Service) Search(ctx context.Context, category, term string, strict bool) ([]*SearchResult, error) {
+
+	var results = make([]*SearchResult, 0)
+
+	switch category {
+	case "character":
+		ids, err := s.esi.Search(ctx, category, term, strict)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to search for %s", category)
+		}
+
+		if len(ids.Character) == 0 {
+			return results, nil
+		}
+
+		names, err := s.esi.Names(ctx, ids.Character)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to search characters name from ids")
+		}
+
+		for _, name := range names {
+			results = append(results, &SearchResult{
+				ID:   uint(name.ID),
+				Name: name.Name,
+			})
+		}
+	case "invgroup":
+		var filters = make([]*krinder.Operator, 0)
+		filters = append(filters, krinder.NewEqualOperator(store.GroupPublished, true))
+
+		switch strict {
+		case true:
+			filters = append(filters, krinder.NewEqualOperator(store.GroupName, term))
+		case false:
+			filters = append(filters, krinder.NewLikeOperator(store.GroupName, term))
+		}
+
+		groups, err := s.Groups(ctx, filters...)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to query universe for group")
+		}
+
+		for _, group := range groups {
+			results = append(results, &SearchResult{
+				ID:   uint(group.GroupID),
+				Name: group.Name,
+			})
+		}
+	default:
+		return nil, errorcode.Newf(errorcode.InvalidArgument, "%s is an invalid category, expected one of %s", category, strings.Join(SearchCategories, ", "))
+	}
+
+	return results, nil
+
+}
@@ -2,15 +2,16 @@ package universe
 
 import (
 	"context"
-	"database/sql"
 	"time"
 
 	"github.com/eveisesi/krinder"
 	"github.com/eveisesi/krinder/internal/esi"
+	"github.com/eveisesi/krinder/internal/jobs"
+	"github.com/eveisesi/krinder/internal/metrics"
+	"github.com/eveisesi/krinder/pkg/clock"
 	"github.com/go-redis/redis/v8"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type UniverseAPI interface {
@@ -25,38 +26,44 @@ type Service struct {
 	esi      esi.API
 	universe krinder.UniverseRepository
 	UniverseAPI
+
+	clock clock.Clock
+
+	status *jobs.Tracker
 }
 
 var _ UniverseAPI = new(Service)
 
-func New(logger *logrus.Logger, cache *redis.Client, esi esi.API, universe krinder.UniverseRepository) *Service {
+func New(logger *logrus.Logger, cache *redis.Client, esi esi.API, universe krinder.UniverseRepository, clock clock.Clock) *Service {
 	return &Service{
 		logger:      logger,
 		cache:       cache,
 		esi:         esi,
 		universe:    universe,
 		UniverseAPI: universe,
+		clock:       clock,
+		status:      jobs.NewTracker("universe"),
 	}
 }
 
 func (s *Service) Entity(ctx context.Context, entityID uint) (*krinder.MongoEntity, error) {
 
-	entry := s.logger.WithFields(logrus.Fields{
+	entry := s.logger.WithContext(ctx).WithFields(logrus.Fields{
 		"entityID": entityID,
 		"service":  "universe",
 	})
 
 	entity, err := s.universe.Entity(ctx, entityID)
-	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+	if err != nil && !errors.Is(err, krinder.ErrNotFound) {
 		return nil, errors.Wrap(err, "failed to fetch entity from datastore")
 	}
 
-	if entity.Expires.Unix() > time.Now().Unix() {
+	if entity.Expires.After(s.clock.Now()) {
 		return entity, err
 	}
 
 	var create = false
-	if errors.Is(err, mongo.ErrNoDocuments) {
+	if errors.Is(err, krinder.ErrNotFound) {
 		create = true
 	}
 
@@ -86,17 +93,52 @@ func (s *Service) Entity(ctx context.Context, entityID uint) (*krinder.MongoEnti
 	return entity, nil
 }
 
-func (s *Service) Run() {
+// Sync refreshes the groups that have expired with ESI. Cancelling ctx aborts the sync
+func (s *Service) Sync(ctx context.Context) error {
+
+	if err := s.status.Start(); err != nil {
+		s.logger.WithContext(ctx).WithField("service", "universe").Info("sync already running, skipping")
+		return err
+	}
+
+	start := time.Now()
+
+	err := s.syncGroups(ctx)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).WithField("service", "universe").Error("failed to sync groups")
+	}
+
+	metrics.ObserveJob("universe", start, err)
+	s.status.Finish(err)
 
-	var ctx = context.Background()
+	return err
+
+}
+
+// MarkComplete records that the group data is complete, because a sync has succeeded elsewhere
+func (s *Service) MarkComplete() {
+	s.status.MarkComplete()
+}
+
+// Status returns the progress of the current, or most recent, sync
+func (s *Service) Status() jobs.Status {
+	return s.status.Status()
+}
+
+// Warning returns a message to attach to results that depend on group data while
+// the initial sync is incomplete, or an empty string once it has completed
+func (s *Service) Warning() string {
+	return s.status.Status().Warning("universe data", "groups may be missing from results")
+}
+
+func (s *Service) syncGroups(ctx context.Context) error {
 
 	var page uint = 1
 	ids := make([]uint, 0, 4000)
 	for {
 		groupIDs, err := s.esi.Groups(ctx, page)
 		if err != nil {
-			s.logger.WithError(err).Error("failed to fetch groups from ESI")
-			return
+			return errors.Wrap(err, "failed to fetch groups from ESI")
 		}
 
 		ids = append(ids, groupIDs.IDs...)
@@ -106,35 +148,48 @@ func (s *Service) Run() {
 		}
 
 		page++
-		time.Sleep(time.Second)
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "sync cancelled")
+		case <-time.After(time.Second):
+		}
 
 	}
 
+	s.status.AddTotal(len(ids))
 	for _, groupID := range ids {
 
-		entry := s.logger.WithFields(logrus.Fields{
+		if err := ctx.Err(); err != nil {
+			return errors.Wrap(err, "sync cancelled")
+		}
+
+		s.status.Advance(1)
+
+		entry := s.logger.WithContext(ctx).WithFields(logrus.Fields{
 			"groupID": groupID,
 			"service": "universe",
 		})
 
 		group, err := s.universe.Group(ctx, groupID)
-		if err != nil && !errors.Is(err, sql.ErrNoRows) {
-			entry.WithError(err).Error("failed to fetch group from mongo")
-			return
+		if err != nil && !errors.Is(err, krinder.ErrNotFound) {
+			return errors.Wrapf(err, "failed to fetch group %d from datastore", groupID)
 		}
 
-		if !group.Expires.IsZero() && group.Expires.Unix() > time.Now().UTC().Unix() {
+		if !group.Expires.IsZero() && group.Expires.After(s.clock.Now()) {
 			continue
 		}
 		var create = false
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, krinder.ErrNotFound) {
 			create = true
 		}
 
 		esiGroup, err := s.esi.Group(ctx, groupID, esi.AddIfNoneMatchHeader(group.Etag))
 		if err != nil {
 			entry.WithError(err).Error("failed to fetch group from ESI")
-			time.Sleep(time.Millisecond * 250)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Millisecond * 250):
+			}
 			continue
 		}
 
@@ -153,10 +208,11 @@ func (s *Service) Run() {
 			_, err = s.universe.UpdateGroup(ctx, group)
 		}
 		if err != nil {
-			entry.WithError(err).Error("encountered error mutating group in database")
-			return
+			return errors.Wrapf(err, "encountered error mutating group %d in database", groupID)
 		}
 
 	}
 
+	return nil
+
 }
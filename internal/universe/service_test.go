@@ -0,0 +1,74 @@
+package universe
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/internal/esi"
+	"github.com/eveisesi/krinder/internal/esi/esitest"
+	"github.com/eveisesi/krinder/internal/store/memory"
+	"github.com/eveisesi/krinder/pkg/clock"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSyncGroups(t *testing.T) {
+
+	ctx := context.Background()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	server := esitest.New(t)
+	server.PageSize = 2
+	server.SetGroup(&krinder.ESIGroup{GroupID: 25, CategoryID: 6, Name: "Frigate", Published: true})
+	server.SetGroup(&krinder.ESIGroup{GroupID: 26, CategoryID: 6, Name: "Cruiser", Published: true})
+	server.SetGroup(&krinder.ESIGroup{GroupID: 27, CategoryID: 6, Name: "Battleship", Published: true})
+	server.Fault("/v1/universe/groups/26/", http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+
+	now := clock.NewFake(time.Now())
+	repo := memory.NewUniverseRepository(now)
+	api := esi.New(logger, "krinder-test", nil, esi.WithBaseURL(server.URL), esi.WithBackoff(time.Millisecond))
+	s := New(logger, nil, api, repo, now)
+
+	if err := s.Sync(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Group 26 failed and is picked up by the next sync
+	groups, _ := repo.Groups(ctx)
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups after the first sync, got %d", len(groups))
+	}
+	if server.Requests("/v1/universe/groups/?page=2") != 1 {
+		t.Errorf("expected the second page of groups to be fetched")
+	}
+
+	if err := s.Sync(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	groups, _ = repo.Groups(ctx)
+	if len(groups) != 3 {
+		t.Fatalf("expected 3 groups after the second sync, got %d", len(groups))
+	}
+	if server.Requests("/v1/universe/groups/25/") != 1 {
+		t.Errorf("expected group 25 not to be fetched again before it expires")
+	}
+
+	// Once the groups expire they are revalidated with their etag and keep their data when unchanged
+	now.Advance(server.TTL + time.Minute)
+
+	if err := s.Sync(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	frigate, _ := repo.Group(ctx, 25)
+	if server.Requests("/v1/universe/groups/25/") != 2 || frigate.Name != "Frigate" {
+		t.Errorf("expected group 25 to be revalidated, got %+v", frigate)
+	}
+
+}
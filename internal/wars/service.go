@@ -3,12 +3,20 @@ package wars
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/eveisesi/krinder"
 	"github.com/eveisesi/krinder/internal/esi"
+	"github.com/eveisesi/krinder/internal/jobs"
+	"github.com/eveisesi/krinder/internal/metrics"
+	"github.com/eveisesi/krinder/internal/notify"
+	"github.com/eveisesi/krinder/pkg/clock"
+	"github.com/eveisesi/krinder/pkg/errorcode"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"github.com/volatiletech/null"
 )
 
 type Service struct {
@@ -17,20 +25,110 @@ type Service struct {
 	esi esi.API
 
 	wars krinder.WarRepository
+
+	notifier notify.Notifier
+	clock    clock.Clock
+
+	status *jobs.Tracker
 }
 
-func NewService(logger *logrus.Logger, esi esi.API, wars krinder.WarRepository) *Service {
+func NewService(logger *logrus.Logger, esi esi.API, wars krinder.WarRepository, notifier notify.Notifier, clock clock.Clock) *Service {
 	return &Service{
 		logger: logger,
 		esi:    esi,
 
 		wars: wars,
+
+		notifier: notifier,
+		clock:    clock,
+
+		status: jobs.NewTracker("wars"),
+	}
+}
+
+// Sync checks ESI for new wars and refreshes the wars that are due for an update.
+// Cancelling ctx aborts the sync
+func (s *Service) Sync(ctx context.Context) error {
+
+	if err := s.status.Start(); err != nil {
+		s.logger.WithContext(ctx).WithField("service", "wars").Info("sync already running, skipping")
+		return err
+	}
+
+	start := time.Now()
+
+	err := s.checkForNewWars(ctx)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("failed to check for new wars")
+	}
+
+	updateErr := s.updateWars(ctx)
+	if updateErr != nil {
+		s.logger.WithContext(ctx).WithError(updateErr).Error("failed to update wars")
+		err = updateErr
 	}
+
+	metrics.ObserveJob("wars", start, err)
+	s.status.Finish(err)
+
+	return err
+
 }
 
-func (s *Service) Run() {
-	s.checkForNewWars()
-	s.updateWars()
+// MarkComplete records that the war data is complete, because a sync has succeeded elsewhere
+func (s *Service) MarkComplete() {
+	s.status.MarkComplete()
+}
+
+// Status returns the progress of the current, or most recent, sync
+func (s *Service) Status() jobs.Status {
+	return s.status.Status()
+}
+
+// Warning returns a message to attach to results that depend on war data while
+// the initial sync is incomplete, or an empty string once it has completed
+func (s *Service) Warning() string {
+	return s.status.Status().Warning("war data", "results may include war kills")
+}
+
+// War returns the war with id from the datastore
+func (s *Service) War(ctx context.Context, id uint) (*krinder.MongoWar, error) {
+	war, err := s.wars.War(ctx, id)
+	if errors.Is(err, krinder.ErrNotFound) {
+		return nil, errorcode.Wrapf(err, errorcode.NotFound, "war %d is unknown", id)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch war %d", id)
+	}
+
+	return war, nil
+}
+
+// EntityWars returns the most recent wars, at most limit, that the alliance or corporation
+// with entityID is an aggressor or defender in. Wars that have finished by now are only included
+// when finished is true
+func (s *Service) EntityWars(ctx context.Context, entityID uint, finished bool, limit int64) ([]*krinder.MongoWar, error) {
+
+	operators := []*krinder.Operator{
+		krinder.NewOrOperator(
+			krinder.NewEqualOperator("aggressor.allianceID", entityID),
+			krinder.NewEqualOperator("aggressor.corporationID", entityID),
+			krinder.NewEqualOperator("defender.allianceID", entityID),
+			krinder.NewEqualOperator("defender.corporationID", entityID),
+		),
+	}
+	if !finished {
+		// ESI sets finished as soon as a war is retracted, so wars finishing later are still active
+		operators = append(operators, krinder.NewOrOperator(krinder.NewExistsOperator("finished", false), krinder.NewGreaterThanOperator("finished", s.clock.Now())))
+	}
+
+	wars, err := s.wars.Wars(ctx, krinder.NewAndOperator(operators...), krinder.NewOrderOperator("id", krinder.SortDesc), krinder.NewLimitOperator(limit))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch wars for entity")
+	}
+
+	return wars, nil
+
 }
 
 type Entity struct {
@@ -74,26 +172,30 @@ func (s *Service) EntitiesAtWar(ctx context.Context, entityA, entityB Entity, ki
 
 }
 
-func (s *Service) updateWars() {
+func (s *Service) updateWars(ctx context.Context) error {
 
-	ctx := context.Background()
-	esiWars, err := s.wars.Wars(ctx, krinder.NewExistsOperator("finished", false), krinder.NewLessThanOperator("expiresAt", time.Now().UTC()))
+	esiWars, err := s.wars.Wars(ctx, krinder.NewExistsOperator("finished", false), krinder.NewLessThanOperator("expiresAt", s.clock.Now().UTC()))
 	if err != nil {
-		s.logger.WithError(err).Error("failed to fetch wars to update")
-		return
+		return errors.Wrap(err, "failed to fetch wars to update")
 	}
 
-	s.logger.WithField("updatedableWars", len(esiWars)).Info("updating wars")
+	s.logger.WithContext(ctx).WithField("updatedableWars", len(esiWars)).Info("updating wars")
+	s.status.AddTotal(len(esiWars))
 
 	var updatedMongoWars = make([]*krinder.MongoWar, 0, len(esiWars))
 	for i, esiWar := range esiWars {
+		if err := ctx.Err(); err != nil {
+			return errors.Wrap(err, "update cancelled")
+		}
+
 		if i%50 == 0 {
-			s.logger.WithField("iteration", i).Infoln()
+			s.logger.WithContext(ctx).WithField("iteration", i).Infoln()
 		}
 
+		s.status.Advance(1)
 		war, err := s.esi.War(ctx, esiWar.ID, esi.AddIfNoneMatchHeader(esiWar.IntegrityHash))
 		if err != nil {
-			s.logger.WithError(err).WithField("id", esiWar.ID).Error("failed to fetch War from ESI")
+			s.logger.WithContext(ctx).WithError(err).WithField("id", esiWar.ID).Error("failed to fetch War from ESI")
 			continue
 		}
 
@@ -101,31 +203,39 @@ func (s *Service) updateWars() {
 			continue
 		}
 
+		if esiWar.Finished == nil && war.Finished.Valid {
+			s.notify(ctx, notify.KindWarFinished, war)
+		}
+
 		updatedMongoWars = append(updatedMongoWars, war.ToMongoWar())
 
 	}
 
-	s.logger.WithField("countUpdatedWars", len(updatedMongoWars)).Infoln()
+	s.logger.WithContext(ctx).WithField("countUpdatedWars", len(updatedMongoWars)).Infoln()
 
 	for _, mongoWar := range updatedMongoWars {
-		s.logger.WithField("id", mongoWar.ID).Info("updating war")
+		if err := ctx.Err(); err != nil {
+			return errors.Wrap(err, "update cancelled")
+		}
+
+		s.logger.WithContext(ctx).WithField("id", mongoWar.ID).Info("updating war")
 		err := s.wars.UpdateWar(ctx, mongoWar)
 		if err != nil {
-			s.logger.WithError(err).Error("failed to update war")
+			s.logger.WithContext(ctx).WithError(err).Error("failed to update war")
 		}
 	}
 
+	return nil
+
 }
 
-func (s *Service) checkForNewWars() {
-	s.logger.Info("initializing wars service")
-	s.logger.Info("fetching known wars from mongo")
+func (s *Service) checkForNewWars(ctx context.Context) error {
+	s.logger.WithContext(ctx).Info("initializing wars service")
+	s.logger.WithContext(ctx).Info("fetching known wars from mongo")
 
-	ctx := context.Background()
 	wars, err := s.wars.Wars(ctx, krinder.NewOrderOperator("id", krinder.SortDesc))
 	if err != nil {
-		s.logger.WithError(err).Error("failed to fetch known wars from mongo")
-		return
+		return errors.Wrap(err, "failed to fetch known wars from mongo")
 	}
 
 	var lastKnownWar int64
@@ -133,12 +243,11 @@ func (s *Service) checkForNewWars() {
 		lastKnownWar = int64(wars[0].ID)
 	}
 
-	s.logger.Info("fetching wars from ESI")
+	s.logger.WithContext(ctx).Info("fetching wars from ESI")
 
 	warIDs, err := s.esi.Wars(ctx)
 	if err != nil {
-		s.logger.WithError(err).Error("failed to fetch warIDs from ESI")
-		return
+		return errors.Wrap(err, "failed to fetch warIDs from ESI")
 	}
 
 	var newIDs = make([]int, 0, len(warIDs))
@@ -149,31 +258,193 @@ func (s *Service) checkForNewWars() {
 	}
 
 	if len(newIDs) == 0 {
-		s.logger.Info("no new wars returns from ESI")
-		return
+		s.logger.WithContext(ctx).Info("no new wars returns from ESI")
+		return nil
 	}
 
-	s.logger.WithField("numNewWars", len(newIDs)).Info("fetching new wars from ESI. ")
-	s.logger.Info("This could take a minute, especially if redis has been cleared recently and mongo is empty")
+	s.logger.WithContext(ctx).WithField("numNewWars", len(newIDs)).Info("fetching new wars from ESI. ")
+	s.logger.WithContext(ctx).Info("This could take a minute, especially if redis has been cleared recently and mongo is empty")
 	var newWars = make([]*krinder.ESIWar, 0, len(newIDs))
 
+	// failed is the lowest id of the wars that could not be fetched for now
+	var failed int
+	s.status.AddTotal(len(newIDs))
 	for _, id := range newIDs {
+		if err := ctx.Err(); err != nil {
+			return errors.Wrap(err, "check for new wars cancelled")
+		}
+
 		war, err := s.esi.War(ctx, uint(id))
+		s.status.Advance(1)
+		if err != nil && !errorcode.Transient(err) {
+			// A war ESI does not know or refuses is skipped, retrying it would hold back every newer war
+			s.logger.WithContext(ctx).WithError(err).WithFields(logrus.Fields{"service": "wars", "id": id}).Warn("skipping war that ESI cannot return")
+			continue
+		}
 		if err != nil {
-			fmt.Println(err)
+			s.logger.WithContext(ctx).WithError(err).WithFields(logrus.Fields{"service": "wars", "id": id}).Error("failed to fetch war from ESI")
+			if failed == 0 || id < failed {
+				failed = id
+			}
 			continue
 		}
 
 		newWars = append(newWars, war)
 	}
 
-	mongoWars := make([]*krinder.MongoWar, 0, len(newWars))
-	for _, war := range newWars {
-		mongoWars = append(mongoWars, war.ToMongoWar())
+	if failed > 0 {
+		// The next sync only fetches wars newer than the last known war, so the wars after
+		// one that failed are left for it rather than skipping the failed war for good
+		kept := make([]*krinder.ESIWar, 0, len(newWars))
+		for _, war := range newWars {
+			if war.ID < failed {
+				kept = append(kept, war)
+			}
+		}
+		newWars = kept
 	}
 
-	err = s.wars.CreateWarBulk(ctx, mongoWars)
-	if err != nil {
-		s.logger.WithError(err).Error("failed to save wars to mongo")
+	if len(newWars) > 0 {
+		mongoWars := make([]*krinder.MongoWar, 0, len(newWars))
+		for _, war := range newWars {
+			mongoWars = append(mongoWars, war.ToMongoWar())
+		}
+
+		err = s.wars.CreateWarBulk(ctx, mongoWars)
+		if err != nil {
+			return errors.Wrap(err, "failed to save wars to mongo")
+		}
+
+		// Every war is new to an empty datastore, so only wars found by later syncs are announced
+		if lastKnownWar > 0 {
+			for _, war := range newWars {
+				s.notify(ctx, notify.KindWarDeclared, war)
+			}
+		}
+	}
+
+	// The wars that were saved are kept, but the sync must not look successful while newer ones are held back
+	if failed > 0 {
+		return errors.Errorf("failed to fetch war %d from ESI, it and the wars after it are left for the next sync", failed)
+	}
+
+	return nil
+}
+
+func (s *Service) notify(ctx context.Context, kind string, war *krinder.ESIWar) {
+
+	n := &notify.Notification{
+		Kind:   kind,
+		URL:    fmt.Sprintf("https://zkillboard.com/war/%d/", war.ID),
+		Fields: make([]*notify.Field, 0, 4),
+	}
+
+	switch kind {
+	case notify.KindWarDeclared:
+		n.Title = fmt.Sprintf("War %d declared", war.ID)
+		n.Time = war.Declared
+		n.Fields = append(n.Fields, &notify.Field{Name: "Started", Value: war.Started.Format(time.RFC3339)})
+	case notify.KindWarFinished:
+		n.Title = fmt.Sprintf("War %d finished", war.ID)
+		n.Time = war.Finished.Time
 	}
+
+	if war.Aggressor != nil {
+		n.Fields = append(n.Fields, &notify.Field{Name: "Aggressor", Value: warParty(war.Aggressor.AllianceID, war.Aggressor.CorporationID)})
+	}
+	if war.Defender != nil {
+		n.Fields = append(n.Fields, &notify.Field{Name: "Defender", Value: warParty(war.Defender.AllianceID, war.Defender.CorporationID)})
+	}
+	n.Fields = append(n.Fields, &notify.Field{Name: "Mutual", Value: strconv.FormatBool(war.Mutual)})
+
+	s.notifier.Notify(ctx, n)
+
+}
+
+func warParty(allianceID, corporationID null.Uint) string {
+	if allianceID.Valid {
+		return fmt.Sprintf("alliance %d", allianceID.Uint)
+	}
+	return fmt.Sprintf("corporation %d", corporationID.Uint)
+}
+
+// Backfill pages backwards through the wars known to ESI, starting from the most recent, and
+// creates any war that is missing from the datastore. At most pages pages of war ids are read,
+// or every page when pages is zero. The number of wars created is returned
+func (s *Service) Backfill(ctx context.Context, pages int) (int, error) {
+
+	entry := s.logger.WithContext(ctx).WithField("service", "wars")
+
+	var created int
+	var warIDs []int
+	var err error
+	for page := 1; pages == 0 || page <= pages; page++ {
+
+		if page == 1 {
+			warIDs, err = s.esi.Wars(ctx)
+		} else {
+			warIDs, err = s.esi.WarsBefore(ctx, warIDs[len(warIDs)-1])
+		}
+		if err != nil {
+			return created, err
+		}
+
+		if len(warIDs) == 0 {
+			break
+		}
+
+		sort.Sort(sort.Reverse(sort.IntSlice(warIDs)))
+
+		ids := make([]uint, 0, len(warIDs))
+		for _, id := range warIDs {
+			ids = append(ids, uint(id))
+		}
+
+		known, err := s.wars.Wars(ctx, krinder.NewInOperator("id", ids), krinder.NewProjectOperator("id"))
+		if err != nil {
+			return created, errors.Wrap(err, "failed to fetch known wars")
+		}
+
+		seen := make(map[uint]bool, len(known))
+		for _, war := range known {
+			seen[war.ID] = true
+		}
+
+		missing := make([]*krinder.MongoWar, 0)
+		for _, id := range ids {
+			if seen[id] {
+				continue
+			}
+
+			if err := ctx.Err(); err != nil {
+				return created, errors.Wrap(err, "backfill cancelled")
+			}
+
+			war, err := s.esi.War(ctx, id)
+			if err != nil {
+				entry.WithError(err).WithField("id", id).Error("failed to fetch war from ESI")
+				continue
+			}
+
+			missing = append(missing, war.ToMongoWar())
+		}
+
+		if len(missing) > 0 {
+			err = s.wars.CreateWarBulk(ctx, missing)
+			if err != nil {
+				return created, errors.Wrap(err, "failed to save wars")
+			}
+			created += len(missing)
+		}
+
+		entry.WithFields(logrus.Fields{
+			"page":    page,
+			"created": len(missing),
+			"oldest":  warIDs[len(warIDs)-1],
+		}).Info("backfilled page of wars")
+
+	}
+
+	return created, nil
+
 }
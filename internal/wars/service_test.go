@@ -0,0 +1,242 @@
+package wars
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eveisesi/krinder"
+	"github.com/eveisesi/krinder/internal/esi"
+	"github.com/eveisesi/krinder/internal/esi/esitest"
+	"github.com/eveisesi/krinder/internal/jobs"
+	"github.com/eveisesi/krinder/internal/notify"
+	"github.com/eveisesi/krinder/internal/store/memory"
+	"github.com/eveisesi/krinder/pkg/clock"
+	"github.com/sirupsen/logrus"
+	"github.com/volatiletech/null"
+)
+
+type notifications struct {
+	mu   sync.Mutex
+	sent []*notify.Notification
+}
+
+func (n *notifications) Notify(ctx context.Context, notification *notify.Notification) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, notification)
+}
+
+func (n *notifications) titles() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	titles := make([]string, 0, len(n.sent))
+	for _, notification := range n.sent {
+		titles = append(titles, notification.Title)
+	}
+	return titles
+}
+
+func war(id int) *krinder.ESIWar {
+	declared := time.Date(2021, 11, 1, 12, 0, 0, 0, time.UTC).AddDate(0, 0, id)
+	return &krinder.ESIWar{
+		ID:        id,
+		Aggressor: &krinder.ESIWarAggressor{CorporationID: null.UintFrom(98000000 + uint(id))},
+		Defender:  &krinder.ESIWarDefender{CorporationID: null.UintFrom(98100000 + uint(id))},
+		Declared:  declared,
+		Started:   declared.Add(24 * time.Hour),
+	}
+}
+
+func service(t *testing.T, now clock.Clock) (*Service, *esitest.Server, *memory.WarRepository, *notifications) {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	server := esitest.New(t)
+	repo := memory.NewWarRepository(now)
+	sent := new(notifications)
+
+	api := esi.New(logger, "krinder-test", nil, esi.WithBaseURL(server.URL), esi.WithBackoff(time.Millisecond))
+
+	return NewService(logger, api, repo, sent, now), server, repo, sent
+}
+
+func TestSyncResumesAfterFailedWar(t *testing.T) {
+
+	ctx := context.Background()
+	s, server, repo, sent := service(t, clock.New())
+	for _, id := range []int{1, 2, 3} {
+		server.SetWar(war(id))
+	}
+	server.Fault("/v1/wars/2/", http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+
+	// The sync fails so that the job does not report wars 2 and 3 as synced
+	if err := s.Sync(ctx); err == nil {
+		t.Fatal("expected the sync to fail while wars are held back")
+	}
+	if status := s.Status(); status.State != jobs.StateFailed {
+		t.Errorf("expected the sync to be failed, got %s", status.State)
+	}
+
+	// War 3 is left for the next sync so that war 2 is not skipped
+	stored, _ := repo.Wars(ctx)
+	if len(stored) != 1 || stored[0].ID != 1 {
+		t.Fatalf("expected only war 1 to be stored, got %d wars", len(stored))
+	}
+
+	if err := s.Sync(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	stored, _ = repo.Wars(ctx, krinder.NewOrderOperator("id", krinder.SortAsc))
+	if len(stored) != 3 || stored[1].ID != 2 || stored[2].ID != 3 {
+		t.Fatalf("expected the second sync to store wars 2 and 3, got %d wars", len(stored))
+	}
+
+	titles := sent.titles()
+	if len(titles) != 2 || titles[0] != "War 3 declared" || titles[1] != "War 2 declared" {
+		t.Errorf("expected wars 2 and 3 to be announced, got %v", titles)
+	}
+
+}
+
+func TestSyncSkipsWarThatAlwaysFails(t *testing.T) {
+
+	ctx := context.Background()
+	s, server, repo, _ := service(t, clock.New())
+	for _, id := range []int{1, 2, 3} {
+		server.SetWar(war(id))
+	}
+	server.Fault("/v1/wars/2/", http.StatusNotFound, http.StatusNotFound, http.StatusNotFound, http.StatusNotFound)
+
+	if err := s.Sync(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	stored, _ := repo.Wars(ctx, krinder.NewOrderOperator("id", krinder.SortAsc))
+	if len(stored) != 2 || stored[0].ID != 1 || stored[1].ID != 3 {
+		t.Fatalf("expected wars 1 and 3 to be stored past the missing war, got %d wars", len(stored))
+	}
+
+	if err := s.Sync(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if requests := server.Requests("/v1/wars/2/"); requests != 1 {
+		t.Errorf("expected the missing war to be fetched once, got %d requests", requests)
+	}
+	if requests := server.Requests("/v1/wars/3/"); requests != 1 {
+		t.Errorf("expected war 3 not to be fetched again, got %d requests", requests)
+	}
+
+}
+
+func TestSyncFailsWhenNoWarIsFetched(t *testing.T) {
+
+	s, server, repo, _ := service(t, clock.New())
+	server.SetWar(war(1))
+	server.Fault("/v1/wars/1/", 420)
+
+	if err := s.Sync(context.Background()); err == nil {
+		t.Fatal("expected the sync to fail")
+	}
+	if server.Requests("/v1/wars/1/") != 1 {
+		t.Errorf("expected error limited requests not to be retried, got %d requests", server.Requests("/v1/wars/1/"))
+	}
+
+	stored, _ := repo.Wars(context.Background())
+	if len(stored) != 0 {
+		t.Errorf("expected no wars to be stored, got %d", len(stored))
+	}
+
+}
+
+func TestSyncUpdatesExpiredWars(t *testing.T) {
+
+	ctx := context.Background()
+	now := clock.NewFake(time.Now())
+	s, server, repo, sent := service(t, now)
+
+	unchanged, finished := war(1), war(2)
+	server.SetWar(unchanged)
+	server.SetWar(finished)
+	if err := s.Sync(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Both wars expire 12 hours after ESI's expiry, but only the second has changed since it was stored
+	now.Advance(server.TTL + 12*time.Hour + time.Minute)
+
+	finished.Finished = null.TimeFrom(time.Date(2021, 11, 20, 12, 0, 0, 0, time.UTC))
+	server.SetWar(finished)
+
+	if err := s.Sync(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	war, err := repo.War(ctx, 2)
+	if err != nil || war.Finished == nil || !war.Finished.Equal(finished.Finished.Time) {
+		t.Errorf("expected war 2 to be finished, got %+v and %v", war, err)
+	}
+
+	if titles := sent.titles(); len(titles) != 1 || titles[0] != "War 2 finished" {
+		t.Errorf("expected only war 2 to be announced as finished, got %v", titles)
+	}
+
+}
+
+func TestEntityWarsIncludesWarsFinishingLater(t *testing.T) {
+
+	ctx := context.Background()
+	now := clock.Fixed(time.Date(2021, 11, 15, 12, 0, 0, 0, time.UTC))
+	s, _, repo, _ := service(t, now)
+
+	corporationID := uint(98000001)
+	finished := map[uint]*time.Time{
+		1: nil,
+		2: timePtr(now.Now().Add(24 * time.Hour)),
+		3: timePtr(now.Now().Add(-24 * time.Hour)),
+	}
+	for id, at := range finished {
+		_, err := repo.CreateWar(ctx, &krinder.MongoWar{
+			ID:        id,
+			Declared:  now.Now().AddDate(0, 0, -7),
+			Started:   now.Now().AddDate(0, 0, -6),
+			Finished:  at,
+			Aggressor: &krinder.MongoWarAggressor{CorporationID: &corporationID},
+			Defender:  &krinder.MongoWarDefender{CorporationID: uintPtr(98100001)},
+		})
+		if err != nil {
+			t.Fatalf("failed to create war: %s", err)
+		}
+	}
+
+	tests := map[bool][]uint{
+		false: {2, 1},
+		true:  {3, 2, 1},
+	}
+	for includeFinished, expected := range tests {
+		wars, err := s.EntityWars(ctx, corporationID, includeFinished, 10)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		ids := make([]uint, 0, len(wars))
+		for _, war := range wars {
+			ids = append(ids, war.ID)
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(expected) {
+			t.Errorf("expected wars %v with finished %t, got %v", expected, includeFinished, ids)
+		}
+	}
+
+}
+
+func timePtr(t time.Time) *time.Time { return &t }
+
+func uintPtr(u uint) *uint { return &u }
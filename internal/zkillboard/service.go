@@ -7,31 +7,71 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/eveisesi/krinder/internal/metrics"
+	"github.com/eveisesi/krinder/pkg/errorcode"
 	"github.com/eveisesi/krinder/pkg/roundtripper"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 )
 
 type API interface{}
 
 type Service struct {
-	url    string
-	client *http.Client
+	url       string
+	transport http.RoundTripper
+	client    *http.Client
+	logger    *logrus.Logger
 }
 
-func New(userAgent string) *Service {
-	return &Service{
-		url: "https://zkillboard.com/api",
-		client: &http.Client{
-			Transport: roundtripper.UserAgent(userAgent, http.DefaultTransport),
-		},
+// Option customises the client returned by New
+type Option func(s *Service)
+
+// WithBaseURL points the client at an API other than https://zkillboard.com/api, such as a local fake
+func WithBaseURL(url string) Option {
+	return func(s *Service) {
+		s.url = strings.TrimSuffix(url, "/")
+	}
+}
+
+// WithTransport sends requests through transport rather than http.DefaultTransport, such as a
+// roundtripper.Recorder replaying fixtures
+func WithTransport(transport http.RoundTripper) Option {
+	return func(s *Service) {
+		s.transport = transport
+	}
+}
+
+func New(logger *logrus.Logger, userAgent string, options ...Option) *Service {
+	s := &Service{
+		logger:    logger,
+		url:       "https://zkillboard.com/api",
+		transport: http.DefaultTransport,
 	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	s.client = &http.Client{
+		Transport: metrics.InstrumentRoundTripper("zkillboard", roundtripper.UserAgent(userAgent, s.transport)),
+	}
+
+	return s
 }
 
 func (s *Service) request(ctx context.Context, method, path string, body io.Reader, expected int, out interface{}) error {
 
 	url := fmt.Sprintf("%s%s", s.url, path)
-	fmt.Println(url)
+
+	entry := s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"service": "zkillboard",
+		"method":  method,
+		"path":    path,
+	})
+
+	start := time.Now()
 	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return errors.Wrap(err, "failed to create request")
@@ -39,23 +79,31 @@ func (s *Service) request(ctx context.Context, method, path string, body io.Read
 
 	res, err := s.client.Do(req)
 	if err != nil {
-		return errors.Wrap(err, "failed to execute request")
+		return upstreamError(err)
 	}
 
-	defer func(requestID string, body io.ReadCloser) {
+	defer func(body io.ReadCloser) {
 		err := body.Close()
 		if err != nil {
-			fmt.Printf("failed to close requst body for %s\n", requestID)
+			entry.WithError(err).Error("failed to close response body")
 		}
-	}(fmt.Sprintf("%s %s", method, path), res.Body)
+	}(res.Body)
+
+	entry.WithFields(logrus.Fields{
+		"status":   res.StatusCode,
+		"duration": time.Since(start),
+	}).Debug("request complete")
 
 	if res.StatusCode > 299 || res.StatusCode != expected {
+		code := errorcode.FromHTTPStatus(res.StatusCode)
+		message := fmt.Sprintf("zkillboard responded with %d %s", res.StatusCode, http.StatusText(res.StatusCode))
+
 		data, err := io.ReadAll(res.Body)
 		if err != nil {
-			return errors.Wrapf(err, "expected status %d, got %d: unable to parse request body", expected, res.StatusCode)
+			return errorcode.Wrap(errors.Wrapf(err, "expected status %d, got %d: unable to parse request body", expected, res.StatusCode), code, message)
 		}
 
-		return errors.Errorf("expected status %d, got %d: %s", expected, res.StatusCode, string(data))
+		return errorcode.Wrap(errors.Errorf("expected status %d, got %d: %s", expected, res.StatusCode, string(data)), code, message)
 	}
 
 	err = json.NewDecoder(res.Body).Decode(out)
@@ -141,11 +189,11 @@ func validEntityTypes() string {
 func (s *Service) Killmails(ctx context.Context, entityType EntityType, id uint64, fetchType FetchType, page uint) ([]*Killmail, error) {
 
 	if !fetchType.valid() {
-		return nil, errors.Errorf("invalid fetch type, got %s, expected one of %s", string(fetchType), validFetchTypes())
+		return nil, errorcode.Newf(errorcode.InvalidArgument, "invalid fetch type, got %s, expected one of %s", string(fetchType), validFetchTypes())
 	}
 
 	if !entityType.valid() {
-		return nil, errors.Errorf("invalid entity type, got %s, expected one of %s", string(entityType), validEntityTypes())
+		return nil, errorcode.Newf(errorcode.InvalidArgument, "invalid entity type, got %s, expected one of %s", string(entityType), validEntityTypes())
 	}
 
 	url := fmt.Sprintf("/%s/%d/%s/npc/0/awox/0/page/%d/", entityType, id, fetchType, page)
@@ -159,3 +207,30 @@ func (s *Service) Killmails(ctx context.Context, entityType EntityType, id uint6
 	return killmails, nil
 
 }
+
+// Killmail looks up the killmail by id, mostly for its hash and value
+func (s *Service) Killmail(ctx context.Context, id uint64) (*Killmail, error) {
+
+	killmails := make([]*Killmail, 0, 1)
+	err := s.request(ctx, http.MethodGet, fmt.Sprintf("/killID/%d/", id), nil, http.StatusOK, &killmails)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch killmail from zkillboard")
+	}
+
+	if len(killmails) == 0 {
+		return nil, errorcode.Newf(errorcode.NotFound, "killmail %d was not found on zkillboard", id)
+	}
+
+	return killmails[0], nil
+
+}
+
+// upstreamError codes an error returned while executing a request to zkillboard, which
+// either timed out or could not reach it
+func upstreamError(err error) error {
+	code := errorcode.UpstreamUnavailable
+	if errorcode.Code(err) == errorcode.Timeout {
+		code = errorcode.Timeout
+	}
+	return errorcode.Wrap(err, code, "zkillboard could not be reached")
+}
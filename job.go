@@ -0,0 +1,22 @@
+package krinder
+
+import (
+	"context"
+	"time"
+)
+
+// JobRepository persists the schedule of the background jobs so that it survives restarts
+// and is shared between replicas
+type JobRepository interface {
+	Job(ctx context.Context, name string) (*Job, error)
+	SaveJob(ctx context.Context, job *Job) error
+}
+
+type Job struct {
+	Name        string    `bson:"name" json:"name"`
+	LastRun     time.Time `bson:"lastRun" json:"lastRun"`
+	LastSuccess time.Time `bson:"lastSuccess" json:"lastSuccess"`
+	LastError   string    `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	NextRun     time.Time `bson:"nextRun" json:"nextRun"`
+	UpdatedAt   time.Time `bson:"updatedAt" json:"updatedAt"`
+}
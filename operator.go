@@ -1,5 +1,11 @@
 package krinder
 
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+)
+
 type Operator struct {
 	Column    string    `json:"column"`
 	Operation Operation `json:"operation"`
@@ -27,6 +33,8 @@ const (
 	OrOp
 	AndOp
 	ExistsOp
+	EqualFoldOp
+	ProjectOp
 )
 
 var AllOperations = []Operation{
@@ -45,19 +53,219 @@ var AllOperations = []Operation{
 	OrOp,
 	AndOp,
 	ExistsOp,
+	EqualFoldOp,
+	ProjectOp,
 }
 
 func (o Operation) IsValid() bool {
 	switch o {
 	case EqualOp, NotEqualOp,
 		GreaterThanOp, LessThanOp, GreaterThanEqualToOp, LessThanEqualToOp,
-		InOp, NotInOp, LikeOp,
-		LimitOp, OrderOp, SkipOp, OrOp, AndOp, ExistsOp:
+		InOp, NotInOp, LikeOp, EqualFoldOp,
+		LimitOp, OrderOp, SkipOp, OrOp, AndOp, ExistsOp, ProjectOp:
 		return true
 	}
 	return false
 }
 
+// IsFilter reports whether the operation restricts which records are returned,
+// as opposed to shaping the result set (limit, skip, order, projection)
+func (o Operation) IsFilter() bool {
+	switch o {
+	case LimitOp, OrderOp, SkipOp, ProjectOp:
+		return false
+	}
+	return o.IsValid()
+}
+
+func (o Operation) String() string {
+	switch o {
+	case EqualOp:
+		return "equal"
+	case NotEqualOp:
+		return "not equal"
+	case GreaterThanOp:
+		return "greater than"
+	case GreaterThanEqualToOp:
+		return "greater than or equal to"
+	case LessThanOp:
+		return "less than"
+	case LessThanEqualToOp:
+		return "less than or equal to"
+	case InOp:
+		return "in"
+	case NotInOp:
+		return "not in"
+	case LikeOp:
+		return "like"
+	case LimitOp:
+		return "limit"
+	case OrderOp:
+		return "order"
+	case SkipOp:
+		return "skip"
+	case OrOp:
+		return "or"
+	case AndOp:
+		return "and"
+	case ExistsOp:
+		return "exists"
+	case EqualFoldOp:
+		return "equal fold"
+	case ProjectOp:
+		return "project"
+	}
+	return fmt.Sprintf("Operation(%d)", int(o))
+}
+
+// columnPattern restricts column names to identifiers and dotted paths. Column names
+// are interpolated into SQL for ORDER BY and projections, so anything else is rejected
+var columnPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// ValidateOperators validates each of the provided operators, returning the first error encountered
+func ValidateOperators(operators ...*Operator) error {
+	for i, o := range operators {
+		if err := o.Validate(); err != nil {
+			return fmt.Errorf("operator %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Validate checks that the operator is well formed, i.e. that it has a column when the
+// operation requires one and that the value is of a type the operation supports. Repositories
+// validate operators before building a query, so an invalid operator results in an error
+// rather than a panic or a silently ignored filter
+func (o *Operator) Validate() error {
+	if o == nil {
+		return fmt.Errorf("operator is nil")
+	}
+
+	if !o.Operation.IsValid() {
+		return fmt.Errorf("invalid operation %d", int(o.Operation))
+	}
+
+	switch o.Operation {
+	case EqualOp, NotEqualOp:
+		return o.validateColumn()
+	case GreaterThanOp, GreaterThanEqualToOp, LessThanOp, LessThanEqualToOp:
+		if err := o.validateColumn(); err != nil {
+			return err
+		}
+		if o.Value == nil {
+			return fmt.Errorf("%s operation on %s requires a value", o.Operation, o.Column)
+		}
+	case InOp, NotInOp:
+		if err := o.validateColumn(); err != nil {
+			return err
+		}
+		if _, err := o.ValueSlice(); err != nil {
+			return err
+		}
+	case LikeOp, EqualFoldOp:
+		if err := o.validateColumn(); err != nil {
+			return err
+		}
+		if _, ok := o.Value.(string); !ok {
+			return fmt.Errorf("%s operation on %s requires a string value, got %T", o.Operation, o.Column, o.Value)
+		}
+	case ExistsOp:
+		if err := o.validateColumn(); err != nil {
+			return err
+		}
+		if _, ok := o.Value.(bool); !ok {
+			return fmt.Errorf("%s operation on %s requires a bool value, got %T", o.Operation, o.Column, o.Value)
+		}
+	case OrOp, AndOp:
+		children, ok := o.Value.([]*Operator)
+		if !ok {
+			return fmt.Errorf("%s operation requires a []*Operator value, got %T", o.Operation, o.Value)
+		}
+		if len(children) == 0 {
+			return fmt.Errorf("%s operation requires at least one operator", o.Operation)
+		}
+		for _, child := range children {
+			if err := child.Validate(); err != nil {
+				return fmt.Errorf("%s: %w", o.Operation, err)
+			}
+			if !child.Operation.IsFilter() {
+				return fmt.Errorf("%s operation may only contain filters, got %s", o.Operation, child.Operation)
+			}
+		}
+	case LimitOp, SkipOp:
+		value, ok := o.Value.(int64)
+		if !ok {
+			return fmt.Errorf("%s operation requires an int64 value, got %T", o.Operation, o.Value)
+		}
+		if value < 0 {
+			return fmt.Errorf("%s operation requires a positive value, got %d", o.Operation, value)
+		}
+	case OrderOp:
+		if err := o.validateColumn(); err != nil {
+			return err
+		}
+		if !o.SortValue().IsValid() {
+			return fmt.Errorf("%s operation on %s requires a valid sort, got %v", o.Operation, o.Column, o.Value)
+		}
+	case ProjectOp:
+		columns, ok := o.Value.([]string)
+		if !ok {
+			return fmt.Errorf("%s operation requires a []string value, got %T", o.Operation, o.Value)
+		}
+		if len(columns) == 0 {
+			return fmt.Errorf("%s operation requires at least one column", o.Operation)
+		}
+		for _, column := range columns {
+			if !columnPattern.MatchString(column) {
+				return fmt.Errorf("%s operation has an invalid column %q", o.Operation, column)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (o *Operator) validateColumn() error {
+	if !columnPattern.MatchString(o.Column) {
+		return fmt.Errorf("%s operation has an invalid column %q", o.Operation, o.Column)
+	}
+	return nil
+}
+
+// ValueSlice returns the value of an In or NotIn operator as a []interface{}.
+// Any slice or array is accepted, so callers may pass a []uint rather than building a []OpValue
+func (o *Operator) ValueSlice() ([]interface{}, error) {
+	switch v := o.Value.(type) {
+	case []interface{}:
+		return v, nil
+	case nil:
+		return nil, fmt.Errorf("%s operation on %s requires a slice value, got nil", o.Operation, o.Column)
+	}
+
+	rv := reflect.ValueOf(o.Value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("%s operation on %s requires a slice value, got %T", o.Operation, o.Column, o.Value)
+	}
+
+	values := make([]interface{}, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		values[i] = rv.Index(i).Interface()
+	}
+
+	return values, nil
+}
+
+// SortValue returns the direction of an Order operator. Zero is returned if the value is not a valid Sort
+func (o *Operator) SortValue() Sort {
+	switch v := o.Value.(type) {
+	case Sort:
+		return v
+	case int:
+		return Sort(v)
+	}
+	return 0
+}
+
 func NewEqualOperator(column string, value interface{}) *Operator {
 	return &Operator{
 		Column:    column,
@@ -204,6 +412,7 @@ func NewExistsOperator(column string, value bool) *Operator {
 	}
 }
 
+// NewLikeOperator matches records where the column contains value. Matching is case insensitive
 func NewLikeOperator(column string, value interface{}) *Operator {
 	return &Operator{
 		Column:    column,
@@ -211,3 +420,21 @@ func NewLikeOperator(column string, value interface{}) *Operator {
 		Value:     value,
 	}
 }
+
+// NewEqualFoldOperator matches records where the column is equal to value, ignoring case
+func NewEqualFoldOperator(column string, value string) *Operator {
+	return &Operator{
+		Column:    column,
+		Operation: EqualFoldOp,
+		Value:     value,
+	}
+}
+
+// NewProjectOperator limits the columns populated on the returned records to those provided
+func NewProjectOperator(columns ...string) *Operator {
+	return &Operator{
+		Column:    "",
+		Operation: ProjectOp,
+		Value:     columns,
+	}
+}
@@ -0,0 +1,76 @@
+package krinder
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOperatorValidate(t *testing.T) {
+
+	valid := []*Operator{
+		NewEqualOperator("name", "Rifter"),
+		NewEqualOperator("finished", nil),
+		NewGreaterThanOperator("aggressor.allianceID", 1),
+		NewInOperator("id", []uint{1, 2}),
+		NewNotInOperator("id", []OpValue{1, 2}),
+		NewLikeOperator("name", "rif"),
+		NewEqualFoldOperator("name", "RIFTER"),
+		NewExistsOperator("finished", false),
+		NewOrOperator(NewEqualOperator("a", 1), NewAndOperator(NewEqualOperator("b", 2))),
+		NewLimitOperator(10),
+		NewSkipOperator(0),
+		NewOrderOperator("id", SortDesc),
+		{Column: "id", Operation: OrderOp, Value: SortAsc},
+		NewProjectOperator("id", "name"),
+	}
+
+	for _, o := range valid {
+		if err := o.Validate(); err != nil {
+			t.Errorf("expected %s operator to be valid, got %s", o.Operation, err)
+		}
+	}
+
+	invalid := []*Operator{
+		nil,
+		NewOrderOperator("id", Sort(0)),
+		{Column: "id", Operation: Operation(-1)},
+		NewGreaterThanOperator("id", nil),
+		NewInOperator("id", 1),
+		NewLikeOperator("name", 1),
+		{Column: "finished", Operation: ExistsOp, Value: 1},
+		NewOrOperator(),
+		NewAndOperator(NewSkipOperator(1)),
+		NewOrOperator(NewInOperator("id", "1")),
+		{Operation: LimitOp, Value: 10},
+		NewLimitOperator(-1),
+		{Column: "id", Operation: OrderOp, Value: "asc"},
+		NewProjectOperator(),
+		NewProjectOperator("id", "name`"),
+		NewEqualOperator("name = 1 OR 1", 1),
+	}
+
+	for i, o := range invalid {
+		if err := o.Validate(); err == nil {
+			t.Errorf("expected invalid operator %d to fail validation", i)
+		}
+	}
+
+}
+
+func TestOperatorValueSlice(t *testing.T) {
+
+	values, err := NewInOperator("id", []uint{1, 2}).ValueSlice()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !reflect.DeepEqual(values, []interface{}{uint(1), uint(2)}) {
+		t.Errorf("unexpected values %#v", values)
+	}
+
+	_, err = NewInOperator("id", 1).ValueSlice()
+	if err == nil {
+		t.Errorf("expected an error for a scalar value")
+	}
+
+}
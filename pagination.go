@@ -0,0 +1,29 @@
+package krinder
+
+import (
+	"context"
+	"time"
+)
+
+// PaginationRepository keeps the pages of replies that are paged through with buttons, so that
+// the buttons keep working after the bot restarts
+type PaginationRepository interface {
+	Pagination(ctx context.Context, messageID string) (*Pagination, error)
+	CreatePagination(ctx context.Context, pagination *Pagination) error
+	// UpdatePaginationPage records the page the message shows
+	UpdatePaginationPage(ctx context.Context, messageID string, page int) error
+}
+
+type Pagination struct {
+	// MessageID identifies the message that shows the pages
+	MessageID string `bson:"messageID" json:"messageID"`
+	ChannelID string `bson:"channelID" json:"channelID"`
+	// AuthorID is the chat user that issued the command, the only user that may turn the pages
+	AuthorID string `bson:"authorID" json:"authorID"`
+	// Pages holds every page, each a chat embed encoded as JSON
+	Pages []string `bson:"pages" json:"pages"`
+	// Page is the index of the page the message shows
+	Page      int       `bson:"page" json:"page"`
+	ExpiresAt time.Time `bson:"expiresAt" json:"expiresAt"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
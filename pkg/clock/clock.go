@@ -0,0 +1,65 @@
+// Package clock abstracts the current time, so that time dependent logic can be tested
+// and evaluated as it stood at a moment in the past
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+type Clock interface {
+	Now() time.Time
+}
+
+type system struct{}
+
+func (system) Now() time.Time {
+	return time.Now()
+}
+
+// New returns the system clock
+func New() Clock {
+	return system{}
+}
+
+type fixed time.Time
+
+func (f fixed) Now() time.Time {
+	return time.Time(f)
+}
+
+// Fixed returns a clock that is always at t
+func Fixed(t time.Time) Clock {
+	return fixed(t)
+}
+
+// Fake is a clock that only moves when it is told to. It is safe for concurrent use
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFake returns a Fake frozen at now
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Set moves the clock to now
+func (f *Fake) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+}
+
+// Advance moves the clock forward by d
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
@@ -0,0 +1,27 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+
+	start := time.Date(2021, 11, 15, 12, 0, 0, 0, time.UTC)
+	c := NewFake(start)
+
+	if !c.Now().Equal(start) {
+		t.Fatalf("expected %s, got %s", start, c.Now())
+	}
+
+	c.Advance(time.Hour)
+	if expected := start.Add(time.Hour); !c.Now().Equal(expected) {
+		t.Errorf("expected %s after advancing, got %s", expected, c.Now())
+	}
+
+	c.Set(start)
+	if !c.Now().Equal(start) {
+		t.Errorf("expected %s after setting, got %s", start, c.Now())
+	}
+
+}
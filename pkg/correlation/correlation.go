@@ -0,0 +1,53 @@
+// Package correlation carries an identifier for a unit of work, such as a single Discord
+// command, through a context so that every log line the work produces can be traced back to it
+package correlation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Field is the log field the correlation id is written to
+const Field = "correlationID"
+
+type key struct{}
+
+// New returns a random correlation id
+func New() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, key{}, id)
+}
+
+// ID returns the correlation id carried by ctx, or an empty string if there is none
+func ID(ctx context.Context) string {
+	id, _ := ctx.Value(key{}).(string)
+	return id
+}
+
+// Hook adds the correlation id carried by the context of an entry to its fields. Entries
+// are given a context with logger.WithContext(ctx)
+type Hook struct{}
+
+func (Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (Hook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+
+	if id := ID(entry.Context); id != "" {
+		entry.Data[Field] = id
+	}
+
+	return nil
+}
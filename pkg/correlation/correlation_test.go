@@ -0,0 +1,37 @@
+package correlation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestHook(t *testing.T) {
+
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.AddHook(Hook{})
+
+	ctx := WithID(context.Background(), "abc123")
+	logger.WithContext(ctx).Info("with id")
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("failed to decode log line: %s", err)
+	}
+	if line[Field] != "abc123" {
+		t.Errorf("expected correlation id abc123, got %v", line[Field])
+	}
+
+	buf.Reset()
+	logger.Info("without id")
+	if bytes.Contains(buf.Bytes(), []byte(Field)) {
+		t.Errorf("expected no correlation id, got %s", buf.String())
+	}
+
+}
@@ -1,25 +1,136 @@
+// Package errorcode classifies errors so that callers can decide how to present them
+// without inspecting messages. Codes survive wrapping with github.com/pkg/errors
 package errorcode
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
 
 type ErrorCode string
 
 const (
+	// InvalidArgument means the request itself is wrong and retrying it will not help
+	InvalidArgument ErrorCode = "INVALID_ARGUMENT"
+	// NotFound means the requested record does not exist, here or upstream
+	NotFound ErrorCode = "NOT_FOUND"
+	// UpstreamThrottled means ESI or zKillboard is rate limiting us
+	UpstreamThrottled ErrorCode = "UPSTREAM_THROTTLED"
+	// UpstreamUnavailable means ESI or zKillboard could not be reached or failed
+	UpstreamUnavailable ErrorCode = "UPSTREAM_UNAVAILABLE"
+	// Timeout means the work did not finish in time
+	Timeout ErrorCode = "TIMEOUT"
+	// Internal is any other failure, and the code of errors that carry none
 	Internal ErrorCode = "INTERNAL"
 )
 
-type instance struct {
-	code    ErrorCode
-	message string
+// Error is an error with a code. Message is safe to show to users, while the
+// wrapped error may hold internal detail
+type Error struct {
+	Code    ErrorCode
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("[%s] %s: %s", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("[%s] %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func New(code ErrorCode, message string) error {
+	return &Error{
+		Code:    code,
+		Message: message,
+	}
+}
+
+func Newf(code ErrorCode, format string, args ...interface{}) error {
+	return New(code, fmt.Sprintf(format, args...))
+}
+
+// Wrap annotates err with a code and message. Wrap returns nil if err is nil
+func Wrap(err error, code ErrorCode, message string) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{
+		Code:    code,
+		Message: message,
+		Err:     err,
+	}
 }
 
-func (i instance) Error() string {
-	return fmt.Sprintf("[%s] %s", i.code, i.message)
+func Wrapf(err error, code ErrorCode, format string, args ...interface{}) error {
+	return Wrap(err, code, fmt.Sprintf(format, args...))
+}
+
+// Code returns the code of the outermost coded error in the chain of err. Deadlines and
+// network timeouts are reported as Timeout, and any other error as Internal.
+// Code returns an empty code for a nil error
+func Code(err error) ErrorCode {
+	if err == nil {
+		return ""
+	}
+
+	var coded *Error
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return Timeout
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return Timeout
+	}
+
+	return Internal
+}
+
+// Message returns the message of the outermost coded error in the chain of err,
+// or an empty string if there is none
+func Message(err error) string {
+	var coded *Error
+	if errors.As(err, &coded) {
+		return coded.Message
+	}
+	return ""
+}
+
+// Transient reports whether err is an upstream failure that may succeed when retried later
+func Transient(err error) bool {
+	switch Code(err) {
+	case UpstreamThrottled, UpstreamUnavailable, Timeout:
+		return true
+	}
+	return false
 }
 
-func New(code ErrorCode, message string) instance {
-	return instance{
-		code:    code,
-		message: message,
+// FromHTTPStatus returns the code for an unexpected status from an upstream API
+func FromHTTPStatus(status int) ErrorCode {
+	switch {
+	case status == http.StatusBadRequest || status == http.StatusUnprocessableEntity:
+		return InvalidArgument
+	case status == http.StatusNotFound:
+		return NotFound
+	// ESI answers 420 once the error limit is reached
+	case status == http.StatusTooManyRequests || status == 420:
+		return UpstreamThrottled
+	case status == http.StatusGatewayTimeout || status == http.StatusRequestTimeout:
+		return Timeout
+	case status >= http.StatusInternalServerError:
+		return UpstreamUnavailable
 	}
+	return Internal
 }
@@ -0,0 +1,92 @@
+package errorcode
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestCode(t *testing.T) {
+
+	tests := map[string]struct {
+		err     error
+		code    ErrorCode
+		message string
+	}{
+		"nil": {
+			err: nil,
+		},
+		"uncoded": {
+			err:  errors.New("boom"),
+			code: Internal,
+		},
+		"wrapped by pkg/errors": {
+			err:     errors.Wrap(New(NotFound, "character not found"), "failed to fetch character"),
+			code:    NotFound,
+			message: "character not found",
+		},
+		"outermost code wins": {
+			err:     Wrap(New(UpstreamUnavailable, "esi failed"), InvalidArgument, "bad id"),
+			code:    InvalidArgument,
+			message: "bad id",
+		},
+		"deadline": {
+			err:  errors.Wrap(context.DeadlineExceeded, "failed to execute request"),
+			code: Timeout,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if code := Code(test.err); code != test.code {
+				t.Errorf("expected code %q, got %q", test.code, code)
+			}
+			if message := Message(test.err); message != test.message {
+				t.Errorf("expected message %q, got %q", test.message, message)
+			}
+		})
+	}
+
+}
+
+func TestWrapNil(t *testing.T) {
+	if Wrap(nil, Internal, "nothing") != nil {
+		t.Error("expected wrapping nil to return nil")
+	}
+}
+
+func TestFromHTTPStatus(t *testing.T) {
+	tests := map[int]ErrorCode{
+		400: InvalidArgument,
+		404: NotFound,
+		420: UpstreamThrottled,
+		429: UpstreamThrottled,
+		502: UpstreamUnavailable,
+		504: Timeout,
+		403: Internal,
+	}
+
+	for status, code := range tests {
+		if got := FromHTTPStatus(status); got != code {
+			t.Errorf("expected %q for status %d, got %q", code, status, got)
+		}
+	}
+}
+
+func TestTransient(t *testing.T) {
+	tests := map[ErrorCode]bool{
+		UpstreamThrottled:   true,
+		UpstreamUnavailable: true,
+		Timeout:             true,
+		NotFound:            false,
+		InvalidArgument:     false,
+		Internal:            false,
+	}
+
+	for code, transient := range tests {
+		if got := Transient(New(code, "boom")); got != transient {
+			t.Errorf("expected %s to be transient %t, got %t", code, transient, got)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+// Package graceful helps bound the work that is still in flight when a process is asked to stop
+package graceful
+
+import (
+	"context"
+	"time"
+)
+
+// WithGrace returns a context that is cancelled grace after parent is cancelled, giving work
+// started under parent time to finish. Values are not inherited from parent.
+// The returned cancel func releases the resources associated with the context and cancels it immediately
+func WithGrace(parent context.Context, grace time.Duration) (context.Context, context.CancelFunc) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-parent.Done():
+		}
+
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+			cancel()
+		}
+	}()
+
+	return ctx, cancel
+
+}
@@ -0,0 +1,43 @@
+package graceful
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithGrace(t *testing.T) {
+
+	parent, stop := context.WithCancel(context.Background())
+
+	ctx, cancel := WithGrace(parent, time.Millisecond*50)
+	defer cancel()
+
+	stop()
+
+	select {
+	case <-ctx.Done():
+		t.Fatal("context was cancelled before the grace period elapsed")
+	case <-time.After(time.Millisecond * 10):
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context was not cancelled after the grace period elapsed")
+	}
+
+}
+
+func TestWithGraceCancel(t *testing.T) {
+
+	ctx, cancel := WithGrace(context.Background(), time.Hour)
+	cancel()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context was not cancelled by its cancel func")
+	}
+
+}
@@ -0,0 +1,228 @@
+package roundtripper
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Mode selects whether a Recorder captures responses or replays captured ones
+type Mode string
+
+const (
+	// ModeReplay answers requests from fixtures and never touches the network
+	ModeReplay Mode = "replay"
+	// ModeRecord forwards requests and captures every response into a fixture
+	ModeRecord Mode = "record"
+)
+
+// ParseMode parses s, which is either record or replay
+func ParseMode(s string) (Mode, error) {
+	switch Mode(strings.ToLower(s)) {
+	case ModeReplay:
+		return ModeReplay, nil
+	case ModeRecord:
+		return ModeRecord, nil
+	}
+	return "", errors.Errorf("unknown fixture mode %q, expected record or replay", s)
+}
+
+// Fixture is a captured response as it is stored on disk. Body holds JSON responses as is so that
+// fixtures are readable and can be edited by hand, any other body is held by Text. Synthetic marks
+// fixtures that were written by hand rather than recorded, which ModeRecord refuses to overwrite
+type Fixture struct {
+	Synthetic bool            `json:"synthetic,omitempty"`
+	Method    string          `json:"method"`
+	URL       string          `json:"url"`
+	Status    int             `json:"status"`
+	Header    http.Header     `json:"header,omitempty"`
+	Body      json.RawMessage `json:"body,omitempty"`
+	Text      string          `json:"text,omitempty"`
+}
+
+// Recorder is a round tripper that records responses into fixture files under a directory,
+// or replays them. Every request maps to one file, named after its host, method, path and query
+type Recorder struct {
+	dir  string
+	mode Mode
+	next http.RoundTripper
+
+	mu sync.Mutex
+}
+
+// NewRecorder returns a Recorder reading and writing fixtures in dir. In ModeRecord requests are
+// forwarded to next, or http.DefaultTransport when next is nil
+func NewRecorder(dir string, mode Mode, next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{
+		dir:  dir,
+		mode: mode,
+		next: next,
+	}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read request body")
+		}
+		_ = req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	path := filepath.Join(r.dir, FixtureName(req.Method, req.URL.String(), body))
+
+	if r.mode == ModeRecord {
+		return r.record(req, path)
+	}
+
+	return r.replay(req, path)
+
+}
+
+func (r *Recorder) replay(req *http.Request, path string) (*http.Response, error) {
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, errors.Errorf("no fixture recorded for %s %s, expected %s", req.Method, req.URL, path)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read fixture")
+	}
+
+	var fixture = new(Fixture)
+	err = json.Unmarshal(data, fixture)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode fixture %s", path)
+	}
+
+	body := []byte(fixture.Body)
+	if len(body) == 0 {
+		body = []byte(fixture.Text)
+	}
+
+	header := fixture.Header
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fixture.Status, http.StatusText(fixture.Status)),
+		StatusCode:    fixture.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+
+}
+
+func (r *Recorder) record(req *http.Request, path string) (*http.Response, error) {
+
+	// Synthetic fixtures hold made up ids that the live APIs do not know, so recording over them
+	// would only replace them with errors
+	data, err := os.ReadFile(path)
+	if err == nil {
+		var existing = new(Fixture)
+		if json.Unmarshal(data, existing) == nil && existing.Synthetic {
+			return nil, errors.Errorf("refusing to record %s %s over the synthetic fixture %s", req.Method, req.URL, path)
+		}
+	}
+
+	res, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read response body")
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	fixture := &Fixture{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Status: res.StatusCode,
+		Header: res.Header.Clone(),
+	}
+	// Headers that vary from one response to the next only add noise to the diff of a re-recording
+	for _, key := range []string{"Date", "Set-Cookie", "Strict-Transport-Security", "X-Esi-Request-Id"} {
+		fixture.Header.Del(key)
+	}
+
+	if json.Valid(body) {
+		fixture.Body = body
+	} else {
+		fixture.Text = string(body)
+	}
+
+	data, err = json.MarshalIndent(fixture, "", "\t")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode fixture")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create fixture directory")
+	}
+
+	err = os.WriteFile(path, append(data, '\n'), 0o644)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to write fixture")
+	}
+
+	return res, nil
+
+}
+
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9.=-]+`)
+
+// FixtureName returns the path, relative to the fixture directory, of the fixture for a request.
+// Requests with a body, such as POSTs to /universe/names/, are told apart by a hash of the body
+func FixtureName(method, rawURL string, body []byte) string {
+
+	host, rest := rawURL, ""
+	if i := strings.Index(rawURL, "://"); i >= 0 {
+		host = rawURL[i+3:]
+	}
+	if i := strings.IndexByte(host, '/'); i >= 0 {
+		host, rest = host[:i], host[i:]
+	}
+
+	name := strings.Trim(unsafeName.ReplaceAllString(rest, "_"), "_")
+	if name == "" {
+		name = "root"
+	}
+	name = strings.ToLower(method) + "_" + name
+
+	if len(body) > 0 {
+		name = fmt.Sprintf("%s_%x", name, sha256.Sum256(body))[:len(name)+9]
+	}
+
+	return filepath.Join(unsafeName.ReplaceAllString(host, "_"), name+".json")
+
+}
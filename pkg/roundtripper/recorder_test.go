@@ -0,0 +1,116 @@
+package roundtripper
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+
+	dir := t.TempDir()
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("X-Pages", "2")
+		w.Header().Set("Date", "Mon, 02 Jan 2006 15:04:05 GMT")
+		_, _ = io.WriteString(w, `{ "id": 1 }`)
+	}))
+
+	get := func(client *http.Client) (*http.Response, string) {
+		t.Helper()
+		res, err := client.Get(server.URL + "/v1/wars/?page=1")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return res, string(body)
+	}
+
+	_, recorded := get(&http.Client{Transport: NewRecorder(dir, ModeRecord, nil)})
+	server.Close()
+
+	res, replayed := get(&http.Client{Transport: NewRecorder(dir, ModeReplay, nil)})
+
+	if calls != 1 {
+		t.Errorf("expected the server to be called once, got %d", calls)
+	}
+	if recorded != `{ "id": 1 }` || strings.Join(strings.Fields(replayed), "") != `{"id":1}` {
+		t.Errorf("unexpected bodies, recorded %q and replayed %q", recorded, replayed)
+	}
+	if res.StatusCode != http.StatusOK || res.Header.Get("X-Pages") != "2" {
+		t.Errorf("unexpected replayed response %d %v", res.StatusCode, res.Header)
+	}
+	if res.Header.Get("Date") != "" {
+		t.Errorf("expected the Date header to be dropped, got %q", res.Header.Get("Date"))
+	}
+
+	_, err := (&http.Client{Transport: NewRecorder(dir, ModeReplay, nil)}).Get(server.URL + "/v1/wars/?page=2")
+	if err == nil || !strings.Contains(err.Error(), "no fixture recorded") {
+		t.Errorf("expected a missing fixture error, got %v", err)
+	}
+
+}
+
+func TestRecorderKeepsSyntheticFixtures(t *testing.T) {
+
+	dir := t.TempDir()
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	synthetic := `{ "synthetic": true, "method": "GET", "url": "` + server.URL + `/v1/wars/1/", "status": 200, "body": { "id": 1 } }`
+	path := filepath.Join(dir, FixtureName(http.MethodGet, server.URL+"/v1/wars/1/", nil))
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err = os.WriteFile(path, []byte(synthetic), 0o644)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	_, err = (&http.Client{Transport: NewRecorder(dir, ModeRecord, nil)}).Get(server.URL + "/v1/wars/1/")
+	if err == nil || !strings.Contains(err.Error(), "synthetic fixture") {
+		t.Errorf("expected recording over a synthetic fixture to be refused, got %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("expected the server not to be called, got %d", calls)
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != synthetic {
+		t.Errorf("expected the synthetic fixture to be kept, got %s", data)
+	}
+
+}
+
+func TestFixtureName(t *testing.T) {
+
+	tests := []struct {
+		method, url, body, expected string
+	}{
+		{"GET", "https://esi.evetech.net/v1/killmails/1/abc/", "", "esi.evetech.net/get_v1_killmails_1_abc.json"},
+		{"GET", "https://zkillboard.com/api/characterID/1/kills/page/1/", "", "zkillboard.com/get_api_characterID_1_kills_page_1.json"},
+		{"GET", "http://127.0.0.1:8080/v1/wars/?max_war_id=10", "", "127.0.0.1_8080/get_v1_wars_max_war_id=10.json"},
+		{"POST", "https://esi.evetech.net/v3/universe/names/", "[1,2]", "esi.evetech.net/post_v3_universe_names_"},
+	}
+
+	for _, test := range tests {
+		name := FixtureName(test.method, test.url, []byte(test.body))
+		if !strings.HasPrefix(name, test.expected) {
+			t.Errorf("expected fixture name of %s %s to start with %q, got %q", test.method, test.url, test.expected, name)
+		}
+	}
+
+}
@@ -33,15 +33,18 @@ func (e *ESIGroup) ToMongoGroup() *MySQLGroup {
 	}
 }
 
+// MySQLGroup is the stored representation of a group. The bson tags
+// mirror the MySQL column names so that the same operators can be used
+// against either backend
 type MySQLGroup struct {
-	CategoryID uint      `db:"category_id"`
-	GroupID    uint      `db:"group_id"`
-	Name       string    `db:"name"`
-	Published  bool      `db:"published"`
-	Expires    time.Time `db:"expires"`
-	Etag       string    `db:"etag"`
-	CreatedAt  time.Time `db:"created_at"`
-	UpdatedAt  time.Time `db:"updated_at"`
+	CategoryID uint      `db:"category_id" bson:"category_id"`
+	GroupID    uint      `db:"group_id" bson:"group_id"`
+	Name       string    `db:"name" bson:"name"`
+	Published  bool      `db:"published" bson:"published"`
+	Expires    time.Time `db:"expires" bson:"expires"`
+	Etag       string    `db:"etag" bson:"etag"`
+	CreatedAt  time.Time `db:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at" bson:"updated_at"`
 }
 
 type entityRepository interface {
@@ -66,9 +69,9 @@ func (e *ESIEntity) ToMongoEntity() *MongoEntity {
 }
 
 type MongoEntity struct {
-	ID        uint      `json:"id"`
-	Name      string    `json:"name"`
-	Published bool      `json:"published"`
+	ID        uint      `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Published bool      `json:"published" db:"published"`
 	Expires   time.Time `db:"expires"`
 	Etag      string    `db:"etag"`
 	CreatedAt time.Time `db:"created_at"`
@@ -102,62 +102,62 @@ func (i *ESIWarAlly) ToMongoWarAlly() *MongoWarAlly {
 
 type MongoWar struct {
 	// ID of the specified war
-	ID uint `bson:"id"`
+	ID uint `bson:"id" json:"id"`
 	// allied corporations or alliances, each object contains either corporation_id or alliance_id
-	Allies []*MongoWarAlly `bson:"allies"`
+	Allies []*MongoWarAlly `bson:"allies" json:"allies"`
 	// Time that the war was declared
-	Declared time.Time `bson:"declared"`
+	Declared time.Time `bson:"declared" json:"declared"`
 	// Time the war ended and shooting was no longer allowed
-	Finished *time.Time `bson:"finished,omitempty"`
+	Finished *time.Time `bson:"finished,omitempty" json:"finished,omitempty"`
 	// Was the war declared mutual by both parties
-	Mutual bool `bson:"mutual"`
+	Mutual bool `bson:"mutual" json:"mutual"`
 	// Is the war currently open for allies or not
-	OpenForAllies bool `bson:"openForAllies"`
+	OpenForAllies bool `bson:"openForAllies" json:"openForAllies"`
 	// Time the war was retracted but both sides could still shoot each other
-	Retracted *time.Time `bson:"retracted,omitempty"`
+	Retracted *time.Time `bson:"retracted,omitempty" json:"retracted,omitempty"`
 	// Time when the war started and both sides could shoot each other
-	Started time.Time `bson:"started"`
+	Started time.Time `bson:"started" json:"started"`
 
-	Aggressor *MongoWarAggressor `bson:"aggressor,omitempty"`
-	Defender  *MongoWarDefender  `bson:"defender,omitempty"`
+	Aggressor *MongoWarAggressor `bson:"aggressor,omitempty" json:"aggressor,omitempty"`
+	Defender  *MongoWarDefender  `bson:"defender,omitempty" json:"defender,omitempty"`
 
 	// DateTime the record was inserted into the DB
-	CreatedAt time.Time `bson:"createdAt"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
 	// DateTime the record in the database was last updated
-	UpdatedAt time.Time `bson:"updatedAt"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
 	// DateTime the record is considered stale and should receive an update.
 	// This can be null is the record will never be considered stale
-	ExpiresAt *time.Time `bson:"expiresAt,omitempty"`
+	ExpiresAt *time.Time `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"`
 	// This is an ETag from the API that sourced this information.
 	// Please do not ask anybody how to calculate this
-	IntegrityHash string `bson:"integrityHash,omitempty"`
+	IntegrityHash string `bson:"integrityHash,omitempty" json:"-"`
 }
 
 type MongoWarAggressor struct {
 	// Alliance ID if and only if the aggressor is an alliance
-	AllianceID *uint `bson:"allianceID,omitempty"`
+	AllianceID *uint `bson:"allianceID,omitempty" json:"allianceID,omitempty"`
 	// Corporation ID if and only if the aggressor is a corporation
-	CorporationID *uint `bson:"corporationID,omitempty"`
+	CorporationID *uint `bson:"corporationID,omitempty" json:"corporationID,omitempty"`
 	// ISK value of ships the aggressor has destroyed
-	IskDestroyed float64 `bson:"iskDestroyed"`
+	IskDestroyed float64 `bson:"iskDestroyed" json:"iskDestroyed"`
 	// The number of ships the aggressor has killed
-	ShipsKilled uint `bson:"shipsKilled"`
+	ShipsKilled uint `bson:"shipsKilled" json:"shipsKilled"`
 }
 
 type MongoWarDefender struct {
 	// Alliance ID if and only if the defender is an alliance
-	AllianceID *uint `bson:"allianceID,omitempty"`
+	AllianceID *uint `bson:"allianceID,omitempty" json:"allianceID,omitempty"`
 	// Corporation ID if and only if the defender is a corporation
-	CorporationID *uint `bson:"corporationID,omitempty"`
+	CorporationID *uint `bson:"corporationID,omitempty" json:"corporationID,omitempty"`
 	// ISK value of ships the aggressor has destroyed
-	IskDestroyed float64 `bson:"iskDestroyed"`
+	IskDestroyed float64 `bson:"iskDestroyed" json:"iskDestroyed"`
 	// The number of ships the aggressor has killed
-	ShipsKilled uint `bson:"shipsKilled"`
+	ShipsKilled uint `bson:"shipsKilled" json:"shipsKilled"`
 }
 
 type MongoWarAlly struct {
 	// Alliance ID if and only if this ally is an alliance
-	AllianceID *uint `bson:"allianceID,omitempty"`
+	AllianceID *uint `bson:"allianceID,omitempty" json:"allianceID,omitempty"`
 	// Corporation ID if and only if this ally is a corporation
-	CorporationID *uint `bson:"corporationID,omitempty"`
+	CorporationID *uint `bson:"corporationID,omitempty" json:"corporationID,omitempty"`
 }